@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/Azzonya/go-shortener/internal/app"
 )
@@ -17,6 +18,14 @@ func main() {
 	fmt.Println("Build date:", getValueOrDefault(buildDate))
 	fmt.Println("Build commit:", getValueOrDefault(buildCommit))
 
+	// "shortener migrate [flags] [up|down [steps]|status]" manages the database schema
+	// instead of starting the server.
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+		app.Migrate()
+		return
+	}
+
 	app.Start()
 }
 
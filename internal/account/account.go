@@ -0,0 +1,212 @@
+// Package account manages the registered user accounts of the URL shortener.
+//
+// An account is a user logging in with a login and password instead of being identified by
+// an anonymous cookie only, so that its links follow it across browsers. Passwords are stored
+// as bcrypt hashes. On signup, which is also the first login of the account, the links of
+// the anonymous user of the browser are transferred to the account. Later logins transfer
+// nothing, so that the anonymous links of a browser are not silently moved into any account
+// logging in there. The accounts whose login is configured as an administrator are granted
+// the admin role.
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+	"github.com/Azzonya/go-shortener/internal/repo"
+	"github.com/Azzonya/go-shortener/internal/user"
+)
+
+// Password length limits, bcrypt ignores the bytes after the 72nd.
+const (
+	MinPasswordLength = 8  // MinPasswordLength is the minimum number of bytes of a password.
+	MaxPasswordLength = 72 // MaxPasswordLength is the maximum number of bytes of a password.
+)
+
+var (
+	// ErrInvalidLogin is returned when the login is malformed.
+	ErrInvalidLogin = errors.New("login must have 3 to 64 lowercase letters, digits, dots, dashes or underscores")
+
+	// ErrInvalidPassword is returned when the password is too short or too long.
+	ErrInvalidPassword = fmt.Errorf("password must have %d to %d bytes", MinPasswordLength, MaxPasswordLength)
+
+	// ErrWrongCredentials is returned when the login is unknown or the password does not match.
+	ErrWrongCredentials = errors.New("wrong login or password")
+)
+
+// loginRe matches the valid logins.
+var loginRe = regexp.MustCompile(`^[a-z0-9._-]{3,64}$`)
+
+// Accounts registers and logs in the user accounts stored in the repository.
+type Accounts struct {
+	repo      repo.Repo        // Repository storing the accounts
+	cost      int              // bcrypt cost of the password hashes
+	dummyHash []byte           // Hash compared on unknown logins, so that they take as long as known ones
+	now       func() time.Time // Current time, replaced in tests
+
+	mu     sync.RWMutex
+	admins map[string]bool // Logins of the accounts granted the admin role
+}
+
+// New creates a new account manager storing the accounts in the repository.
+func New(repo repo.Repo) *Accounts {
+	return newAccounts(repo, bcrypt.DefaultCost)
+}
+
+// newAccounts creates a new account manager hashing the passwords with the given bcrypt cost.
+func newAccounts(repo repo.Repo, cost int) *Accounts {
+	dummyHash, _ := bcrypt.GenerateFromPassword([]byte("dummy password"), cost)
+
+	return &Accounts{
+		repo:      repo,
+		cost:      cost,
+		dummyHash: dummyHash,
+		now:       time.Now,
+	}
+}
+
+// SetAdmins grants the admin role to the accounts with the given logins, replacing the previous ones.
+// Tokens already issued keep their role, but IsAdmin no longer confirms it.
+func (a *Accounts) SetAdmins(logins []string) {
+	admins := make(map[string]bool, len(logins))
+	for _, login := range logins {
+		if login = normalizeLogin(login); login != "" {
+			admins[login] = true
+		}
+	}
+
+	a.mu.Lock()
+	a.admins = admins
+	a.mu.Unlock()
+}
+
+// IsAdmin checks if the user is an account currently granted the admin role.
+func (a *Accounts) IsAdmin(ctx context.Context, userID string) (bool, error) {
+	account, err := a.repo.GetAccount(ctx, userID)
+	if errors.Is(err, entities.ErrAccountNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return a.role(account.Login) == user.RoleAdmin, nil
+}
+
+// Signup registers a new account with the login and password and transfers the links
+// of the anonymous user to it. An empty anonymousID transfers nothing.
+// It returns entities.ErrLoginExists if the login is already taken.
+func (a *Accounts) Signup(ctx context.Context, login, password, anonymousID string) (*entities.Account, error) {
+	login = normalizeLogin(login)
+	if !loginRe.MatchString(login) {
+		return nil, ErrInvalidLogin
+	}
+	if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return nil, ErrInvalidPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), a.cost)
+	if err != nil {
+		return nil, fmt.Errorf("cannot hash password: %w", err)
+	}
+
+	u, err := user.New()
+	if err != nil {
+		return nil, err
+	}
+
+	account := &entities.Account{
+		ID:           u.ID,
+		Login:        login,
+		PasswordHash: string(hash),
+		CreatedAt:    a.now().UTC(),
+		Role:         a.role(login),
+	}
+
+	if err = a.repo.AddAccount(ctx, account); err != nil {
+		return nil, err
+	}
+
+	if err = a.merge(ctx, anonymousID, account.ID); err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// Login checks the login and password of an account. The links of anonymous users are only
+// transferred by Signup, the first login of the account.
+// It returns ErrWrongCredentials if the login is unknown or the password does not match.
+func (a *Accounts) Login(ctx context.Context, login, password string) (*entities.Account, error) {
+	account, err := a.repo.GetAccountByLogin(ctx, normalizeLogin(login))
+	if errors.Is(err, entities.ErrAccountNotFound) {
+		_ = bcrypt.CompareHashAndPassword(a.dummyHash, []byte(password))
+		return nil, ErrWrongCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrWrongCredentials
+	}
+
+	account.Role = a.role(account.Login)
+
+	return account, nil
+}
+
+// merge transfers the links of the anonymous user to the new account.
+// Nothing is transferred if the user is another account, as happens when signing up without logging out,
+// nor if the user is banned, since the ban would no longer apply to its links once they are transferred.
+func (a *Accounts) merge(ctx context.Context, anonymousID, accountID string) error {
+	if anonymousID == "" || anonymousID == accountID {
+		return nil
+	}
+
+	_, err := a.repo.GetAccount(ctx, anonymousID)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, entities.ErrAccountNotFound) {
+		return err
+	}
+
+	_, err = a.repo.GetBan(ctx, anonymousID)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, entities.ErrBanNotFound) {
+		return err
+	}
+
+	if _, err = a.repo.TransferURLs(ctx, anonymousID, accountID); err != nil {
+		return fmt.Errorf("cannot transfer links to the account: %w", err)
+	}
+
+	return nil
+}
+
+// role returns the role granted to the account with the login.
+func (a *Accounts) role(login string) string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.admins[login] {
+		return user.RoleAdmin
+	}
+	return ""
+}
+
+// normalizeLogin returns the login in the form it is stored in.
+func normalizeLogin(login string) string {
+	return strings.ToLower(strings.TrimSpace(login))
+}
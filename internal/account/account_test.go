@@ -0,0 +1,177 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+	"github.com/Azzonya/go-shortener/internal/repo/inmemory"
+	"github.com/Azzonya/go-shortener/internal/user"
+)
+
+func newTestAccounts(t *testing.T) (*Accounts, *inmemory.St) {
+	t.Helper()
+
+	storage, err := inmemory.New("")
+	if err != nil {
+		t.Fatalf("inmemory.New() error = %v", err)
+	}
+
+	return newAccounts(storage, bcrypt.MinCost), storage
+}
+
+func TestAccounts_Signup(t *testing.T) {
+	tests := []struct {
+		name     string
+		login    string
+		password string
+		wantErr  error
+	}{
+		{name: "Valid credentials", login: " Alice ", password: "correct horse"},
+		{name: "Taken login", login: "bob", password: "correct horse", wantErr: entities.ErrLoginExists},
+		{name: "Short login", login: "al", password: "correct horse", wantErr: ErrInvalidLogin},
+		{name: "Login with spaces", login: "al ice", password: "correct horse", wantErr: ErrInvalidLogin},
+		{name: "Short password", login: "alice", password: "short", wantErr: ErrInvalidPassword},
+		{name: "Long password", login: "alice", password: strings.Repeat("a", MaxPasswordLength+1), wantErr: ErrInvalidPassword},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _ := newTestAccounts(t)
+			ctx := context.Background()
+
+			if _, err := a.Signup(ctx, "bob", "bob's password", ""); err != nil {
+				t.Fatalf("Signup() error = %v", err)
+			}
+
+			got, err := a.Signup(ctx, tt.login, tt.password, "")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Signup() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if got.Login != "alice" || got.ID == "" || got.PasswordHash == tt.password {
+				t.Errorf("Signup() = %+v, want a hashed account with the login alice", got)
+			}
+		})
+	}
+}
+
+func TestAccounts_Login(t *testing.T) {
+	a, _ := newTestAccounts(t)
+	ctx := context.Background()
+
+	signedUp, err := a.Signup(ctx, "alice", "correct horse", "")
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+
+	got, err := a.Login(ctx, "ALICE", "correct horse")
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if got.ID != signedUp.ID {
+		t.Errorf("Login() ID = %v, want %v", got.ID, signedUp.ID)
+	}
+
+	for _, creds := range [][2]string{{"alice", "wrong horse"}, {"mallory", "correct horse"}} {
+		if _, err = a.Login(ctx, creds[0], creds[1]); !errors.Is(err, ErrWrongCredentials) {
+			t.Errorf("Login(%q, %q) error = %v, want %v", creds[0], creds[1], err, ErrWrongCredentials)
+		}
+	}
+}
+
+func TestAccounts_Merge(t *testing.T) {
+	a, storage := newTestAccounts(t)
+	ctx := context.Background()
+
+	for _, link := range []struct{ shortURL, userID string }{{"anon1", "anonymous"}, {"anon2", "anonymous"}, {"bob1", "bob"}} {
+		if err := storage.Add(ctx, "https://example.com/"+link.shortURL, link.shortURL, link.userID, nil); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	alice, err := a.Signup(ctx, "alice", "correct horse", "anonymous")
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+
+	urls, err := storage.ListAll(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("ListAll() error = %v", err)
+	}
+	if len(urls) != 2 {
+		t.Errorf("account links after signup = %v, want the 2 anonymous links", len(urls))
+	}
+
+	// Signing up without logging out does not move the links of the account logged in before.
+	carol, err := a.Signup(ctx, "carol", "correct horse", alice.ID)
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+	if urls, _ = storage.ListAll(ctx, carol.ID); len(urls) != 0 {
+		t.Errorf("links moved from another account = %v, want none", len(urls))
+	}
+
+	// Later logins leave the links of the anonymous user of the browser alone.
+	if _, err = a.Login(ctx, "carol", "correct horse"); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if urls, _ = storage.ListAll(ctx, "bob"); len(urls) != 1 {
+		t.Errorf("anonymous links after login = %v, want the link left to the anonymous user", len(urls))
+	}
+	// The links of a banned user stay banned.
+	if err = storage.BanUser(ctx, &entities.Ban{UserID: "bob", BannedBy: "admin"}); err != nil {
+		t.Fatalf("BanUser() error = %v", err)
+	}
+	dave, err := a.Signup(ctx, "dave", "correct horse", "bob")
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+	if urls, _ = storage.ListAll(ctx, dave.ID); len(urls) != 0 {
+		t.Errorf("links moved from a banned user = %v, want none", len(urls))
+	}
+}
+
+func TestAccounts_AdminRole(t *testing.T) {
+	a, _ := newTestAccounts(t)
+	ctx := context.Background()
+
+	a.SetAdmins([]string{" Root "})
+
+	root, err := a.Signup(ctx, "root", "root's password", "")
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+	if root.Role != user.RoleAdmin {
+		t.Errorf("Signup() role = %q, want %q", root.Role, user.RoleAdmin)
+	}
+
+	alice, err := a.Signup(ctx, "alice", "alice's password", "")
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+	if alice.Role != "" {
+		t.Errorf("Signup() role = %q, want none", alice.Role)
+	}
+
+	for id, want := range map[string]bool{root.ID: true, alice.ID: false, "anonymous": false} {
+		if got, err := a.IsAdmin(ctx, id); err != nil || got != want {
+			t.Errorf("IsAdmin(%s) = %v, %v, want %v", id, got, err, want)
+		}
+	}
+
+	a.SetAdmins(nil)
+
+	if got, _ := a.IsAdmin(ctx, root.ID); got {
+		t.Errorf("IsAdmin() of a removed administrator = %v, want false", got)
+	}
+	if got, _ := a.Login(ctx, "root", "root's password"); got.Role != "" {
+		t.Errorf("Login() role of a removed administrator = %q, want none", got.Role)
+	}
+}
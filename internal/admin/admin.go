@@ -0,0 +1,181 @@
+// Package admin provides the moderation actions of the administrators of the URL shortener.
+//
+// Administrators search the links of every user, disable and enable them again whoever owns them,
+// give them to another owner and ban the users whose requests must be rejected.
+// Every action is recorded in the audit trail with the administrator who made it.
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+	"github.com/Azzonya/go-shortener/internal/repo"
+)
+
+// Limits of the searches and audit trail listings.
+const (
+	DefaultLimit = 100  // DefaultLimit is the number of results returned when no limit is given.
+	MaxLimit     = 1000 // MaxLimit is the maximum number of results returned at once.
+)
+
+var (
+	// ErrInvalidUserID is returned when the user ID of a reassignment or ban is empty.
+	ErrInvalidUserID = errors.New("user ID must be set")
+
+	// ErrSelfBan is returned when an administrator tries to ban themselves.
+	ErrSelfBan = errors.New("administrators can not ban themselves")
+)
+
+// Admin moderates the links and users stored in the repository.
+type Admin struct {
+	repo repo.Repo        // Repository storing the links, bans and audit trail
+	now  func() time.Time // Current time, replaced in tests
+}
+
+// New creates a new moderator of the links and users stored in the repository.
+func New(repo repo.Repo) *Admin {
+	return &Admin{
+		repo: repo,
+		now:  time.Now,
+	}
+}
+
+// SearchLinks returns the links of every user matching the filter, including deleted, disabled and expired links.
+// A limit out of range is replaced by DefaultLimit or capped at MaxLimit.
+func (a *Admin) SearchLinks(ctx context.Context, filter entities.LinkFilter) ([]*entities.Link, error) {
+	filter.Query = strings.TrimSpace(filter.Query)
+	filter.Limit = limit(filter.Limit)
+	filter.Offset = max(filter.Offset, 0)
+
+	return a.repo.SearchLinks(ctx, &filter)
+}
+
+// DisableLink disables the short URL, so that it no longer redirects, whoever owns it.
+// It returns entities.ErrURLNotFound if the short URL does not exist.
+func (a *Admin) DisableLink(ctx context.Context, actor, shortURL, reason string) error {
+	if err := a.repo.SetURLDisabled(ctx, shortURL, true); err != nil {
+		return err
+	}
+
+	return a.audit(ctx, actor, entities.AuditDisableURL, shortURL, reason)
+}
+
+// EnableLink enables again the disabled short URL.
+// It returns entities.ErrURLNotFound if the short URL does not exist.
+func (a *Admin) EnableLink(ctx context.Context, actor, shortURL, reason string) error {
+	if err := a.repo.SetURLDisabled(ctx, shortURL, false); err != nil {
+		return err
+	}
+
+	return a.audit(ctx, actor, entities.AuditEnableURL, shortURL, reason)
+}
+
+// ReassignLink makes the user the owner of the short URL.
+// It returns entities.ErrURLNotFound if the short URL does not exist.
+func (a *Admin) ReassignLink(ctx context.Context, actor, shortURL, userID, reason string) error {
+	userID = strings.TrimSpace(userID)
+	if userID == "" {
+		return ErrInvalidUserID
+	}
+
+	link, err := a.repo.GetLink(ctx, shortURL)
+	if err != nil {
+		return err
+	}
+
+	if err = a.repo.ReassignURL(ctx, shortURL, userID); err != nil {
+		return err
+	}
+
+	return a.audit(ctx, actor, entities.AuditReassignURL, shortURL, details(reason, "from "+link.UserID+" to "+userID))
+}
+
+// BanUser bans the user, so that its requests are rejected.
+// Banning a banned user replaces the reason of the ban.
+func (a *Admin) BanUser(ctx context.Context, actor, userID, reason string) error {
+	userID = strings.TrimSpace(userID)
+	if userID == "" {
+		return ErrInvalidUserID
+	}
+	if userID == actor {
+		return ErrSelfBan
+	}
+
+	err := a.repo.BanUser(ctx, &entities.Ban{
+		UserID:    userID,
+		Reason:    reason,
+		BannedBy:  actor,
+		CreatedAt: a.now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return a.audit(ctx, actor, entities.AuditBanUser, userID, reason)
+}
+
+// UnbanUser lifts the ban of the user.
+// It returns entities.ErrBanNotFound if the user is not banned.
+func (a *Admin) UnbanUser(ctx context.Context, actor, userID, reason string) error {
+	if err := a.repo.UnbanUser(ctx, userID); err != nil {
+		return err
+	}
+
+	return a.audit(ctx, actor, entities.AuditUnbanUser, userID, reason)
+}
+
+// Banned checks if the user is banned.
+func (a *Admin) Banned(ctx context.Context, userID string) (bool, error) {
+	_, err := a.repo.GetBan(ctx, userID)
+	if errors.Is(err, entities.ErrBanNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// AuditTrail returns the most recent actions of the administrators, the latest first.
+// A limit out of range is replaced by DefaultLimit or capped at MaxLimit.
+func (a *Admin) AuditTrail(ctx context.Context, n int) ([]*entities.AuditEntry, error) {
+	return a.repo.ListAuditEntries(ctx, limit(n))
+}
+
+// audit records the action of the administrator in the audit trail.
+// The action has already been made, so a failure is reported with the action it misses.
+func (a *Admin) audit(ctx context.Context, actor, action, target, details string) error {
+	err := a.repo.AddAuditEntry(ctx, &entities.AuditEntry{
+		Actor:     actor,
+		Action:    action,
+		Target:    target,
+		Details:   details,
+		CreatedAt: a.now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("cannot record %s of %s in the audit trail: %w", action, target, err)
+	}
+
+	return nil
+}
+
+// limit returns the number of results to return for the requested number.
+func limit(n int) int {
+	if n <= 0 {
+		return DefaultLimit
+	}
+	return min(n, MaxLimit)
+}
+
+// details joins the reason of an action with what the action changed.
+func details(reason, change string) string {
+	if reason = strings.TrimSpace(reason); reason == "" {
+		return change
+	}
+	return change + ": " + reason
+}
@@ -0,0 +1,161 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+	"github.com/Azzonya/go-shortener/internal/repo/inmemory"
+)
+
+func newTestAdmin(t *testing.T) *Admin {
+	t.Helper()
+
+	storage, err := inmemory.New("")
+	if err != nil {
+		t.Fatalf("inmemory.New() error = %v", err)
+	}
+
+	for _, shortURL := range []string{"spam", "good"} {
+		if err = storage.Add(context.Background(), "https://example.com/"+shortURL, shortURL, "owner", nil); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	a := New(storage)
+	a.now = func() time.Time { return time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC) }
+
+	return a
+}
+
+func TestAdmin_Actions(t *testing.T) {
+	tests := []struct {
+		name       string
+		action     func(ctx context.Context, a *Admin) error
+		wantErr    error
+		wantAudit  string
+		wantTarget string
+	}{
+		{
+			name:       "Disable link",
+			action:     func(ctx context.Context, a *Admin) error { return a.DisableLink(ctx, "admin", "spam", "phishing") },
+			wantAudit:  entities.AuditDisableURL,
+			wantTarget: "spam",
+		},
+		{
+			name:    "Disable missing link",
+			action:  func(ctx context.Context, a *Admin) error { return a.DisableLink(ctx, "admin", "missing", "") },
+			wantErr: entities.ErrURLNotFound,
+		},
+		{
+			name:       "Enable link",
+			action:     func(ctx context.Context, a *Admin) error { return a.EnableLink(ctx, "admin", "spam", "") },
+			wantAudit:  entities.AuditEnableURL,
+			wantTarget: "spam",
+		},
+		{
+			name:       "Reassign link",
+			action:     func(ctx context.Context, a *Admin) error { return a.ReassignLink(ctx, "admin", "good", "heir", "") },
+			wantAudit:  entities.AuditReassignURL,
+			wantTarget: "good",
+		},
+		{
+			name:    "Reassign link to nobody",
+			action:  func(ctx context.Context, a *Admin) error { return a.ReassignLink(ctx, "admin", "good", " ", "") },
+			wantErr: ErrInvalidUserID,
+		},
+		{
+			name:       "Ban user",
+			action:     func(ctx context.Context, a *Admin) error { return a.BanUser(ctx, "admin", "owner", "spam") },
+			wantAudit:  entities.AuditBanUser,
+			wantTarget: "owner",
+		},
+		{
+			name:    "Ban self",
+			action:  func(ctx context.Context, a *Admin) error { return a.BanUser(ctx, "admin", "admin", "") },
+			wantErr: ErrSelfBan,
+		},
+		{
+			name:    "Unban user not banned",
+			action:  func(ctx context.Context, a *Admin) error { return a.UnbanUser(ctx, "admin", "owner", "") },
+			wantErr: entities.ErrBanNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAdmin(t)
+			ctx := context.Background()
+
+			err := tt.action(ctx, a)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("action error = %v, want %v", err, tt.wantErr)
+			}
+
+			entries, err := a.AuditTrail(ctx, 0)
+			if err != nil {
+				t.Fatalf("AuditTrail() error = %v", err)
+			}
+			if tt.wantAudit == "" {
+				if len(entries) != 0 {
+					t.Errorf("AuditTrail() = %+v, want no entry for a failed action", entries)
+				}
+				return
+			}
+			if len(entries) != 1 || entries[0].Action != tt.wantAudit || entries[0].Target != tt.wantTarget || entries[0].Actor != "admin" {
+				t.Errorf("AuditTrail() = %+v, want a %s of %s by admin", entries, tt.wantAudit, tt.wantTarget)
+			}
+		})
+	}
+}
+
+func TestAdmin_Moderation(t *testing.T) {
+	a := newTestAdmin(t)
+	ctx := context.Background()
+
+	if err := a.DisableLink(ctx, "admin", "spam", "phishing"); err != nil {
+		t.Fatalf("DisableLink() error = %v", err)
+	}
+	if err := a.ReassignLink(ctx, "admin", "good", "heir", "owner left"); err != nil {
+		t.Fatalf("ReassignLink() error = %v", err)
+	}
+
+	links, err := a.SearchLinks(ctx, entities.LinkFilter{Query: " example.com "})
+	if err != nil {
+		t.Fatalf("SearchLinks() error = %v", err)
+	}
+	if len(links) != 2 || !links[0].Disabled || links[1].UserID != "heir" {
+		t.Errorf("SearchLinks() = %+v, want the disabled spam and the reassigned good link", links)
+	}
+
+	if err = a.BanUser(ctx, "admin", "owner", "spam"); err != nil {
+		t.Fatalf("BanUser() error = %v", err)
+	}
+	if banned, err := a.Banned(ctx, "owner"); err != nil || !banned {
+		t.Errorf("Banned() = %v, %v, want true", banned, err)
+	}
+
+	if err = a.UnbanUser(ctx, "admin", "owner", "appeal"); err != nil {
+		t.Fatalf("UnbanUser() error = %v", err)
+	}
+	if banned, err := a.Banned(ctx, "owner"); err != nil || banned {
+		t.Errorf("Banned() of an unbanned user = %v, %v, want false", banned, err)
+	}
+
+	entries, err := a.AuditTrail(ctx, 2)
+	if err != nil {
+		t.Fatalf("AuditTrail() error = %v", err)
+	}
+	if len(entries) != 2 || entries[0].Action != entities.AuditUnbanUser || entries[1].Action != entities.AuditBanUser {
+		t.Errorf("AuditTrail() = %+v, want the unban then the ban", entries)
+	}
+
+	all, _ := a.AuditTrail(ctx, 0)
+	if len(all) != 4 {
+		t.Fatalf("AuditTrail() = %v entries, want 4", len(all))
+	}
+	if want := "from owner to heir: owner left"; all[2].Details != want {
+		t.Errorf("AuditTrail() reassignment details = %q, want %q", all[2].Details, want)
+	}
+}
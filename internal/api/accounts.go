@@ -0,0 +1,134 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Azzonya/go-shortener/internal/account"
+	"github.com/Azzonya/go-shortener/internal/entities"
+	"github.com/Azzonya/go-shortener/internal/session"
+	"github.com/Azzonya/go-shortener/internal/user"
+)
+
+// CredentialsRequest represents the login and password of a signup or login request.
+type CredentialsRequest struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+// Signup handles HTTP requests registering a new account.
+// The links of the anonymous user of the request are transferred to the account,
+// which is logged in with a new session cookie.
+func (o *Rest) Signup(c *gin.Context) {
+	creds, anonymousID, ok := credentials(c)
+	if !ok {
+		return
+	}
+
+	acc, err := o.accounts.Signup(c.Request.Context(), creds.Login, creds.Password, anonymousID)
+	if errors.Is(err, account.ErrInvalidLogin) || errors.Is(err, account.ErrInvalidPassword) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid credentials",
+			"error":   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, entities.ErrLoginExists) {
+		c.JSON(http.StatusConflict, gin.H{
+			"message": "Login already taken",
+			"error":   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to sign up",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	o.startSession(c, acc, http.StatusCreated)
+}
+
+// Login handles HTTP requests logging in to an account with a new session cookie.
+// The links of the anonymous user of the request stay with it, only signing up transfers them.
+func (o *Rest) Login(c *gin.Context) {
+	creds, _, ok := credentials(c)
+	if !ok {
+		return
+	}
+
+	acc, err := o.accounts.Login(c.Request.Context(), creds.Login, creds.Password)
+	if errors.Is(err, account.ErrWrongCredentials) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": "Failed to log in",
+			"error":   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to log in",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	o.startSession(c, acc, http.StatusOK)
+}
+
+// Logout handles HTTP requests logging out by deleting the session cookie.
+// The next request without a cookie is made by a new anonymous user.
+func (o *Rest) Logout(c *gin.Context) {
+	c.Header("Set-Cookie", o.auth.ExpiredCookie().String())
+	c.Status(http.StatusNoContent)
+}
+
+// startSession answers the request with the account and a session cookie of its user, carrying its role.
+func (o *Rest) startSession(c *gin.Context, acc *entities.Account, status int) {
+	u := user.NewWithID(acc.ID)
+	u.Role = acc.Role
+
+	sessionCookie, err := o.auth.CreateJWTCookie(u)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to create session",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.Header("Set-Cookie", sessionCookie.String())
+	c.JSON(status, acc)
+}
+
+// credentials reads the credentials of the request and returns them with the ID of the user
+// making the request, empty for a new user who has no links yet.
+// It answers the request itself if the body is malformed or the request is authenticated by an API key.
+func credentials(c *gin.Context) (*CredentialsRequest, string, bool) {
+	if _, scoped := session.GetScopes(c.Request.Context()); scoped {
+		c.JSON(http.StatusForbidden, gin.H{
+			"message": "API keys can not log in",
+		})
+		return nil, "", false
+	}
+
+	var creds CredentialsRequest
+	if err := c.BindJSON(&creds); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Failed to read body",
+			"error":   err.Error(),
+		})
+		return nil, "", false
+	}
+
+	var anonymousID string
+	if u, err := session.GetUser(c.Request.Context()); err == nil {
+		anonymousID = u.ID
+	}
+
+	return &creds, anonymousID, true
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Azzonya/go-shortener/internal/account"
+	"github.com/Azzonya/go-shortener/internal/auth"
+	"github.com/Azzonya/go-shortener/internal/entities"
+	"github.com/Azzonya/go-shortener/internal/middleware"
+	"github.com/Azzonya/go-shortener/internal/repo/inmemory"
+	shortener_service "github.com/Azzonya/go-shortener/internal/shortener"
+	"github.com/Azzonya/go-shortener/internal/user"
+)
+
+func TestRest_Accounts(t *testing.T) {
+	repo, err := inmemory.New("")
+	require.NoError(t, err)
+
+	rest := &Rest{
+		shortener: shortener_service.New("http://localhost:8080", repo, nil, 0, 0),
+		auth:      auth.New("supersecret"),
+	}
+	rest.SetAccounts(account.New(repo))
+
+	r := gin.New()
+	r.Use(middleware.Authenticate(rest.auth))
+	rest.SetRouters(r)
+
+	serve := func(method, target, body string, cookie *http.Cookie) *http.Response {
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		if cookie != nil {
+			request.AddCookie(cookie)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, request)
+		return w.Result()
+	}
+	sessionCookie := func(result *http.Response) *http.Cookie {
+		cookies := result.Cookies()
+		require.Len(t, cookies, 1)
+		return cookies[0]
+	}
+
+	// An anonymous user shortens a link, then signs up from the same browser.
+	result := serve(http.MethodPost, "/", "https://example.com/anonymous", nil)
+	result.Body.Close()
+	require.Equal(t, http.StatusCreated, result.StatusCode)
+	anonymous := sessionCookie(result)
+
+	result = serve(http.MethodPost, "/api/user/signup", `{"login":"alice","password":"correct horse"}`, anonymous)
+	defer result.Body.Close()
+	require.Equal(t, http.StatusCreated, result.StatusCode)
+
+	var signedUp entities.Account
+	require.NoError(t, json.NewDecoder(result.Body).Decode(&signedUp))
+	assert.Equal(t, "alice", signedUp.Login)
+	aliceBrowser := sessionCookie(result)
+
+	result = serve(http.MethodPost, "/api/user/signup", `{"login":"alice","password":"other password"}`, nil)
+	result.Body.Close()
+	assert.Equal(t, http.StatusConflict, result.StatusCode)
+
+	result = serve(http.MethodPost, "/api/user/login", `{"login":"alice","password":"wrong horse"}`, nil)
+	result.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, result.StatusCode)
+
+	// Logging in from another browser reaches the links made anonymously in the first one,
+	// and leaves the links made anonymously in this browser to their anonymous user.
+	result = serve(http.MethodPost, "/", "https://example.com/other-browser", nil)
+	result.Body.Close()
+	require.Equal(t, http.StatusCreated, result.StatusCode)
+	otherAnonymous := sessionCookie(result)
+
+	result = serve(http.MethodPost, "/api/user/login", `{"login":"alice","password":"correct horse"}`, otherAnonymous)
+	result.Body.Close()
+	require.Equal(t, http.StatusOK, result.StatusCode)
+	otherBrowser := sessionCookie(result)
+
+	for _, cookie := range []*http.Cookie{aliceBrowser, otherBrowser} {
+		result = serve(http.MethodGet, "/api/user/urls", "", cookie)
+		var urls []*entities.ReqListAll
+		require.NoError(t, json.NewDecoder(result.Body).Decode(&urls))
+		result.Body.Close()
+		if assert.Len(t, urls, 1) {
+			assert.Equal(t, "https://example.com/anonymous", urls[0].OriginalURL)
+		}
+	}
+
+	result = serve(http.MethodGet, "/api/user/urls", "", otherAnonymous)
+	result.Body.Close()
+	assert.Equal(t, http.StatusOK, result.StatusCode, "the anonymous user keeps its links")
+
+	result = serve(http.MethodPost, "/api/user/logout", "", otherBrowser)
+	result.Body.Close()
+	assert.Equal(t, http.StatusNoContent, result.StatusCode)
+	if logout := sessionCookie(result); assert.NotNil(t, logout) {
+		assert.Less(t, logout.MaxAge, 0)
+	}
+}
+
+func TestRest_AuthRateLimit(t *testing.T) {
+	repo, err := inmemory.New("")
+	require.NoError(t, err)
+
+	rest := &Rest{
+		shortener: shortener_service.New("http://localhost:8080", repo, nil, 0, 0),
+		auth:      auth.New("supersecret"),
+	}
+	rest.SetAccounts(account.New(repo))
+	rest.SetRateLimits(RateLimits{
+		Auth: middleware.NewRateLimiter(middleware.PerMinute(1, 2), time.Minute),
+	})
+
+	r := gin.New()
+	r.Use(middleware.Authenticate(rest.auth))
+	rest.SetRouters(r)
+
+	login := func(remoteAddr string, cookie *http.Cookie) int {
+		request := httptest.NewRequest(http.MethodPost, "/api/user/login", strings.NewReader(`{"login":"alice","password":"guessed"}`))
+		request.RemoteAddr = remoteAddr
+		if cookie != nil {
+			request.AddCookie(cookie)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, request)
+		return w.Code
+	}
+
+	// Password guesses are limited by address, whatever session they are made with.
+	assert.Equal(t, http.StatusUnauthorized, login("192.0.2.1:1", nil))
+	cookie, err := rest.auth.CreateJWTCookie(user.NewWithID("other"))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, login("192.0.2.1:2", cookie))
+	assert.Equal(t, http.StatusTooManyRequests, login("192.0.2.1:3", nil))
+
+	assert.Equal(t, http.StatusUnauthorized, login("192.0.2.2:1", nil), "other addresses have their own bucket")
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Azzonya/go-shortener/internal/admin"
+	"github.com/Azzonya/go-shortener/internal/entities"
+	"github.com/Azzonya/go-shortener/internal/session"
+)
+
+// ModerationRequest represents the optional body of a moderation request.
+type ModerationRequest struct {
+	Reason string `json:"reason"`            // Reason is recorded in the audit trail.
+	UserID string `json:"user_id,omitempty"` // UserID is the new owner of a reassigned link.
+}
+
+// SearchLinks handles HTTP requests searching the links of every user.
+// The optional q, user_id, limit and offset query parameters filter and page the links.
+func (o *Rest) SearchLinks(c *gin.Context) {
+	limit, errL := queryInt(c, "limit")
+	offset, errO := queryInt(c, "offset")
+	if err := errors.Join(errL, errO); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid paging",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	links, err := o.admin.SearchLinks(c.Request.Context(), entities.LinkFilter{
+		Query:  c.Query("q"),
+		UserID: c.Query("user_id"),
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to search links",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, links)
+}
+
+// DisableLink handles HTTP requests disabling a link, whoever owns it.
+func (o *Rest) DisableLink(c *gin.Context) {
+	req, ok := moderationRequest(c)
+	if !ok {
+		return
+	}
+
+	err := o.admin.DisableLink(c.Request.Context(), actor(c), c.Param("id"), req.Reason)
+	moderated(c, err, "Failed to disable link")
+}
+
+// EnableLink handles HTTP requests enabling a disabled link again.
+func (o *Rest) EnableLink(c *gin.Context) {
+	req, ok := moderationRequest(c)
+	if !ok {
+		return
+	}
+
+	err := o.admin.EnableLink(c.Request.Context(), actor(c), c.Param("id"), req.Reason)
+	moderated(c, err, "Failed to enable link")
+}
+
+// ReassignLink handles HTTP requests giving a link to the user of the request body.
+func (o *Rest) ReassignLink(c *gin.Context) {
+	req, ok := moderationRequest(c)
+	if !ok {
+		return
+	}
+
+	err := o.admin.ReassignLink(c.Request.Context(), actor(c), c.Param("id"), req.UserID, req.Reason)
+	moderated(c, err, "Failed to reassign link")
+}
+
+// BanUser handles HTTP requests banning a user.
+func (o *Rest) BanUser(c *gin.Context) {
+	req, ok := moderationRequest(c)
+	if !ok {
+		return
+	}
+
+	err := o.admin.BanUser(c.Request.Context(), actor(c), c.Param("id"), req.Reason)
+	moderated(c, err, "Failed to ban user")
+}
+
+// UnbanUser handles HTTP requests lifting the ban of a user.
+func (o *Rest) UnbanUser(c *gin.Context) {
+	req, ok := moderationRequest(c)
+	if !ok {
+		return
+	}
+
+	err := o.admin.UnbanUser(c.Request.Context(), actor(c), c.Param("id"), req.Reason)
+	moderated(c, err, "Failed to unban user")
+}
+
+// AuditTrail handles HTTP requests listing the most recent actions of the administrators.
+// The optional limit query parameter sets the number of actions listed.
+func (o *Rest) AuditTrail(c *gin.Context) {
+	limit, err := queryInt(c, "limit")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid limit",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	entries, err := o.admin.AuditTrail(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to list audit trail",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// moderated answers a moderation request with the outcome of the action.
+func moderated(c *gin.Context, err error, message string) {
+	switch {
+	case err == nil:
+		c.Status(http.StatusNoContent)
+	case errors.Is(err, entities.ErrURLNotFound), errors.Is(err, entities.ErrBanNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": message,
+			"error":   err.Error(),
+		})
+	case errors.Is(err, admin.ErrInvalidUserID), errors.Is(err, admin.ErrSelfBan):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": message,
+			"error":   err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": message,
+			"error":   err.Error(),
+		})
+	}
+}
+
+// moderationRequest reads the optional body of a moderation request,
+// answering the request itself if the body is malformed.
+func moderationRequest(c *gin.Context) (*ModerationRequest, bool) {
+	var req ModerationRequest
+	if c.Request.ContentLength == 0 {
+		return &req, true
+	}
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Failed to read body",
+			"error":   err.Error(),
+		})
+		return nil, false
+	}
+
+	return &req, true
+}
+
+// actor returns the ID of the administrator making the request, checked by middleware.RequireAdmin.
+func actor(c *gin.Context) string {
+	u, _ := session.GetUserFromContext(c.Request.Context())
+	return u.ID
+}
+
+// queryInt returns the integer query parameter, zero if it is missing.
+func queryInt(c *gin.Context, key string) (int, error) {
+	value := c.Query(key)
+	if value == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, errors.New(key + " must be a non-negative integer")
+	}
+
+	return n, nil
+}
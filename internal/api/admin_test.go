@@ -0,0 +1,184 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Azzonya/go-shortener/internal/account"
+	"github.com/Azzonya/go-shortener/internal/admin"
+	"github.com/Azzonya/go-shortener/internal/auth"
+	"github.com/Azzonya/go-shortener/internal/entities"
+	"github.com/Azzonya/go-shortener/internal/middleware"
+	"github.com/Azzonya/go-shortener/internal/repo/inmemory"
+	shortener_service "github.com/Azzonya/go-shortener/internal/shortener"
+)
+
+func TestRest_Admin(t *testing.T) {
+	repo, err := inmemory.New("")
+	require.NoError(t, err)
+
+	accounts := account.New(repo)
+	accounts.SetAdmins([]string{"root"})
+
+	rest := &Rest{
+		shortener: shortener_service.New("http://localhost:8080", repo, nil, 0, 0),
+		auth:      auth.New("supersecret"),
+	}
+	rest.SetAccounts(accounts)
+	rest.SetAdmin(admin.New(repo))
+
+	r := gin.New()
+	r.Use(middleware.Authenticate(rest.auth))
+	rest.SetRouters(r)
+
+	serve := func(method, target, body string, cookie *http.Cookie) (int, string) {
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		if cookie != nil {
+			request.AddCookie(cookie)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, request)
+
+		data, err := io.ReadAll(w.Result().Body)
+		require.NoError(t, err)
+		return w.Code, string(data)
+	}
+	signup := func(login string) (*http.Cookie, *entities.Account) {
+		request := httptest.NewRequest(http.MethodPost, "/api/user/signup", strings.NewReader(`{"login":"`+login+`","password":"correct horse"}`))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, request)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var acc entities.Account
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&acc))
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+		return cookies[0], &acc
+	}
+
+	root, rootAccount := signup("root")
+	assert.Equal(t, "admin", rootAccount.Role)
+	alice, aliceAccount := signup("alice")
+	assert.Empty(t, aliceAccount.Role)
+
+	status, body := serve(http.MethodPost, "/", "https://phishing.example", alice)
+	require.Equal(t, http.StatusCreated, status)
+	shortURL := body[strings.LastIndex(body, "/")+1:]
+
+	// Regular users can not moderate.
+	status, _ = serve(http.MethodGet, "/api/admin/urls", "", alice)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	status, body = serve(http.MethodGet, "/api/admin/urls?q=phishing", "", root)
+	require.Equal(t, http.StatusOK, status)
+	var links []*entities.Link
+	require.NoError(t, json.Unmarshal([]byte(body), &links))
+	if assert.Len(t, links, 1) {
+		assert.Equal(t, shortURL, links[0].ShortURL)
+		assert.Equal(t, aliceAccount.ID, links[0].UserID)
+	}
+
+	status, _ = serve(http.MethodGet, "/api/admin/urls?limit=many", "", root)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// A disabled link no longer redirects, whoever owns it.
+	status, _ = serve(http.MethodPost, "/api/admin/urls/"+shortURL+"/disable", `{"reason":"phishing"}`, root)
+	assert.Equal(t, http.StatusNoContent, status)
+	status, _ = serve(http.MethodGet, "/"+shortURL, "", nil)
+	assert.Equal(t, http.StatusGone, status)
+
+	status, _ = serve(http.MethodPost, "/api/admin/urls/missing/disable", "", root)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, _ = serve(http.MethodPut, "/api/admin/urls/"+shortURL+"/owner", `{"user_id":"`+rootAccount.ID+`"}`, root)
+	assert.Equal(t, http.StatusNoContent, status)
+	status, _ = serve(http.MethodPut, "/api/admin/urls/"+shortURL+"/owner", `{}`, root)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// Banned users can no longer create or manage links until the ban is lifted, but their links still redirect.
+	status, body = serve(http.MethodPost, "/", "https://legit.example", alice)
+	require.Equal(t, http.StatusCreated, status)
+	kept := body[strings.LastIndex(body, "/")+1:]
+
+	status, _ = serve(http.MethodPut, "/api/admin/bans/"+aliceAccount.ID, `{"reason":"phishing"}`, root)
+	assert.Equal(t, http.StatusNoContent, status)
+	status, _ = serve(http.MethodGet, "/"+kept, "", alice)
+	assert.Equal(t, http.StatusTemporaryRedirect, status)
+	status, _ = serve(http.MethodPost, "/", "https://phishing.example/again", alice)
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = serve(http.MethodGet, "/api/user/urls", "", alice)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	status, _ = serve(http.MethodDelete, "/api/admin/bans/"+aliceAccount.ID, "", root)
+	assert.Equal(t, http.StatusNoContent, status)
+	status, _ = serve(http.MethodDelete, "/api/admin/bans/"+aliceAccount.ID, "", root)
+	assert.Equal(t, http.StatusNotFound, status)
+	status, body = serve(http.MethodGet, "/api/user/urls", "", alice)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, kept)
+	assert.NotContains(t, body, shortURL, "the reassigned link is no longer listed")
+
+	status, body = serve(http.MethodGet, "/api/admin/audit", "", root)
+	require.Equal(t, http.StatusOK, status)
+	var entries []*entities.AuditEntry
+	require.NoError(t, json.Unmarshal([]byte(body), &entries))
+	var actions []string
+	for _, entry := range entries {
+		actions = append(actions, entry.Action)
+		assert.Equal(t, rootAccount.ID, entry.Actor)
+	}
+	assert.Equal(t, []string{entities.AuditUnbanUser, entities.AuditBanUser, entities.AuditReassignURL, entities.AuditDisableURL}, actions)
+
+	// An account removed from the administrators loses the role its token still carries.
+	accounts.SetAdmins(nil)
+	status, _ = serve(http.MethodGet, "/api/admin/audit", "", root)
+	assert.Equal(t, http.StatusForbidden, status)
+}
+
+func TestRest_SignupBanned(t *testing.T) {
+	repo, err := inmemory.New("")
+	require.NoError(t, err)
+
+	rest := &Rest{
+		shortener: shortener_service.New("http://localhost:8080", repo, nil, 0, 0),
+		auth:      auth.New("supersecret"),
+	}
+	rest.SetAccounts(account.New(repo))
+	moderator := admin.New(repo)
+	rest.SetAdmin(moderator)
+
+	r := gin.New()
+	r.Use(middleware.Authenticate(rest.auth))
+	rest.SetRouters(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://phishing.example")))
+	require.Equal(t, http.StatusCreated, w.Code)
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+
+	u, _, err := rest.auth.VerifyToken(cookies[0].Value)
+	require.NoError(t, err)
+	require.NoError(t, moderator.BanUser(context.Background(), "admin", u.ID, "phishing"))
+
+	// Signing up would move the links of the banned anonymous user out of reach of the ban.
+	request := httptest.NewRequest(http.MethodPost, "/api/user/signup", strings.NewReader(`{"login":"mallory","password":"correct horse"}`))
+	request.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	urls, err := repo.ListAll(context.Background(), u.ID)
+	require.NoError(t, err)
+	assert.Len(t, urls, 1, "the links stay with the banned user")
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Azzonya/go-shortener/internal/apikey"
+	"github.com/Azzonya/go-shortener/internal/entities"
+	"github.com/Azzonya/go-shortener/internal/session"
+	"github.com/Azzonya/go-shortener/internal/user"
+)
+
+// APIKeyRequest represents the request to mint an API key with the given name and scopes.
+type APIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// APIKeyResponse represents a newly minted API key, the only response carrying the key itself.
+type APIKeyResponse struct {
+	*entities.APIKey
+	Key string `json:"key"`
+}
+
+// CreateAPIKey handles HTTP requests minting an API key of the user.
+func (o *Rest) CreateAPIKey(c *gin.Context) {
+	u, ok := keyOwner(c)
+	if !ok {
+		return
+	}
+
+	var req APIKeyRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Failed to read body",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	key, stored, err := o.keys.Mint(c.Request.Context(), u.ID, req.Name, req.Scopes)
+	if errors.Is(err, apikey.ErrInvalidName) || errors.Is(err, apikey.ErrInvalidScopes) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid API key",
+			"error":   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to create API key",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, APIKeyResponse{APIKey: stored, Key: key})
+}
+
+// ListAPIKeys handles HTTP requests listing the API keys of the user, including revoked keys.
+func (o *Rest) ListAPIKeys(c *gin.Context) {
+	u, ok := keyOwner(c)
+	if !ok {
+		return
+	}
+
+	keys, err := o.keys.List(c.Request.Context(), u.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to list API keys",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// RevokeAPIKey handles HTTP requests revoking an API key of the user.
+func (o *Rest) RevokeAPIKey(c *gin.Context) {
+	u, ok := keyOwner(c)
+	if !ok {
+		return
+	}
+
+	err := o.keys.Revoke(c.Request.Context(), c.Param("id"), u.ID)
+	if errors.Is(err, entities.ErrAPIKeyNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "API key not found",
+			"error":   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to revoke API key",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// keyOwner returns the user managing the API keys, answering the request itself if there is none.
+// Keys are managed with a session cookie only, so that a leaked key can not mint more keys.
+func keyOwner(c *gin.Context) (*user.User, bool) {
+	if _, scoped := session.GetScopes(c.Request.Context()); scoped {
+		c.JSON(http.StatusForbidden, gin.H{
+			"message": "API keys can not manage API keys",
+		})
+		return nil, false
+	}
+
+	u, err := session.GetUser(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": "no cookie",
+			"error":   err.Error(),
+		})
+		return nil, false
+	}
+
+	return u, true
+}
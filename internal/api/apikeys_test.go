@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Azzonya/go-shortener/internal/apikey"
+	"github.com/Azzonya/go-shortener/internal/auth"
+	"github.com/Azzonya/go-shortener/internal/entities"
+	"github.com/Azzonya/go-shortener/internal/middleware"
+	"github.com/Azzonya/go-shortener/internal/repo/inmemory"
+	shortener_service "github.com/Azzonya/go-shortener/internal/shortener"
+	"github.com/Azzonya/go-shortener/internal/user"
+)
+
+func TestRest_APIKeys(t *testing.T) {
+	repo, err := inmemory.New("")
+	require.NoError(t, err)
+
+	authorizer := auth.New("supersecret")
+	rest := &Rest{shortener: shortener_service.New("http://localhost:8080", repo, nil, 0, 0)}
+	rest.SetAPIKeys(apikey.New(repo))
+
+	r := gin.New()
+	r.Use(middleware.AuthenticateAPIKey(rest.keys), middleware.Authenticate(authorizer))
+	rest.SetRouters(r)
+
+	cookie, err := authorizer.CreateJWTCookie(user.NewWithID("owner"))
+	require.NoError(t, err)
+
+	serve := func(method, target, body string, header http.Header) (int, []byte) {
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		for name, values := range header {
+			request.Header[name] = values
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, request)
+
+		result := w.Result()
+		defer result.Body.Close()
+
+		data, err := io.ReadAll(result.Body)
+		require.NoError(t, err)
+
+		return result.StatusCode, data
+	}
+	withCookie := http.Header{"Cookie": {cookie.String()}}
+
+	status, body := serve(http.MethodPost, "/api/user/keys", `{"name":"ci","scopes":["shorten","read"]}`, withCookie)
+	require.Equal(t, http.StatusCreated, status, string(body))
+
+	var minted APIKeyResponse
+	require.NoError(t, json.Unmarshal(body, &minted))
+	assert.Equal(t, "ci", minted.Name)
+	assert.Equal(t, []string{entities.ScopeShorten, entities.ScopeRead}, minted.Scopes)
+	assert.NotContains(t, string(body), "hash")
+
+	withKey := http.Header{"X-Api-Key": {minted.Key}}
+	withBearer := http.Header{"Authorization": {"Bearer " + minted.Key}}
+
+	status, body = serve(http.MethodPost, "/api/shorten", `{"url":"https://example.com"}`, withKey)
+	assert.Equal(t, http.StatusCreated, status, string(body))
+
+	status, body = serve(http.MethodGet, "/api/user/urls", "", withBearer)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, string(body), "https://example.com")
+
+	status, _ = serve(http.MethodDelete, "/api/user/urls", `[]`, withKey)
+	assert.Equal(t, http.StatusForbidden, status, "key without the delete scope")
+
+	status, _ = serve(http.MethodPost, "/api/user/keys", `{"name":"more","scopes":["delete"]}`, withKey)
+	assert.Equal(t, http.StatusForbidden, status, "key minting keys")
+
+	status, _ = serve(http.MethodGet, "/api/user/urls", "", http.Header{"X-Api-Key": {"sk_invalid"}})
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	status, _ = serve(http.MethodPost, "/api/user/keys", `{"name":"bad","scopes":["admin"]}`, withCookie)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = serve(http.MethodDelete, "/api/user/keys/"+minted.ID, "", withCookie)
+	assert.Equal(t, http.StatusNoContent, status)
+
+	status, _ = serve(http.MethodDelete, "/api/user/keys/missing", "", withCookie)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, _ = serve(http.MethodGet, "/api/user/urls", "", withKey)
+	assert.Equal(t, http.StatusUnauthorized, status, "revoked key")
+
+	status, body = serve(http.MethodGet, "/api/user/keys", "", withCookie)
+	require.Equal(t, http.StatusOK, status)
+
+	var keys []*entities.APIKey
+	require.NoError(t, json.Unmarshal(body, &keys))
+	if assert.Len(t, keys, 1) {
+		assert.True(t, keys[0].Revoked())
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"github.com/Azzonya/go-shortener/internal/auth"
 	"github.com/Azzonya/go-shortener/internal/repo/pg"
 	"github.com/Azzonya/go-shortener/internal/shortener"
 	"github.com/Azzonya/go-shortener/pkg"
@@ -12,11 +13,14 @@ func Example() {
 		panic(err)
 	}
 
-	repo := pg.New(db)
+	repo, err := pg.New(db)
+	if err != nil {
+		panic(err)
+	}
 
-	shortenerService := shortener.New("http://localhost:8594", repo)
+	shortenerService := shortener.New("http://localhost:8594", repo, nil, 0, 0)
 
-	api := New(shortenerService, "jwt_secret")
+	api := New(shortenerService, nil, auth.New("jwt_secret"), nil)
 
 	api.Start("localhost:8594", "localhost:8082")
 }
@@ -2,12 +2,18 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/Azzonya/go-shortener/internal/entities"
 
@@ -15,8 +21,12 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/Azzonya/go-shortener/internal/auth"
+	"github.com/Azzonya/go-shortener/internal/middleware"
 	"github.com/Azzonya/go-shortener/internal/repo/inmemory"
+	"github.com/Azzonya/go-shortener/internal/session"
 	shortener_service "github.com/Azzonya/go-shortener/internal/shortener"
+	"github.com/Azzonya/go-shortener/internal/user"
 )
 
 func TestRest_ShortenJSON(t *testing.T) {
@@ -46,10 +56,10 @@ func TestRest_ShortenJSON(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			repo, err := inmemory.New("/tmp/short-url-repo.json")
+			repo, err := inmemory.New(filepath.Join(t.TempDir(), "short-url-repo.json"))
 			require.NoError(t, err)
 
-			tt.rest.shortener = shortener_service.New("http://localhost:8080", repo)
+			tt.rest.shortener = shortener_service.New("http://localhost:8080", repo, nil, 0, 0)
 
 			r := gin.Default()
 			r.POST(tt.request, tt.rest.ShortenJSON)
@@ -88,7 +98,7 @@ func TestRest_ShortenJSON(t *testing.T) {
 
 			parts := strings.Split(shortURL, "/")
 
-			originalURL, exist := tt.rest.shortener.GetOneByShortURL(parts[len(parts)-1])
+			originalURL, exist := tt.rest.shortener.GetOneByShortURL(context.Background(), parts[len(parts)-1])
 			if !exist {
 				require.Fail(t, "Expected short URL in urlMap")
 			}
@@ -98,6 +108,96 @@ func TestRest_ShortenJSON(t *testing.T) {
 	}
 }
 
+func TestRest_ShortenJSONAlias(t *testing.T) {
+	repo, err := inmemory.New("")
+	require.NoError(t, err)
+
+	rest := &Rest{shortener: shortener_service.New("http://localhost:8080", repo, nil, 0, 0)}
+
+	r := gin.Default()
+	r.POST("/api/shorten", rest.ShortenJSON)
+	r.POST("/api/shorten/batch", rest.ShortenURLs)
+
+	tests := []struct {
+		name       string
+		request    string
+		body       any
+		wantStatus int
+		wantResult string
+	}{
+		{
+			name:       "alias created",
+			request:    "/api/shorten",
+			body:       &Request{URL: "www.example.com/sale", Alias: "spring-sale"},
+			wantStatus: http.StatusCreated,
+			wantResult: "http://localhost:8080/spring-sale",
+		},
+		{
+			name:       "alias taken",
+			request:    "/api/shorten",
+			body:       &Request{URL: "www.example.com/other", Alias: "spring-sale"},
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "original URL already shortened",
+			request:    "/api/shorten",
+			body:       &Request{URL: "www.example.com/sale", Alias: "summer-sale"},
+			wantStatus: http.StatusConflict,
+			wantResult: "http://localhost:8080/spring-sale",
+		},
+		{
+			name:       "reserved alias",
+			request:    "/api/shorten",
+			body:       &Request{URL: "www.example.com/api", Alias: "api"},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid alias",
+			request:    "/api/shorten",
+			body:       &Request{URL: "www.example.com/invalid", Alias: "spring sale"},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:    "batch alias taken",
+			request: "/api/shorten/batch",
+			body: []*entities.ReqURL{
+				{ID: "1", OriginalURL: "www.example.com/batch", Alias: "spring-sale"},
+			},
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:    "batch alias created",
+			request: "/api/shorten/batch",
+			body: []*entities.ReqURL{
+				{ID: "1", OriginalURL: "www.example.com/batch", Alias: "winter-sale"},
+				{ID: "2", OriginalURL: "www.example.com/batch2"},
+			},
+			wantStatus: http.StatusCreated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestBody, err := json.Marshal(tt.body)
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.request, bytes.NewBuffer(requestBody)))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+
+			if tt.wantResult != "" {
+				resp := Response{}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, tt.wantResult, resp.Result)
+			}
+		})
+	}
+
+	originalURL, exist := rest.shortener.GetOneByShortURL(context.Background(), "winter-sale")
+	assert.True(t, exist)
+	assert.Equal(t, "www.example.com/batch", originalURL)
+}
+
 func TestRest_Shorten(t *testing.T) {
 	type want struct {
 		contentType string
@@ -126,10 +226,10 @@ func TestRest_Shorten(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			repo, err := inmemory.New("/tmp/short-url-repo.json")
+			repo, err := inmemory.New(filepath.Join(t.TempDir(), "short-url-repo.json"))
 			require.NoError(t, err)
 
-			tt.rest.shortener = shortener_service.New("http://localhost:8080", repo)
+			tt.rest.shortener = shortener_service.New("http://localhost:8080", repo, nil, 0, 0)
 
 			r := gin.Default()
 			r.POST(tt.request, tt.rest.Shorten)
@@ -154,7 +254,7 @@ func TestRest_Shorten(t *testing.T) {
 
 			parts := strings.Split(shortURL, "/")
 
-			originalURL, exist := tt.rest.shortener.GetOneByShortURL(parts[len(parts)-1])
+			originalURL, exist := tt.rest.shortener.GetOneByShortURL(context.Background(), parts[len(parts)-1])
 			if !exist {
 				require.Fail(t, "Expected short URL in urlMap")
 			}
@@ -174,12 +274,15 @@ func TestRest_Redirect(t *testing.T) {
 		name          string
 		rest          Rest
 		requestMethod string
+		originalURL   string
+		expiresAt     time.Time
 		want          want
 	}{
 		{
 			name:          "1st test",
 			rest:          Rest{},
 			requestMethod: http.MethodGet,
+			originalURL:   "https://www.youtube.com",
 			want: want{
 				location:   "https://www.youtube.com",
 				statusCode: http.StatusTemporaryRedirect,
@@ -189,26 +292,53 @@ func TestRest_Redirect(t *testing.T) {
 			name:          "2nd test",
 			rest:          Rest{},
 			requestMethod: http.MethodGet,
+			originalURL:   "https://www.google.com",
 			want: want{
 				location:   "https://www.google.com",
 				statusCode: http.StatusTemporaryRedirect,
 			},
 		},
+		{
+			name:          "not expired yet",
+			rest:          Rest{},
+			requestMethod: http.MethodGet,
+			originalURL:   "https://www.google.com",
+			expiresAt:     time.Now().Add(time.Hour),
+			want: want{
+				location:   "https://www.google.com",
+				statusCode: http.StatusTemporaryRedirect,
+			},
+		},
+		{
+			name:          "expired",
+			rest:          Rest{},
+			requestMethod: http.MethodGet,
+			originalURL:   "https://www.google.com",
+			expiresAt:     time.Now().Add(-time.Hour),
+			want: want{
+				statusCode: http.StatusGone,
+			},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			var expiresAt *time.Time
+			if !tt.expiresAt.IsZero() {
+				expiresAt = &tt.expiresAt
+			}
+
 			r := gin.Default()
 			r.GET("/:id", tt.rest.Redirect)
 
 			testShortURL := "Abcdefgh"
 
-			repo, err := inmemory.New("/tmp/short-url-repo.json")
+			repo, err := inmemory.New(filepath.Join(t.TempDir(), "short-url-repo.json"))
 			require.NoError(t, err)
 
-			tt.rest.shortener = shortener_service.New("http://localhost:8080", repo)
+			tt.rest.shortener = shortener_service.New("http://localhost:8080", repo, nil, 0, 0)
 
-			err = repo.Add(tt.want.location, testShortURL, "")
+			err = repo.Add(context.Background(), tt.originalURL, testShortURL, "", expiresAt)
 			require.NoError(t, err)
 
 			request := httptest.NewRequest(tt.requestMethod, "/"+testShortURL, nil)
@@ -252,10 +382,10 @@ func TestRest_ShortenURLs(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			repo, err := inmemory.New("/tmp/short-url-repo.json")
+			repo, err := inmemory.New(filepath.Join(t.TempDir(), "short-url-repo.json"))
 			require.NoError(t, err)
 
-			tt.rest.shortener = shortener_service.New("http://localhost:8080", repo)
+			tt.rest.shortener = shortener_service.New("http://localhost:8080", repo, nil, 0, 0)
 
 			r := gin.Default()
 			r.POST(tt.request, tt.rest.ShortenURLs)
@@ -322,10 +452,10 @@ func TestRest_ListAll(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			repo, err := inmemory.New("/tmp/short-url-repo.json")
+			repo, err := inmemory.New(filepath.Join(t.TempDir(), "short-url-repo.json"))
 			require.NoError(t, err)
 
-			tt.rest.shortener = shortener_service.New("http://localhost:8080", repo)
+			tt.rest.shortener = shortener_service.New("http://localhost:8080", repo, nil, 0, 0)
 
 			r := gin.Default()
 			r.GET(tt.request, tt.rest.ListAll)
@@ -371,10 +501,10 @@ func TestRest_DeleteURLs(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			repo, err := inmemory.New("/tmp/short-url-repo.json")
+			repo, err := inmemory.New(filepath.Join(t.TempDir(), "short-url-repo.json"))
 			require.NoError(t, err)
 
-			tt.rest.shortener = shortener_service.New("http://localhost:8080", repo)
+			tt.rest.shortener = shortener_service.New("http://localhost:8080", repo, nil, 0, 0)
 
 			r := gin.Default()
 			r.POST("/api/shorten/batch", tt.rest.ShortenURLs)
@@ -432,6 +562,59 @@ func TestRest_DeleteURLs(t *testing.T) {
 	}
 }
 
+func TestRest_LinkStats(t *testing.T) {
+	repo, err := inmemory.New("")
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Add(context.Background(), "www.example.com", "tst", "1", nil))
+	require.NoError(t, repo.AddClicks(context.Background(), []*entities.Click{
+		{ShortURL: "tst", ClickedAt: time.Now(), IPHash: "a"},
+		{ShortURL: "tst", ClickedAt: time.Now(), IPHash: "a"},
+	}))
+
+	rest := &Rest{shortener: shortener_service.New("http://localhost:8080", repo, nil, 0, 0)}
+
+	tests := []struct {
+		name       string
+		userID     string
+		request    string
+		wantStatus int
+	}{
+		{name: "owner", userID: "1", request: "/api/user/urls/tst/stats", wantStatus: http.StatusOK},
+		{name: "daily buckets", userID: "1", request: "/api/user/urls/tst/stats?bucket=24h", wantStatus: http.StatusOK},
+		{name: "invalid bucket", userID: "1", request: "/api/user/urls/tst/stats?bucket=1s", wantStatus: http.StatusBadRequest},
+		{name: "another user", userID: "2", request: "/api/user/urls/tst/stats", wantStatus: http.StatusNotFound},
+		{name: "unknown URL", userID: "1", request: "/api/user/urls/unknown/stats", wantStatus: http.StatusNotFound},
+		{name: "unauthorized", request: "/api/user/urls/tst/stats", wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.Default()
+			r.Use(func(c *gin.Context) {
+				u, _ := user.New()
+				if tt.userID != "" {
+					u = user.NewWithID(tt.userID)
+				}
+				c.Request = c.Request.WithContext(session.SetUserContext(c.Request.Context(), u))
+			})
+			r.GET("/api/user/urls/:id/stats", rest.LinkStats)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.request, nil))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+
+			if tt.wantStatus == http.StatusOK {
+				stats := &entities.LinkStats{}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), stats))
+				assert.Equal(t, int64(2), stats.TotalClicks)
+				assert.Equal(t, int64(1), stats.UniqueVisitors)
+				assert.NotEmpty(t, stats.Histogram)
+			}
+		})
+	}
+}
+
 func TestRest_Ping(t *testing.T) {
 	type want struct {
 		statusCode int
@@ -455,10 +638,10 @@ func TestRest_Ping(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			repo, err := inmemory.New("/tmp/short-url-repo.json")
+			repo, err := inmemory.New(filepath.Join(t.TempDir(), "short-url-repo.json"))
 			require.NoError(t, err)
 
-			tt.rest.shortener = shortener_service.New("http://localhost:8080", repo)
+			tt.rest.shortener = shortener_service.New("http://localhost:8080", repo, nil, 0, 0)
 
 			r := gin.Default()
 			r.GET(tt.request, tt.rest.Ping)
@@ -476,3 +659,162 @@ func TestRest_Ping(t *testing.T) {
 		})
 	}
 }
+
+func TestRest_ConcurrentShortenRedirectDelete(t *testing.T) {
+	const (
+		workers        = 16
+		urlsPerWorker  = 20
+		redirectsPerOp = 3
+	)
+
+	repo, err := inmemory.New(filepath.Join(t.TempDir(), "short-url-repo.json"))
+	require.NoError(t, err)
+
+	rest := &Rest{shortener: shortener_service.New("http://localhost:8080", repo, nil, 0, 0)}
+
+	r := gin.New()
+	r.Use(middleware.AuthMiddleware("supersecret"))
+	rest.SetRouters(r)
+
+	serve := func(req *http.Request) *http.Response {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Result()
+	}
+
+	var wg sync.WaitGroup
+	deleted := make(chan string, workers*urlsPerWorker)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+
+			var cookie *http.Cookie
+			var ids []string
+
+			for j := 0; j < urlsPerWorker; j++ {
+				request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(fmt.Sprintf("https://example.com/%d/%d", worker, j)))
+				if cookie != nil {
+					request.AddCookie(cookie)
+				}
+
+				result := serve(request)
+				body, err := io.ReadAll(result.Body)
+				assert.NoError(t, err)
+				result.Body.Close()
+				assert.Equal(t, http.StatusCreated, result.StatusCode)
+
+				if cookie == nil {
+					cookies := result.Cookies()
+					if assert.NotEmpty(t, cookies) {
+						cookie = cookies[0]
+					}
+				}
+
+				parts := strings.Split(string(body), "/")
+				id := parts[len(parts)-1]
+				ids = append(ids, id)
+
+				for k := 0; k < redirectsPerOp; k++ {
+					result = serve(httptest.NewRequest(http.MethodGet, "/"+ids[k%len(ids)], nil))
+					result.Body.Close()
+					assert.Contains(t, []int{http.StatusTemporaryRedirect, http.StatusGone}, result.StatusCode)
+				}
+
+				if j%2 == 1 {
+					requestBody, err := json.Marshal([]string{ids[j-1]})
+					assert.NoError(t, err)
+
+					request = httptest.NewRequest(http.MethodDelete, "/api/user/urls", bytes.NewBuffer(requestBody))
+					request.AddCookie(cookie)
+
+					result = serve(request)
+					result.Body.Close()
+					assert.Equal(t, http.StatusAccepted, result.StatusCode)
+
+					deleted <- ids[j-1]
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	close(deleted)
+
+	for id := range deleted {
+		assert.Eventually(t, func() bool {
+			result := serve(httptest.NewRequest(http.MethodGet, "/"+id, nil))
+			result.Body.Close()
+			return result.StatusCode == http.StatusGone
+		}, time.Second, 10*time.Millisecond)
+	}
+}
+
+func TestRest_DeleteURLsQueueFull(t *testing.T) {
+	repo, err := inmemory.New("")
+	require.NoError(t, err)
+
+	rest := &Rest{shortener: shortener_service.New("http://localhost:8080", repo, nil, 0, 0)}
+	rest.shortener.SetDeleter(shortener_service.NewDeleter(repo, 1, 1, 100, time.Hour, 0))
+
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(session.SetUserContext(c.Request.Context(), user.NewWithID("1")))
+	})
+	r.DELETE("/api/user/urls", rest.DeleteURLs)
+
+	wantStatuses := []int{http.StatusAccepted, http.StatusServiceUnavailable}
+	for _, wantStatus := range wantStatuses {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/user/urls", strings.NewReader(`["tst"]`)))
+
+		assert.Equal(t, wantStatus, w.Code)
+	}
+}
+
+func TestRest_InternalStats(t *testing.T) {
+	repo, err := inmemory.New("")
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Add(context.Background(), "www.example.com", "first", "1", nil))
+	require.NoError(t, repo.Add(context.Background(), "www.example2.com", "second", "1", nil))
+	require.NoError(t, repo.Add(context.Background(), "www.example3.com", "third", "2", nil))
+
+	_, subnet, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+
+	rest := New(shortener_service.New("http://localhost:8080", repo, nil, 0, 0), nil, auth.New("jwt_secret"), subnet)
+
+	r := gin.Default()
+	rest.SetRouters(r)
+
+	tests := []struct {
+		name       string
+		path       string
+		realIP     string
+		wantStatus int
+	}{
+		{name: "trusted", path: "/api/internal/stats", realIP: "10.1.2.3", wantStatus: http.StatusOK},
+		{name: "untrusted", path: "/api/internal/stats", realIP: "192.168.1.1", wantStatus: http.StatusForbidden},
+		{name: "metrics trusted", path: "/metrics", realIP: "10.1.2.3", wantStatus: http.StatusOK},
+		{name: "metrics untrusted", path: "/metrics", realIP: "192.168.1.1", wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			request.Header.Set("X-Real-IP", tt.realIP)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, request)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+
+			if tt.wantStatus == http.StatusOK && tt.path == "/api/internal/stats" {
+				stats := &entities.ServiceStats{}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), stats))
+				assert.Equal(t, &entities.ServiceStats{URLs: 3, Users: 2}, stats)
+			}
+		})
+	}
+}
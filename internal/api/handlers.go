@@ -4,19 +4,35 @@ package api
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/Azzonya/go-shortener/internal/entities"
+	"github.com/Azzonya/go-shortener/internal/metrics"
 	"github.com/Azzonya/go-shortener/internal/session"
+	shortener_service "github.com/Azzonya/go-shortener/internal/shortener"
+)
+
+// Histogram bucket limits of the link statistics.
+const (
+	defaultStatsBucket = time.Hour           // defaultStatsBucket is used when no bucket is requested.
+	minStatsBucket     = time.Minute         // minStatsBucket is the shortest allowed bucket.
+	maxStatsBucket     = 31 * 24 * time.Hour // maxStatsBucket is the longest allowed bucket.
 )
 
 // Request represents the request structure for URL shortening.
+// The optional alias replaces the generated short code, the optional expires_at or ttl in seconds
+// limit the lifetime of the link.
 type Request struct {
-	URL string `json:"url"`
+	URL       string     `json:"url"`
+	Alias     string     `json:"alias,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	TTL       int64      `json:"ttl,omitempty"`
 }
 
 // Response represents the response structure for URL shortening.
@@ -47,9 +63,36 @@ func (o *Rest) ShortenJSON(c *gin.Context) {
 		userID = user.ID
 	}
 
-	resp.Result, err = o.shortener.ShortenAndSaveLink(req.URL, userID)
+	expiresAt, err := shortener_service.ResolveExpiration(req.ExpiresAt, req.TTL)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid expiration",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if req.Alias != "" {
+		resp.Result, err = o.shortener.ShortenAndSaveAlias(c.Request.Context(), req.URL, req.Alias, userID, expiresAt)
+	} else {
+		resp.Result, err = o.shortener.ShortenAndSaveLink(c.Request.Context(), req.URL, userID, expiresAt)
+	}
+	if errors.Is(err, shortener_service.ErrInvalidAlias) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid alias",
+			"error":   err.Error(),
+		})
+		return
+	}
 	if err != nil {
-		resp.Result, exist = o.shortener.GetOneByOriginalURL(req.URL)
+		resp.Result, exist = o.shortener.GetOneByOriginalURL(c.Request.Context(), req.URL)
+		if !exist && errors.Is(err, shortener_service.ErrAliasTaken) {
+			c.JSON(http.StatusConflict, gin.H{
+				"message": "Alias is taken",
+				"error":   err.Error(),
+			})
+			return
+		}
 		if !exist {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"message": "Failed to create short URL",
@@ -96,9 +139,9 @@ func (o *Rest) Shorten(c *gin.Context) {
 
 	reqObj := strings.TrimSpace(string(body))
 
-	outputURL, err := o.shortener.ShortenAndSaveLink(reqObj, userID)
+	outputURL, err := o.shortener.ShortenAndSaveLink(c.Request.Context(), reqObj, userID, nil)
 	if err != nil {
-		outputURL, exist = o.shortener.GetOneByOriginalURL(reqObj)
+		outputURL, exist = o.shortener.GetOneByOriginalURL(c.Request.Context(), reqObj)
 		if !exist {
 			c.String(http.StatusBadRequest, "Failed to add line to inmemory")
 			return
@@ -115,6 +158,7 @@ func (o *Rest) Shorten(c *gin.Context) {
 }
 
 // Redirect redirects requests from short URLs to their original URLs.
+// Deleted, disabled and expired links are answered with 410 Gone.
 func (o *Rest) Redirect(c *gin.Context) {
 	shortURL, exist := c.Params.Get("id")
 	if !exist {
@@ -122,19 +166,29 @@ func (o *Rest) Redirect(c *gin.Context) {
 		return
 	}
 
-	if o.shortener.IsDeleted(shortURL) {
+	link, err := o.shortener.Resolve(c.Request.Context(), shortURL)
+	switch {
+	case errors.Is(err, shortener_service.ErrURLGone):
+		metrics.ObserveRedirect(metrics.RedirectGone)
 		c.AbortWithStatus(http.StatusGone)
 		return
-	}
-
-	URL, exist := o.shortener.GetOneByShortURL(shortURL)
-	if !exist {
+	case errors.Is(err, entities.ErrURLNotFound):
+		metrics.ObserveRedirect(metrics.RedirectMiss)
 		c.String(http.StatusBadRequest, "Failed to get original URL")
 		return
+	case err != nil:
+		c.String(http.StatusInternalServerError, "Failed to get original URL")
+		return
+	}
+
+	if o.clicks != nil {
+		o.clicks.Record(shortURL, c.Request.Referer(), c.Request.UserAgent(), c.ClientIP())
 	}
 
-	c.Header("Location", URL)
-	c.Redirect(http.StatusTemporaryRedirect, URL)
+	metrics.ObserveRedirect(metrics.RedirectHit)
+
+	c.Header("Location", link.OriginalURL)
+	c.Redirect(http.StatusTemporaryRedirect, link.OriginalURL)
 }
 
 // ShortenURLs handles HTTP requests to shorten multiple URLs simultaneously.
@@ -156,7 +210,14 @@ func (o *Rest) ShortenURLs(c *gin.Context) {
 		userID = user.ID
 	}
 
-	shortenedURLs, err := o.shortener.ShortenURLs(URLs, userID)
+	shortenedURLs, err := o.shortener.ShortenURLs(c.Request.Context(), URLs, userID)
+	if errors.Is(err, shortener_service.ErrAliasTaken) {
+		c.JSON(http.StatusConflict, gin.H{
+			"message": "Alias is taken",
+			"error":   err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"message": "Shorten URLs",
@@ -192,7 +253,7 @@ func (o *Rest) ListAll(c *gin.Context) {
 
 	userID := u.ID
 
-	result, err := o.shortener.ListAll(userID)
+	result, err := o.shortener.ListAll(c.Request.Context(), userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"message": "Failed to get urls",
@@ -210,6 +271,63 @@ func (o *Rest) ListAll(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// LinkStats handles HTTP requests for the click statistics of a short URL owned by the user.
+// The optional bucket query parameter sets the duration of the histogram buckets, one hour by default.
+func (o *Rest) LinkStats(c *gin.Context) {
+	u, err := session.GetUser(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": "no cookie",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	bucket := defaultStatsBucket
+	if value := c.Query("bucket"); value != "" {
+		bucket, err = time.ParseDuration(value)
+		if err != nil || bucket < minStatsBucket || bucket > maxStatsBucket || bucket%time.Second != 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid bucket",
+				"error":   fmt.Sprintf("bucket must be a whole number of seconds from %s to %s", minStatsBucket, maxStatsBucket),
+			})
+			return
+		}
+	}
+
+	stats, err := o.shortener.LinkStats(c.Request.Context(), c.Param("id"), u.ID, bucket)
+	if errors.Is(err, entities.ErrURLNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "URL not found",
+			"error":   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to get stats",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// InternalStats handles HTTP requests for the usage statistics of the whole service.
+func (o *Rest) InternalStats(c *gin.Context) {
+	stats, err := o.shortener.Stats(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to get stats",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
 // Ping handles HTTP requests to check the API's connectivity.
 func (o *Rest) Ping(c *gin.Context) {
 	c.JSON(http.StatusOK, "")
@@ -239,7 +357,21 @@ func (o *Rest) DeleteURLs(c *gin.Context) {
 
 	userID := u.ID
 
-	o.shortener.DeleteURLs(shortURLs, userID)
+	err = o.shortener.DeleteURLs(c.Request.Context(), shortURLs, userID)
+	if errors.Is(err, shortener_service.ErrDeletionQueueFull) {
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"message": "Too many pending deletions, try again later",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to delete URLs",
+			"error":   err.Error(),
+		})
+		return
+	}
 
 	c.AbortWithStatus(http.StatusAccepted)
 }
@@ -3,61 +3,120 @@ package api
 
 import (
 	"context"
+	"net"
 	"net/http"
+	"net/url"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
+	"github.com/Azzonya/go-shortener/internal/account"
+	"github.com/Azzonya/go-shortener/internal/admin"
+	"github.com/Azzonya/go-shortener/internal/apikey"
+	"github.com/Azzonya/go-shortener/internal/auth"
+	"github.com/Azzonya/go-shortener/internal/entities"
 	"github.com/Azzonya/go-shortener/internal/logger"
+	"github.com/Azzonya/go-shortener/internal/metrics"
 	"github.com/Azzonya/go-shortener/internal/middleware"
 	shortener_service "github.com/Azzonya/go-shortener/internal/shortener"
+	"github.com/Azzonya/go-shortener/internal/tracing"
 )
 
+// TLSConfig represents the HTTPS settings of the REST API server.
+type TLSConfig struct {
+	CertFile       string // CertFile is the PEM encoded certificate file.
+	KeyFile        string // KeyFile is the PEM encoded private key file.
+	RedirectListen string // RedirectListen is the address of the HTTP listener redirecting to HTTPS, empty to disable it.
+}
+
+// RateLimits represents the per-client rate limiters of the REST API routes, nil disables a limit.
+type RateLimits struct {
+	Create   *middleware.RateLimiter // Create limits the requests shortening URLs.
+	Redirect *middleware.RateLimiter // Redirect limits the requests resolving short URLs.
+	Delete   *middleware.RateLimiter // Delete limits the requests deleting URLs.
+	Auth     *middleware.RateLimiter // Auth limits the signup and login requests by IP address.
+}
+
 // Rest represents the REST API server.
 type Rest struct {
-	server      *http.Server
-	pprofServer *http.Server
-	shortener   *shortener_service.Shortener
+	server         *http.Server
+	pprofServer    *http.Server
+	redirectServer *http.Server
+	tls            *TLSConfig
+	limits         RateLimits
+	shortener      *shortener_service.Shortener
+	clicks         *shortener_service.ClickTracker
+	keys           *apikey.Keys
+	accounts       *account.Accounts
+	admin          *admin.Admin
 
-	ErrorChan chan error
-	jwtSecret string
+	ErrorChan     chan error
+	auth          *auth.Auth
+	trustedSubnet *net.IPNet
 }
 
-// New creates a new instance of the REST API server.
-func New(shortener *shortener_service.Shortener, jwtSecret string) *Rest {
+// New creates a new instance of the REST API server authenticating users with the authorizer.
+// Redirects are recorded by the click tracker, nil disables click tracking.
+// Internal endpoints, the service statistics and the Prometheus metrics, are only served
+// to clients inside the trusted subnet, nil forbids them to everyone.
+func New(shortener *shortener_service.Shortener, clicks *shortener_service.ClickTracker, authorizer *auth.Auth, trustedSubnet *net.IPNet) *Rest {
 	return &Rest{
 		shortener: shortener,
+		clicks:    clicks,
 
-		ErrorChan: make(chan error, 1),
-		jwtSecret: jwtSecret,
+		ErrorChan:     make(chan error, 1),
+		auth:          authorizer,
+		trustedSubnet: trustedSubnet,
 	}
 }
 
-// Start starts the REST API server.
+// SetTLS makes the REST API server serve HTTPS with the given settings.
+func (o *Rest) SetTLS(conf *TLSConfig) {
+	o.tls = conf
+}
+
+// SetRateLimits limits the rate of requests of every client to the create, redirect, delete and authentication routes.
+func (o *Rest) SetRateLimits(limits RateLimits) {
+	o.limits = limits
+}
+
+// SetAPIKeys makes the REST API server authenticate programmatic clients with the API keys managed by keys
+// and serve the routes minting, listing and revoking them.
+func (o *Rest) SetAPIKeys(keys *apikey.Keys) {
+	o.keys = keys
+}
+
+// SetAccounts makes the REST API server serve the routes signing up, logging in and logging out the accounts.
+func (o *Rest) SetAccounts(accounts *account.Accounts) {
+	o.accounts = accounts
+}
+
+// SetAdmin makes the REST API server reject the requests of banned users and serve the moderation
+// routes to the administrators among the accounts, so it requires SetAccounts.
+func (o *Rest) SetAdmin(moderator *admin.Admin) {
+	o.admin = moderator
+}
+
+// Start starts the REST API server, serving HTTPS if TLS is set.
 func (o *Rest) Start(lAddr, pAddr string) {
 	logger.Log.Info("Running server", zap.String("address", lAddr))
 
 	gin.SetMode(gin.ReleaseMode)
 
-	r := gin.Default()
-
-	r.Use(
-		middleware.RequestLogger(logger.Log),
-		middleware.CompressRequest(),
-		middleware.DecompressRequest(),
-		middleware.AuthMiddleware(o.jwtSecret),
-		gin.Recovery())
-
-	o.SetRouters(r)
+	r := o.newRouter()
 
-	o.server = &http.Server{
+	// The goroutines only use these copies, as a later call of Start replaces the fields.
+	server := &http.Server{
 		Addr:    lAddr,
 		Handler: r,
 	}
-
-	o.pprofServer = &http.Server{
+	pprofServer := &http.Server{
 		Addr: pAddr,
 	}
+	tls := o.tls
+
+	o.server = server
+	o.pprofServer = pprofServer
 
 	go func() {
 		defer func() {
@@ -66,12 +125,21 @@ func (o *Rest) Start(lAddr, pAddr string) {
 			}
 		}()
 
-		err := o.server.ListenAndServe()
+		var err error
+		if tls != nil {
+			err = server.ListenAndServeTLS(tls.CertFile, tls.KeyFile)
+		} else {
+			err = server.ListenAndServe()
+		}
 		if err != nil && err != http.ErrServerClosed {
 			o.ErrorChan <- err
 		}
 	}()
 
+	if tls != nil && tls.RedirectListen != "" {
+		o.startRedirect(tls.RedirectListen, lAddr)
+	}
+
 	go func() {
 		defer func() {
 			if rec := recover(); rec != nil {
@@ -79,13 +147,35 @@ func (o *Rest) Start(lAddr, pAddr string) {
 			}
 		}()
 
-		err := o.pprofServer.ListenAndServe()
+		err := pprofServer.ListenAndServe()
 		if err != nil && err != http.ErrServerClosed {
 			o.ErrorChan <- err
 		}
 	}()
 }
 
+// newRouter creates the Gin engine serving the REST API with its middlewares and routes.
+// No proxy is trusted, so that clients can not pick the address their requests are rate limited by
+// with the X-Forwarded-For header.
+func (o *Rest) newRouter() *gin.Engine {
+	r := gin.Default()
+	_ = r.SetTrustedProxies(nil)
+
+	r.Use(
+		tracing.Middleware(),
+		metrics.Middleware(),
+		middleware.RequestLogger(logger.Log),
+		middleware.CompressRequest(),
+		middleware.DecompressRequest(),
+		middleware.AuthenticateAPIKey(o.keys),
+		middleware.Authenticate(o.auth),
+		gin.Recovery())
+
+	o.SetRouters(r)
+
+	return r
+}
+
 // Stop stops the REST API server.
 func (o *Rest) Stop(ctx context.Context) error {
 	defer close(o.ErrorChan)
@@ -95,16 +185,92 @@ func (o *Rest) Stop(ctx context.Context) error {
 		return err
 	}
 
+	if o.redirectServer != nil {
+		return o.redirectServer.Shutdown(ctx)
+	}
+
 	return nil
 }
 
+// startRedirect starts the HTTP listener redirecting every request to the same URL served over HTTPS on lAddr.
+func (o *Rest) startRedirect(rAddr, lAddr string) {
+	logger.Log.Info("Running HTTP to HTTPS redirect", zap.String("address", rAddr))
+
+	_, httpsPort, _ := net.SplitHostPort(lAddr)
+
+	server := &http.Server{
+		Addr:    rAddr,
+		Handler: redirectToHTTPS(httpsPort),
+	}
+	o.redirectServer = server
+
+	go func() {
+		err := server.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			o.ErrorChan <- err
+		}
+	}()
+}
+
+// redirectToHTTPS returns a handler permanently redirecting requests to the same host on the HTTPS port.
+func redirectToHTTPS(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+
+		target := url.URL{Scheme: "https", Host: host, Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+
+		http.Redirect(w, r, target.String(), http.StatusPermanentRedirect)
+	})
+}
+
 // SetRouters sets up the routes for the REST API server.
 func (o *Rest) SetRouters(r *gin.Engine) {
-	r.POST("/", o.Shorten)
-	r.GET("/:id", o.Redirect)
-	r.POST("/api/shorten", o.ShortenJSON)
+	createLimit := middleware.RateLimit(o.limits.Create)
+	redirectLimit := middleware.RateLimit(o.limits.Redirect)
+	deleteLimit := middleware.RateLimit(o.limits.Delete)
+	authLimit := middleware.RateLimitByAddress(o.limits.Auth)
+
+	shorten := middleware.RequireScope(entities.ScopeShorten)
+	read := middleware.RequireScope(entities.ScopeRead)
+	del := middleware.RequireScope(entities.ScopeDelete)
+	owner := middleware.RequireSession()
+	banned := middleware.RejectBanned(o.admin)
+
+	r.POST("/", banned, shorten, createLimit, o.Shorten)
+	r.GET("/:id", redirectLimit, o.Redirect)
+	r.POST("/api/shorten", banned, shorten, createLimit, o.ShortenJSON)
 	r.GET("/ping", o.Ping)
-	r.POST("/api/shorten/batch", o.ShortenURLs)
-	r.GET("/api/user/urls", o.ListAll)
-	r.DELETE("/api/user/urls", o.DeleteURLs)
+	r.POST("/api/shorten/batch", banned, shorten, createLimit, o.ShortenURLs)
+	r.GET("/api/user/urls", owner, banned, read, o.ListAll)
+	r.DELETE("/api/user/urls", owner, banned, del, deleteLimit, o.DeleteURLs)
+	r.GET("/api/user/urls/:id/stats", owner, banned, read, o.LinkStats)
+	if o.keys != nil {
+		r.POST("/api/user/keys", owner, banned, o.CreateAPIKey)
+		r.GET("/api/user/keys", owner, banned, o.ListAPIKeys)
+		r.DELETE("/api/user/keys/:id", owner, banned, o.RevokeAPIKey)
+	}
+	if o.accounts != nil {
+		r.POST("/api/user/signup", authLimit, banned, o.Signup)
+		r.POST("/api/user/login", authLimit, o.Login)
+		r.POST("/api/user/logout", o.Logout)
+	}
+	if o.admin != nil {
+		moderation := r.Group("/api/admin", owner, banned, middleware.RequireAdmin(o.accounts))
+		moderation.GET("/urls", o.SearchLinks)
+		moderation.POST("/urls/:id/disable", o.DisableLink)
+		moderation.POST("/urls/:id/enable", o.EnableLink)
+		moderation.PUT("/urls/:id/owner", o.ReassignLink)
+		moderation.PUT("/bans/:id", o.BanUser)
+		moderation.DELETE("/bans/:id", o.UnbanUser)
+		moderation.GET("/audit", o.AuditTrail)
+	}
+	trusted := middleware.TrustedSubnet(o.trustedSubnet)
+	r.GET("/api/internal/stats", trusted, o.InternalStats)
+	r.GET("/metrics", trusted, gin.WrapH(metrics.Handler()))
 }
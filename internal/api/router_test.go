@@ -2,9 +2,16 @@ package api
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/Azzonya/go-shortener/internal/auth"
+	"github.com/Azzonya/go-shortener/internal/middleware"
 	"github.com/Azzonya/go-shortener/internal/repo/inmemory"
 	shortener_service "github.com/Azzonya/go-shortener/internal/shortener"
 	"github.com/gin-gonic/gin"
@@ -14,14 +21,16 @@ import (
 
 func TestNew(t *testing.T) {
 
-	repo, err := inmemory.New("/tmp/short-url-repo.json")
+	repo, err := inmemory.New(filepath.Join(t.TempDir(), "short-url-repo.json"))
 	require.NoError(t, err)
 
-	shortener := shortener_service.New("http://localhost:8080", repo)
+	shortener := shortener_service.New("http://localhost:8080", repo, nil, 0, 0)
+
+	authorizer := auth.New("supersecret")
 
 	type args struct {
 		shortener *shortener_service.Shortener
-		jwtSecret string
+		auth      *auth.Auth
 	}
 	tests := []struct {
 		name string
@@ -32,20 +41,20 @@ func TestNew(t *testing.T) {
 			name: "test New router",
 			args: args{
 				shortener: shortener,
-				jwtSecret: "supersecret",
+				auth:      authorizer,
 			},
 			want: &Rest{
 				shortener: shortener,
-				jwtSecret: "supersecret",
+				auth:      authorizer,
 				ErrorChan: make(chan error, 1),
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			restTest := New(tt.args.shortener, tt.args.jwtSecret)
-			assert.Equalf(t, tt.want.shortener, restTest.shortener, "New(%v, %v)", tt.args.shortener, tt.args.jwtSecret)
-			assert.Equalf(t, tt.want.jwtSecret, restTest.jwtSecret, "New(%v, %v)", tt.args.shortener, tt.args.jwtSecret)
+			restTest := New(tt.args.shortener, nil, tt.args.auth, nil)
+			assert.Equalf(t, tt.want.shortener, restTest.shortener, "New(%v, %v)", tt.args.shortener, tt.args.auth)
+			assert.Equalf(t, tt.want.auth, restTest.auth, "New(%v, %v)", tt.args.shortener, tt.args.auth)
 		})
 	}
 }
@@ -69,6 +78,7 @@ func TestRest_SetRouters(t *testing.T) {
 		{"POST", "/api/shorten/batch"},
 		{"GET", "/api/user/urls"},
 		{"DELETE", "/api/user/urls"},
+		{"GET", "/api/internal/stats"},
 	}
 
 	for _, route := range expectedRoutes {
@@ -78,9 +88,30 @@ func TestRest_SetRouters(t *testing.T) {
 	}
 }
 
+func TestRest_RateLimitIgnoresForwardedFor(t *testing.T) {
+	repo, err := inmemory.New("")
+	require.NoError(t, err)
+
+	rest := New(shortener_service.New("http://localhost:8080", repo, nil, 0, 0), nil, auth.New("supersecret"), nil)
+	rest.SetRateLimits(RateLimits{
+		Create: middleware.NewRateLimiter(middleware.PerMinute(1, 1), time.Minute),
+	})
+	r := rest.newRouter()
+
+	for i, want := range []int{http.StatusCreated, http.StatusTooManyRequests} {
+		request := httptest.NewRequest(http.MethodPost, "/api/shorten/batch", strings.NewReader(`[{"correlation_id":"1","original_url":"https://example.com/`+strconv.Itoa(i)+`"}]`))
+		request.RemoteAddr = "192.0.2.1:1234"
+		request.Header.Set("X-Forwarded-For", "203.0.113."+strconv.Itoa(i))
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, request)
+		assert.Equal(t, want, w.Code, "a spoofed X-Forwarded-For must not give a new bucket")
+	}
+}
+
 func TestRest_Start(t *testing.T) {
 	rest := &Rest{
-		jwtSecret: "my_secret",
+		auth: auth.New("my_secret"),
 	}
 
 	testCases := []struct {
@@ -97,13 +128,19 @@ func TestRest_Start(t *testing.T) {
 			rest.Start(tc.listenAddr, tc.pprofAddr)
 			assert.NotNil(t, rest.pprofServer)
 			assert.NotNil(t, rest.server)
+
+			server, pprofServer := rest.server, rest.pprofServer
+			t.Cleanup(func() {
+				server.Close()
+				pprofServer.Close()
+			})
 		})
 	}
 }
 
 func TestRest_Stop(t *testing.T) {
 	rest := &Rest{
-		jwtSecret: "my_secret",
+		auth:      auth.New("my_secret"),
 		ErrorChan: make(chan error, 1),
 	}
 
@@ -128,3 +165,24 @@ func TestRest_Stop(t *testing.T) {
 		})
 	}
 }
+
+func TestRedirectToHTTPS(t *testing.T) {
+	tests := []struct {
+		name      string
+		httpsPort string
+		target    string
+		want      string
+	}{
+		{name: "custom port", httpsPort: "8443", target: "http://example.com:8080/abc?x=1", want: "https://example.com:8443/abc?x=1"},
+		{name: "default port", httpsPort: "443", target: "http://example.com/abc", want: "https://example.com/abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			redirectToHTTPS(tt.httpsPort).ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.target, nil))
+
+			assert.Equal(t, http.StatusPermanentRedirect, w.Code)
+			assert.Equal(t, tt.want, w.Header().Get("Location"))
+		})
+	}
+}
@@ -0,0 +1,187 @@
+// Package apikey manages the API keys authenticating programmatic clients of the URL shortener.
+//
+// An API key has the form sk_<id>_<secret>. The ID identifies the key in the repository,
+// while only the SHA-256 hash of the secret is stored, so a leaked repository does not leak usable keys.
+// Secrets are 256 bits of randomness, which makes a slow password hash unnecessary.
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+	"github.com/Azzonya/go-shortener/internal/repo"
+)
+
+const (
+	keyPrefix  = "sk_" // keyPrefix starts every API key.
+	idBytes    = 8     // idBytes is the number of random bytes of a key ID.
+	secretSize = 32    // secretSize is the number of random bytes of a key secret.
+
+	// MaxNameLength is the maximum number of characters of a key name.
+	MaxNameLength = 100
+)
+
+var (
+	// ErrInvalidKey is returned when the API key is malformed, unknown or revoked.
+	ErrInvalidKey = errors.New("invalid API key")
+
+	// ErrInvalidName is returned when the key name is empty or too long.
+	ErrInvalidName = fmt.Errorf("key name must have 1 to %d characters", MaxNameLength)
+
+	// ErrInvalidScopes is returned when no scope or an unknown scope is requested.
+	ErrInvalidScopes = fmt.Errorf("key scopes must be a non-empty subset of %s", strings.Join(entities.Scopes, ", "))
+)
+
+// Keys mints, lists, revokes and checks the API keys stored in the repository.
+type Keys struct {
+	repo repo.Repo        // Repository storing the keys
+	now  func() time.Time // Current time, replaced in tests
+}
+
+// New creates a new API key manager storing the keys in the repository.
+func New(repo repo.Repo) *Keys {
+	return &Keys{
+		repo: repo,
+		now:  time.Now,
+	}
+}
+
+// Mint creates a new API key of the user with the given name and scopes.
+// It returns the key, which is never shown again, and its stored state.
+func (k *Keys) Mint(ctx context.Context, userID, name string, scopes []string) (string, *entities.APIKey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > MaxNameLength {
+		return "", nil, ErrInvalidName
+	}
+
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return "", nil, err
+	}
+
+	id, err := randomString(idBytes, hex.EncodeToString)
+	if err != nil {
+		return "", nil, err
+	}
+	secret, err := randomString(secretSize, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return "", nil, err
+	}
+
+	key := &entities.APIKey{
+		ID:        id,
+		UserID:    userID,
+		Name:      name,
+		Hash:      hash(secret),
+		Scopes:    scopes,
+		CreatedAt: k.now().UTC(),
+	}
+
+	if err = k.repo.AddAPIKey(ctx, key); err != nil {
+		return "", nil, err
+	}
+
+	return keyPrefix + id + "_" + secret, key, nil
+}
+
+// List returns the API keys of the user, including revoked keys.
+func (k *Keys) List(ctx context.Context, userID string) ([]*entities.APIKey, error) {
+	return k.repo.ListAPIKeys(ctx, userID)
+}
+
+// Revoke revokes the user's API key, so that it no longer authenticates requests.
+// It returns entities.ErrAPIKeyNotFound if the user has no such key.
+func (k *Keys) Revoke(ctx context.Context, id, userID string) error {
+	return k.repo.RevokeAPIKey(ctx, id, userID, k.now().UTC())
+}
+
+// Authenticate returns the stored state of the API key.
+// It returns ErrInvalidKey if the key is malformed, unknown or revoked.
+func (k *Keys) Authenticate(ctx context.Context, apiKey string) (*entities.APIKey, error) {
+	id, secret, ok := parse(apiKey)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+
+	key, err := k.repo.GetAPIKey(ctx, id)
+	if errors.Is(err, entities.ErrAPIKeyNotFound) {
+		return nil, ErrInvalidKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hash(secret))) != 1 || key.Revoked() {
+		return nil, ErrInvalidKey
+	}
+
+	return key, nil
+}
+
+// IsKey checks if the token looks like an API key rather than another kind of credential.
+func IsKey(token string) bool {
+	return strings.HasPrefix(token, keyPrefix)
+}
+
+// parse splits the API key into its ID and secret.
+func parse(apiKey string) (id, secret string, ok bool) {
+	rest, ok := strings.CutPrefix(apiKey, keyPrefix)
+	if !ok {
+		return "", "", false
+	}
+
+	id, secret, ok = strings.Cut(rest, "_")
+	if !ok || len(id) != hex.EncodedLen(idBytes) || secret == "" {
+		return "", "", false
+	}
+
+	return id, secret, true
+}
+
+// normalizeScopes validates the scopes and returns them deduplicated in the order of entities.Scopes.
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, ErrInvalidScopes
+	}
+
+	for _, scope := range scopes {
+		if !slices.Contains(entities.Scopes, scope) {
+			return nil, ErrInvalidScopes
+		}
+	}
+
+	result := make([]string, 0, len(scopes))
+	for _, scope := range entities.Scopes {
+		if slices.Contains(scopes, scope) {
+			result = append(result, scope)
+		}
+	}
+
+	return result, nil
+}
+
+// randomString returns n random bytes encoded with encode.
+func randomString(n int, encode func([]byte) string) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("cannot generate API key: %w", err)
+	}
+	return encode(b), nil
+}
+
+// hash returns the hex encoded SHA-256 hash of the key secret.
+func hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
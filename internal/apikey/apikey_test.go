@@ -0,0 +1,128 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+	"github.com/Azzonya/go-shortener/internal/repo/inmemory"
+)
+
+func newTestKeys(t *testing.T) *Keys {
+	t.Helper()
+
+	storage, err := inmemory.New("")
+	if err != nil {
+		t.Fatalf("inmemory.New() error = %v", err)
+	}
+
+	return New(storage)
+}
+
+func TestKeys_Mint(t *testing.T) {
+	tests := []struct {
+		name       string
+		keyName    string
+		scopes     []string
+		wantScopes []string
+		wantErr    error
+	}{
+		{
+			name:       "Scopes are deduplicated and ordered",
+			keyName:    " ci ",
+			scopes:     []string{entities.ScopeDelete, entities.ScopeShorten, entities.ScopeDelete},
+			wantScopes: []string{entities.ScopeShorten, entities.ScopeDelete},
+		},
+		{
+			name:    "Empty name",
+			keyName: " ",
+			scopes:  []string{entities.ScopeRead},
+			wantErr: ErrInvalidName,
+		},
+		{
+			name:    "Too long name",
+			keyName: strings.Repeat("a", MaxNameLength+1),
+			scopes:  []string{entities.ScopeRead},
+			wantErr: ErrInvalidName,
+		},
+		{
+			name:    "No scopes",
+			keyName: "ci",
+			wantErr: ErrInvalidScopes,
+		},
+		{
+			name:    "Unknown scope",
+			keyName: "ci",
+			scopes:  []string{entities.ScopeRead, "admin"},
+			wantErr: ErrInvalidScopes,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := newTestKeys(t)
+
+			key, stored, err := k.Mint(context.Background(), "1", tt.keyName, tt.scopes)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Mint() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if !IsKey(key) || !strings.HasPrefix(key, keyPrefix+stored.ID+"_") {
+				t.Errorf("Mint() key = %v, want it prefixed by %v", key, keyPrefix+stored.ID+"_")
+			}
+			if strings.Contains(key, stored.Hash) {
+				t.Errorf("Mint() key contains its hash")
+			}
+			if stored.Name != "ci" || stored.UserID != "1" || strings.Join(stored.Scopes, ",") != strings.Join(tt.wantScopes, ",") {
+				t.Errorf("Mint() stored = %+v, want name ci, user 1 and scopes %v", stored, tt.wantScopes)
+			}
+		})
+	}
+}
+
+func TestKeys_Authenticate(t *testing.T) {
+	k := newTestKeys(t)
+	ctx := context.Background()
+
+	key, stored, err := k.Mint(ctx, "1", "ci", []string{entities.ScopeShorten})
+	if err != nil {
+		t.Fatalf("Mint() error = %v", err)
+	}
+
+	got, err := k.Authenticate(ctx, key)
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if got.ID != stored.ID || got.UserID != "1" {
+		t.Errorf("Authenticate() = %+v, want key %v of user 1", got, stored.ID)
+	}
+
+	for _, invalid := range []string{"", "sk_", "other", keyPrefix + stored.ID + "_wrong", keyPrefix + "0000000000000000_" + key[len(keyPrefix)+len(stored.ID)+1:]} {
+		if _, err = k.Authenticate(ctx, invalid); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Authenticate(%q) error = %v, want %v", invalid, err, ErrInvalidKey)
+		}
+	}
+
+	if err = k.Revoke(ctx, stored.ID, "2"); !errors.Is(err, entities.ErrAPIKeyNotFound) {
+		t.Errorf("Revoke() by another user error = %v, want %v", err, entities.ErrAPIKeyNotFound)
+	}
+	if err = k.Revoke(ctx, stored.ID, "1"); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+
+	if _, err = k.Authenticate(ctx, key); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Authenticate() of a revoked key error = %v, want %v", err, ErrInvalidKey)
+	}
+
+	keys, err := k.List(ctx, "1")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(keys) != 1 || !keys[0].Revoked() {
+		t.Errorf("List() = %+v, want the revoked key", keys)
+	}
+}
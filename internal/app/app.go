@@ -4,19 +4,36 @@ package app
 
 import (
 	"context"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
 
+	"github.com/Azzonya/go-shortener/internal/account"
+	"github.com/Azzonya/go-shortener/internal/admin"
 	"github.com/Azzonya/go-shortener/internal/api"
+	"github.com/Azzonya/go-shortener/internal/apikey"
+	"github.com/Azzonya/go-shortener/internal/auth"
 	"github.com/Azzonya/go-shortener/internal/cfg"
+	"github.com/Azzonya/go-shortener/internal/codegen"
+	"github.com/Azzonya/go-shortener/internal/grpcapi"
 	"github.com/Azzonya/go-shortener/internal/logger"
+	"github.com/Azzonya/go-shortener/internal/metrics"
+	"github.com/Azzonya/go-shortener/internal/middleware"
 	"github.com/Azzonya/go-shortener/internal/repo"
+	"github.com/Azzonya/go-shortener/internal/repo/cache"
 	"github.com/Azzonya/go-shortener/internal/repo/inmemory"
 	"github.com/Azzonya/go-shortener/internal/repo/pg"
 	"github.com/Azzonya/go-shortener/internal/shortener"
+	"github.com/Azzonya/go-shortener/internal/tlscert"
+	"github.com/Azzonya/go-shortener/internal/tracing"
 	"github.com/Azzonya/go-shortener/pkg"
 )
 
@@ -24,10 +41,19 @@ import (
 type appSt struct {
 	conf *cfg.Conf
 	api  *api.Rest
+	grpc *grpcapi.Server
 	//storage   *inmemory.Storage
 	shortener *shortener.Shortener
 	db        *pgxpool.Pool
 	repo      repo.Repo
+	sweeper   *shortener.Sweeper
+	clicks    *shortener.ClickTracker
+	deleter   *shortener.Deleter
+	auth      *auth.Auth
+	accounts  *account.Accounts
+	limits    api.RateLimits
+
+	shutdownTracing func(context.Context) error
 }
 
 // StopSignal returns a channel for receiving OS signals to stop the application.
@@ -43,51 +69,247 @@ func (a *appSt) Init(conf *cfg.Conf) {
 
 	a.conf = conf
 
+	if err = logger.Initialize(conf.LogLevel); err != nil {
+		panic(err)
+	}
+
 	if conf.UseDatabase() {
 		a.db, err = pkg.InitDatabasePg(conf.PgDsn)
 		if err != nil {
 			panic(err)
 		}
 
-		a.repo = pg.New(a.db)
+		storage, err := pg.New(a.db)
+		if err != nil {
+			panic(err)
+		}
+
+		a.repo = metrics.NewRepo(tracing.NewRepo(storage, "pg"), "pg")
+
+		if err = metrics.RegisterPool(a.db); err != nil {
+			panic(err)
+		}
 	} else {
-		a.repo, err = inmemory.New(conf.FileStoragePath)
+		storage, err := inmemory.New(conf.FileStoragePath)
 		if err != nil {
 			panic(err)
 		}
+
+		a.repo = metrics.NewRepo(tracing.NewRepo(storage, "inmemory"), "inmemory")
 	}
 
-	if err = logger.Initialize(conf.LogLevel); err != nil {
+	if conf.CacheSize > 0 {
+		cached := cache.New(a.repo, conf.CacheSize, conf.CacheTTL, conf.CacheNegativeTTL)
+		if err = metrics.RegisterCache(cached.CacheStats); err != nil {
+			panic(err)
+		}
+
+		a.repo = cached
+	}
+
+	a.shutdownTracing, err = tracing.Init(context.Background(), tracing.Config{
+		Exporter: conf.TraceExporter,
+		Endpoint: conf.TraceEndpoint,
+		File:     conf.TraceFile,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	codes, err := codegen.New(conf.CodeGenerator, conf.CodeLength, conf.CodeSalt, a.repo)
+	if err != nil {
 		panic(err)
 	}
 
-	a.shortener = shortener.New(conf.BaseURL, a.repo)
+	a.shortener = shortener.New(conf.BaseURL, a.repo, codes, conf.ReadTimeout, conf.WriteTimeout)
+
+	if conf.DeleteQueueSize > 0 {
+		a.deleter = shortener.NewDeleter(a.repo, conf.DeleteQueueSize, conf.DeleteWorkers, conf.DeleteBatchSize, conf.DeleteFlushInterval, conf.WriteTimeout)
+		a.shortener.SetDeleter(a.deleter)
+
+		if err = metrics.RegisterQueueDepth(a.deleter.QueueDepth); err != nil {
+			panic(err)
+		}
+	}
+
+	if conf.ClickBufferSize > 0 {
+		salt, err := shortener.ResolveSalt(conf.ClickHashSalt, conf.ClickHashSaltFile)
+		if err != nil {
+			panic(err)
+		}
+
+		a.clicks = shortener.NewClickTracker(a.repo, conf.ClickBufferSize, conf.ClickFlushInterval, salt, conf.WriteTimeout)
+	}
+
+	var trustedSubnet *net.IPNet
+	if conf.TrustedSubnet != "" {
+		if _, trustedSubnet, err = net.ParseCIDR(conf.TrustedSubnet); err != nil {
+			panic(fmt.Sprintf("invalid trusted subnet %q: %v", conf.TrustedSubnet, err))
+		}
+	}
+
+	keyring, err := auth.LoadKeyring(conf.JWTSecret, conf.JWTKeys, conf.JWTActiveKey)
+	if err != nil {
+		panic(err)
+	}
+	a.auth = auth.NewWithKeyring(keyring)
+	a.auth.SetCookieConfig(cookieConfig(conf))
+
+	a.api = api.New(a.shortener, a.clicks, a.auth, trustedSubnet)
+
+	a.limits = api.RateLimits{
+		Create:   middleware.NewRateLimiter(middleware.PerMinute(conf.CreateRateLimit, conf.CreateRateBurst), conf.RateLimitIdle),
+		Redirect: middleware.NewRateLimiter(middleware.PerMinute(conf.RedirectRateLimit, conf.RedirectRateBurst), conf.RateLimitIdle),
+		Delete:   middleware.NewRateLimiter(middleware.PerMinute(conf.DeleteRateLimit, conf.DeleteRateBurst), conf.RateLimitIdle),
+		Auth:     middleware.NewRateLimiter(middleware.PerMinute(conf.AuthRateLimit, conf.AuthRateBurst), conf.RateLimitIdle),
+	}
+	a.api.SetRateLimits(a.limits)
+	keys := apikey.New(a.repo)
+	a.api.SetAPIKeys(keys)
+
+	a.accounts = account.New(a.repo)
+	a.accounts.SetAdmins(cfg.SplitList(conf.AdminLogins))
+	a.api.SetAccounts(a.accounts)
+
+	moderator := admin.New(a.repo)
+	a.api.SetAdmin(moderator)
+
+	if conf.EnableHTTPS {
+		certFile, keyFile, err := tlscert.Resolve(conf.TLSCertFile, conf.TLSKeyFile, conf.TLSCacheDir, certHosts(conf))
+		if err != nil {
+			panic(err)
+		}
+
+		a.api.SetTLS(&api.TLSConfig{
+			CertFile:       certFile,
+			KeyFile:        keyFile,
+			RedirectListen: conf.HTTPSRedirect,
+		})
+	}
+
+	if conf.GRPCListen != "" {
+		a.grpc = grpcapi.New(a.shortener, a.auth)
+		a.grpc.SetAPIKeys(keys)
+		a.grpc.SetRateLimits(a.limits.Create, a.limits.Redirect, a.limits.Delete)
+		a.grpc.SetAdmin(moderator)
+	}
+
+	if conf.SweepInterval > 0 {
+		a.sweeper = shortener.NewSweeper(a.repo, conf.SweepInterval, conf.ExpiredRetention, conf.ArchiveExpired, conf.WriteTimeout)
+	}
+}
+
+// certHosts returns the hosts a generated certificate is issued for:
+// the hosts of the listen address and of the base URL, and localhost.
+func certHosts(conf *cfg.Conf) []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+
+	if host, _, err := net.SplitHostPort(conf.HTTPListen); err == nil && host != "" {
+		hosts = append(hosts, host)
+	}
+
+	if u, err := url.Parse(conf.BaseURL); err == nil && u.Hostname() != "" {
+		hosts = append(hosts, u.Hostname())
+	}
+
+	return hosts
+}
+
+// cookieConfig returns the session cookie configuration of the application configuration.
+// The cookie is always secure when the server serves HTTPS.
+func cookieConfig(conf *cfg.Conf) auth.CookieConfig {
+	sameSite, _ := auth.ParseSameSite(conf.CookieSameSite) // checked by cfg.Validate
 
-	a.api = api.New(a.shortener, conf.JWTSecret)
+	return auth.CookieConfig{
+		Path:        conf.CookiePath,
+		Domain:      conf.CookieDomain,
+		Secure:      conf.CookieSecure || conf.EnableHTTPS,
+		HTTPOnly:    conf.CookieHTTPOnly,
+		SameSite:    sameSite,
+		TTL:         conf.SessionTTL,
+		RenewBefore: conf.SessionRenewBefore,
+	}
 }
 
 // Start starts the application, initializing and running the API server.
 func (a *appSt) Start() {
+	if a.clicks != nil {
+		a.clicks.Start()
+	}
+
+	if a.deleter != nil {
+		a.deleter.Start()
+	}
+
 	a.api.Start(a.conf.HTTPListen, a.conf.HTTPPprof)
+
+	if a.grpc != nil {
+		if err := a.grpc.Start(a.conf.GRPCListen); err != nil {
+			panic(err)
+		}
+	}
+
+	if a.sweeper != nil {
+		a.sweeper.Start()
+	}
 }
 
-// Listen listens for signals to stop the application.
+// Listen listens for signals to stop the application, reloading the configuration on SIGHUP.
 func (a *appSt) Listen() {
-	select {
-	case <-StopSignal():
-	case <-a.api.ErrorChan:
+	var grpcErrors chan error
+	if a.grpc != nil {
+		grpcErrors = a.grpc.ErrorChan
+	}
+
+	stop := StopSignal()
+	reload := ReloadSignal()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-a.api.ErrorChan:
+			return
+		case <-grpcErrors:
+			return
+		case <-reload:
+			a.Reload()
+		}
 	}
 }
 
 // Stop stops the application, closing database connections and shutting down the API server.
 func (a *appSt) Stop() {
-	if !a.conf.UseDatabase() {
-		a.repo.SyncData()
+	if err := a.api.Stop(context.Background()); err != nil {
+		panic(err)
+	}
+
+	if a.grpc != nil {
+		a.grpc.Stop(context.Background())
+	}
+
+	if a.sweeper != nil {
+		a.sweeper.Stop()
+	}
+
+	if a.clicks != nil {
+		a.clicks.Stop()
+	}
+
+	if a.deleter != nil {
+		a.deleter.Stop()
+	}
+
+	a.repo.SyncData()
+
+	if a.conf.UseDatabase() {
 		a.db.Close()
 	}
 
-	if err := a.api.Stop(context.Background()); err != nil {
-		panic(err)
+	if a.shutdownTracing != nil {
+		if err := a.shutdownTracing(context.Background()); err != nil {
+			logger.Log.Error("Failed to flush traces", zap.Error(err))
+		}
 	}
 }
 
@@ -95,6 +317,13 @@ func (a *appSt) Stop() {
 func Start() {
 	conf := cfg.InitConfig()
 
+	if conf.PrintConfig {
+		if err := conf.Print(os.Stdout); err != nil {
+			panic(err)
+		}
+		return
+	}
+
 	app := &appSt{}
 
 	app.Init(&conf)
@@ -102,3 +331,67 @@ func Start() {
 	app.Listen()
 	app.Stop()
 }
+
+// Migrate runs the schema migration command given by the positional command-line arguments:
+// "up" (the default) applies all pending migrations, "down [steps]" reverts the given number
+// of migrations (one by default) and "status" prints the state of every migration.
+func Migrate() {
+	conf := cfg.InitConfig()
+
+	if err := logger.Initialize(conf.LogLevel); err != nil {
+		panic(err)
+	}
+
+	if !conf.UseDatabase() {
+		panic("migrate: database connection line is not set")
+	}
+
+	db, err := pkg.InitDatabasePg(conf.PgDsn)
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	migrator, err := pg.NewMigrator(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ctx := context.Background()
+
+	args := flag.Args()
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil {
+				panic(fmt.Sprintf("migrate: invalid number of steps %q", args[1]))
+			}
+		}
+		err = migrator.Down(ctx, steps)
+	case "status":
+		var statuses []*pg.MigrationStatus
+		if statuses, err = migrator.Status(ctx); err == nil {
+			for _, status := range statuses {
+				appliedAt := "pending"
+				if status.AppliedAt != nil {
+					appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05 MST")
+				}
+				fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, appliedAt)
+			}
+		}
+	default:
+		panic(fmt.Sprintf("migrate: unknown command %q, expected up, down or status", command))
+	}
+
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go.uber.org/zap"
+
+	"github.com/Azzonya/go-shortener/internal/auth"
+	"github.com/Azzonya/go-shortener/internal/cfg"
+	"github.com/Azzonya/go-shortener/internal/logger"
+	"github.com/Azzonya/go-shortener/internal/middleware"
+)
+
+// reloader applies a reloadable setting of the new configuration to the running application.
+type reloader func(conf *cfg.Conf) error
+
+// ReloadSignal returns a channel for receiving the OS signal to reload the configuration.
+func ReloadSignal() <-chan os.Signal {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	return ch
+}
+
+// reloaders returns the functions applying the reloadable settings keyed by their configuration key.
+// Changes of the other settings only take effect after a restart.
+func (a *appSt) reloaders() map[string]reloader {
+	createLimit := func(conf *cfg.Conf) error {
+		a.limits.Create.SetLimit(middleware.PerMinute(conf.CreateRateLimit, conf.CreateRateBurst))
+		a.conf.CreateRateLimit, a.conf.CreateRateBurst = conf.CreateRateLimit, conf.CreateRateBurst
+		return nil
+	}
+	redirectLimit := func(conf *cfg.Conf) error {
+		a.limits.Redirect.SetLimit(middleware.PerMinute(conf.RedirectRateLimit, conf.RedirectRateBurst))
+		a.conf.RedirectRateLimit, a.conf.RedirectRateBurst = conf.RedirectRateLimit, conf.RedirectRateBurst
+		return nil
+	}
+	jwtKeys := func(conf *cfg.Conf) error {
+		keyring, err := auth.LoadKeyring(conf.JWTSecret, conf.JWTKeys, conf.JWTActiveKey)
+		if err != nil {
+			return err
+		}
+		a.auth.SetKeyring(keyring)
+		a.conf.JWTSecret, a.conf.JWTKeys, a.conf.JWTActiveKey = conf.JWTSecret, conf.JWTKeys, conf.JWTActiveKey
+		return nil
+	}
+	cookie := func(conf *cfg.Conf) error {
+		a.conf.CookiePath, a.conf.CookieDomain, a.conf.CookieSecure = conf.CookiePath, conf.CookieDomain, conf.CookieSecure
+		a.conf.CookieHTTPOnly, a.conf.CookieSameSite = conf.CookieHTTPOnly, conf.CookieSameSite
+		a.conf.SessionTTL, a.conf.SessionRenewBefore = conf.SessionTTL, conf.SessionRenewBefore
+		a.auth.SetCookieConfig(cookieConfig(a.conf))
+		return nil
+	}
+	authLimit := func(conf *cfg.Conf) error {
+		a.limits.Auth.SetLimit(middleware.PerMinute(conf.AuthRateLimit, conf.AuthRateBurst))
+		a.conf.AuthRateLimit, a.conf.AuthRateBurst = conf.AuthRateLimit, conf.AuthRateBurst
+		return nil
+	}
+	deleteLimit := func(conf *cfg.Conf) error {
+		a.limits.Delete.SetLimit(middleware.PerMinute(conf.DeleteRateLimit, conf.DeleteRateBurst))
+		a.conf.DeleteRateLimit, a.conf.DeleteRateBurst = conf.DeleteRateLimit, conf.DeleteRateBurst
+		return nil
+	}
+
+	return map[string]reloader{
+		"log_level": func(conf *cfg.Conf) error {
+			if err := logger.SetLevel(conf.LogLevel); err != nil {
+				return err
+			}
+			a.conf.LogLevel = conf.LogLevel
+			return nil
+		},
+		"jwt_secret":     jwtKeys,
+		"jwt_keys":       jwtKeys,
+		"jwt_active_key": jwtKeys,
+		"admin_logins": func(conf *cfg.Conf) error {
+			a.accounts.SetAdmins(cfg.SplitList(conf.AdminLogins))
+			a.conf.AdminLogins = conf.AdminLogins
+			return nil
+		},
+		"cookie_path":          cookie,
+		"cookie_domain":        cookie,
+		"cookie_secure":        cookie,
+		"cookie_http_only":     cookie,
+		"cookie_same_site":     cookie,
+		"session_ttl":          cookie,
+		"session_renew_before": cookie,
+		"create_rate_limit":    createLimit,
+		"create_rate_burst":    createLimit,
+		"redirect_rate_limit":  redirectLimit,
+		"redirect_rate_burst":  redirectLimit,
+		"delete_rate_limit":    deleteLimit,
+		"delete_rate_burst":    deleteLimit,
+		"auth_rate_limit":      authLimit,
+		"auth_rate_burst":      authLimit,
+	}
+}
+
+// Reload reads the configuration again and applies the changed reloadable settings without
+// restarting the listeners. The new configuration is validated as a whole before anything is applied,
+// an invalid one is rejected and the running configuration is kept.
+// Changed settings which cannot be reloaded are logged as needing a restart.
+func (a *appSt) Reload() {
+	conf, err := cfg.Reload()
+	if err != nil {
+		logger.Log.Error("Failed to reload configuration, keeping the running one", zap.Error(err))
+		return
+	}
+
+	reloaders := a.reloaders()
+
+	var applied, failed, restartRequired []string
+	for _, key := range cfg.Changed(*a.conf, conf) {
+		reload, ok := reloaders[key]
+		if !ok {
+			restartRequired = append(restartRequired, key)
+			continue
+		}
+
+		if err = reload(&conf); err != nil {
+			logger.Log.Error("Failed to apply setting", zap.String("setting", key), zap.Error(err))
+			failed = append(failed, key)
+			continue
+		}
+		applied = append(applied, key)
+	}
+
+	logger.Log.Info("Configuration reloaded",
+		zap.Strings("applied", applied),
+		zap.Strings("failed", failed),
+		zap.Strings("restart_required", restartRequired),
+	)
+}
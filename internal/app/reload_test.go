@@ -0,0 +1,64 @@
+package app
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Azzonya/go-shortener/internal/api"
+	"github.com/Azzonya/go-shortener/internal/auth"
+	"github.com/Azzonya/go-shortener/internal/cfg"
+	"github.com/Azzonya/go-shortener/internal/middleware"
+	"github.com/Azzonya/go-shortener/internal/user"
+)
+
+func TestAppSt_Reload(t *testing.T) {
+	args := os.Args
+	t.Cleanup(func() { os.Args = args })
+	os.Args = []string{"shortener"}
+
+	conf := cfg.Default()
+	a := &appSt{
+		conf: &conf,
+		auth: auth.New(conf.JWTSecret),
+		limits: api.RateLimits{
+			Create:   middleware.NewRateLimiter(middleware.PerMinute(conf.CreateRateLimit, conf.CreateRateBurst), 0),
+			Redirect: middleware.NewRateLimiter(middleware.PerMinute(conf.RedirectRateLimit, conf.RedirectRateBurst), 0),
+			Delete:   middleware.NewRateLimiter(middleware.PerMinute(conf.DeleteRateLimit, conf.DeleteRateBurst), 0),
+			Auth:     middleware.NewRateLimiter(middleware.PerMinute(conf.AuthRateLimit, conf.AuthRateBurst), 0),
+		},
+	}
+
+	oldToken, err := a.auth.NewToken(user.NewWithID("1"))
+	require.NoError(t, err)
+
+	t.Setenv("JWT_SECRET", "rotated")
+	t.Setenv("LOG_LEVEL", "debug")
+	t.Setenv("SERVER_ADDRESS", "localhost:9999")
+	t.Setenv("CREATE_RATE_BURST", "5")
+	t.Setenv("COOKIE_SAME_SITE", "strict")
+
+	a.Reload()
+
+	assert.Equal(t, "rotated", a.conf.JWTSecret)
+	assert.Equal(t, "debug", a.conf.LogLevel)
+	assert.Equal(t, 5, a.conf.CreateRateBurst)
+	assert.Equal(t, cfg.Default().HTTPListen, a.conf.HTTPListen, "listen address needs a restart")
+
+	_, err = a.auth.GetUserFromJWT(oldToken)
+	assert.Error(t, err, "tokens signed with the old secret must be rejected")
+
+	cookie, err := a.auth.CreateJWTCookie(user.NewWithID("1"))
+	require.NoError(t, err)
+	assert.Equal(t, http.SameSiteStrictMode, cookie.SameSite)
+
+	t.Setenv("LOG_LEVEL", "loud")
+	t.Setenv("JWT_SECRET", "invalid config is not applied")
+
+	a.Reload()
+
+	assert.Equal(t, "rotated", a.conf.JWTSecret)
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -13,59 +14,127 @@ import (
 	"github.com/Azzonya/go-shortener/internal/user"
 )
 
-const (
-	sessionCookie              = "userID"
-	defaultJWTCookieExpiration = 24 * time.Hour
-)
+// sessionCookie is the name of the cookie holding the session token.
+const sessionCookie = "userID"
+
+// ErrTokenExpired is returned when the token is valid but has expired.
+var ErrTokenExpired = errors.New("token has expired")
 
 // Claims represents the custom claims structure for JWT tokens.
 type Claims struct {
 	jwt.RegisteredClaims
-	UID string
+	UID  string
+	Role string `json:"role,omitempty"` // Role is the role granted to the user, empty for regular users.
 }
 
 // Auth provides methods for user authentication and JWT handling.
+// It is safe for concurrent use, the keyring and the cookie configuration may be replaced while tokens are checked.
 type Auth struct {
-	JwtSecret string // JwtSecret is the secret key used for signing JWT tokens.
+	keyring atomic.Pointer[Keyring]      // keyring holds the keys signing and verifying JWT tokens.
+	cookie  atomic.Pointer[CookieConfig] // cookie holds the attributes of the session cookie and the lifetime of its token.
+	now     func() time.Time             // now returns the current time, replaced in tests.
 }
 
-// New creates a new instance of the Auth struct with the provided JWT secret key.
+// New creates a new instance of the Auth struct signing tokens with the provided HS256 JWT secret key.
 func New(jwtSecret string) *Auth {
-	return &Auth{JwtSecret: jwtSecret}
+	a := newAuth()
+	a.SetSecret(jwtSecret)
+	return a
+}
+
+// NewWithKeyring creates a new instance of the Auth struct signing and verifying tokens with the keyring.
+func NewWithKeyring(kr *Keyring) *Auth {
+	a := newAuth()
+	a.SetKeyring(kr)
+	return a
+}
+
+// newAuth creates a new instance of the Auth struct with the default cookie configuration and no keys.
+func newAuth() *Auth {
+	a := &Auth{now: time.Now}
+	a.SetCookieConfig(DefaultCookieConfig())
+	return a
+}
+
+// SetCookieConfig replaces the session cookie configuration, applied to the cookies and tokens created afterwards.
+func (a *Auth) SetCookieConfig(conf CookieConfig) {
+	a.cookie.Store(&conf)
+}
+
+// SetSecret replaces the keyring with the single HS256 JWT secret key,
+// tokens signed with the previous keys are no longer valid.
+func (a *Auth) SetSecret(jwtSecret string) {
+	kr, _ := NewKeyring("", NewHMACKey(LegacyKeyID, []byte(jwtSecret)))
+	a.SetKeyring(kr)
+}
+
+// SetKeyring replaces the keyring, only tokens signed with one of its keys remain valid.
+func (a *Auth) SetKeyring(kr *Keyring) {
+	a.keyring.Store(kr)
 }
 
 // GetUserFromCookie retrieves the user information from the session cookie.
 func (a *Auth) GetUserFromCookie(c *gin.Context) (*user.User, error) {
+	u, _, err := a.VerifyCookie(c)
+	return u, err
+}
+
+// VerifyCookie retrieves the user information from the session cookie.
+// The renew flag reports that the cookie should be replaced by a new one, see VerifyToken.
+func (a *Auth) VerifyCookie(c *gin.Context) (u *user.User, renew bool, err error) {
 	userCookie, err := c.Cookie(sessionCookie)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	return a.GetUserFromJWT(userCookie)
+	return a.VerifyToken(userCookie)
 }
 
 // GetUserFromJWT retrieves the user information from the JWT token.
 func (a *Auth) GetUserFromJWT(signedToken string) (*user.User, error) {
-	token, err := jwt.ParseWithClaims(signedToken, &Claims{},
-		func(t *jwt.Token) (interface{}, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-			}
-			return []byte(a.JwtSecret), nil
-		})
-	if err != nil {
-		return nil, fmt.Errorf("token is not valid")
+	u, _, err := a.VerifyToken(signedToken)
+	return u, err
+}
+
+// VerifyToken retrieves the user information from the JWT token signed with any key of the keyring.
+// The renew flag reports that the token should be replaced by a new one: it was signed with a key
+// other than the active one, or it expires within the renewal period of the cookie configuration.
+// It returns ErrTokenExpired if the token is valid but has expired.
+func (a *Auth) VerifyToken(signedToken string) (u *user.User, renew bool, err error) {
+	kr := a.keyring.Load()
+
+	// the lifetime is checked against a.now below, so that expired tokens are told from invalid ones
+	parser := jwt.NewParser(jwt.WithoutClaimsValidation())
+
+	token, err := parser.ParseWithClaims(signedToken, &Claims{}, kr.keyFunc)
+	if err != nil || !token.Valid {
+		return nil, false, errors.New("token is not valid")
 	}
 
-	if !token.Valid {
-		return nil, errors.New("token is not valid")
+	claims, ok := token.Claims.(*Claims)
+	if !ok {
+		return nil, false, errors.New("token does not contain user id")
+	}
+
+	now := a.now()
+	if !claims.VerifyNotBefore(now, false) || !claims.VerifyIssuedAt(now, false) {
+		return nil, false, errors.New("token is not valid yet")
+	}
+	if !claims.VerifyExpiresAt(now, false) {
+		return nil, false, ErrTokenExpired
 	}
 
-	if claims, ok := token.Claims.(*Claims); !ok {
-		return nil, errors.New("token does not contain user id")
-	} else {
-		return user.NewWithID(claims.UID), nil
+	kid, _ := token.Header["kid"].(string)
+	renew = kid != kr.ActiveID()
+
+	if renewBefore := a.cookie.Load().RenewBefore; renewBefore > 0 && claims.ExpiresAt != nil {
+		renew = renew || claims.ExpiresAt.Sub(now) < renewBefore
 	}
+
+	u = user.NewWithID(claims.UID)
+	u.Role = claims.Role
+
+	return u, renew, nil
 }
 
 // CreateJWTCookie creates a new JWT cookie for the given user.
@@ -74,22 +143,28 @@ func (a *Auth) CreateJWTCookie(u *user.User) (*http.Cookie, error) {
 	if err != nil {
 		return nil, fmt.Errorf("cannot create auth token: %w", err)
 	}
-	return &http.Cookie{
-		Name:  sessionCookie,
-		Value: token,
-	}, nil
+	conf := a.cookie.Load()
+
+	return conf.newCookie(token, a.now().Add(conf.TTL)), nil
 }
 
-// NewToken creates a new JWT token for the given user.
+// ExpiredCookie returns a cookie deleting the session cookie from the client.
+func (a *Auth) ExpiredCookie() *http.Cookie {
+	c := a.cookie.Load().newCookie("", time.Unix(0, 0))
+	c.MaxAge = -1
+	return c
+}
+
+// NewToken creates a new JWT token for the given user signed with the active key.
+// The token carries the role of the user.
 func (a *Auth) NewToken(u *user.User) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+	signedToken, err := a.keyring.Load().sign(Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(defaultJWTCookieExpiration)),
+			ExpiresAt: jwt.NewNumericDate(a.now().Add(a.cookie.Load().TTL)),
 		},
-		UID: u.ID,
+		UID:  u.ID,
+		Role: u.Role,
 	})
-
-	signedToken, err := token.SignedString([]byte(a.JwtSecret))
 	if err != nil {
 		return "", fmt.Errorf("cannot sign jwt token: %w", err)
 	}
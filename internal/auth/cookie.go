@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SameSite values of the session cookie configuration.
+const (
+	SameSiteLax    = "lax"    // SameSiteLax sends the cookie with top-level cross-site navigations only.
+	SameSiteStrict = "strict" // SameSiteStrict never sends the cookie with cross-site requests.
+	SameSiteNone   = "none"   // SameSiteNone sends the cookie with every request, it requires a secure cookie.
+)
+
+// CookieConfig represents the attributes of the session cookie and the lifetime of its token.
+type CookieConfig struct {
+	Path        string        // Path is the URL path prefix the cookie is sent to.
+	Domain      string        // Domain is the domain the cookie is sent to, empty for the host of the server only.
+	Secure      bool          // Secure restricts the cookie to HTTPS requests.
+	HTTPOnly    bool          // HTTPOnly hides the cookie from scripts.
+	SameSite    http.SameSite // SameSite restricts the cookie in cross-site requests.
+	TTL         time.Duration // TTL is the lifetime of the session token and cookie.
+	RenewBefore time.Duration // RenewBefore is the remaining lifetime under which the token of an active user is renewed, zero disables renewal.
+}
+
+// DefaultCookieConfig returns the session cookie configuration used unless another one is set:
+// an HTTP-only lax cookie for the whole site holding a token valid for a day and renewed during its second half.
+func DefaultCookieConfig() CookieConfig {
+	return CookieConfig{
+		Path:        "/",
+		HTTPOnly:    true,
+		SameSite:    http.SameSiteLaxMode,
+		TTL:         24 * time.Hour,
+		RenewBefore: 12 * time.Hour,
+	}
+}
+
+// ParseSameSite returns the SameSite attribute of the lax, strict or none value.
+// An empty value leaves the attribute out of the cookie.
+func ParseSameSite(value string) (http.SameSite, error) {
+	switch strings.ToLower(value) {
+	case "":
+		return http.SameSiteDefaultMode, nil
+	case SameSiteLax:
+		return http.SameSiteLaxMode, nil
+	case SameSiteStrict:
+		return http.SameSiteStrictMode, nil
+	case SameSiteNone:
+		return http.SameSiteNoneMode, nil
+	default:
+		return 0, fmt.Errorf("unknown SameSite value %q, expected lax, strict or none", value)
+	}
+}
+
+// newCookie returns the session cookie with the value expiring at the given time.
+func (c CookieConfig) newCookie(value string, expires time.Time) *http.Cookie {
+	return &http.Cookie{
+		Name:     sessionCookie,
+		Value:    value,
+		Path:     c.Path,
+		Domain:   c.Domain,
+		Expires:  expires,
+		Secure:   c.Secure,
+		HttpOnly: c.HTTPOnly,
+		SameSite: c.SameSite,
+	}
+}
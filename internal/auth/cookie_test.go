@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Azzonya/go-shortener/internal/user"
+)
+
+func TestParseSameSite(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    http.SameSite
+		wantErr bool
+	}{
+		{value: "", want: http.SameSiteDefaultMode},
+		{value: "lax", want: http.SameSiteLaxMode},
+		{value: "Strict", want: http.SameSiteStrictMode},
+		{value: "none", want: http.SameSiteNoneMode},
+		{value: "relaxed", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseSameSite(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAuth_CookieAttributes(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	a := New("testSecret")
+	a.now = func() time.Time { return now }
+	a.SetCookieConfig(CookieConfig{
+		Path:     "/app",
+		Domain:   "example.com",
+		Secure:   true,
+		HTTPOnly: true,
+		SameSite: http.SameSiteStrictMode,
+		TTL:      time.Hour,
+	})
+
+	cookie, err := a.CreateJWTCookie(user.NewWithID("1"))
+	require.NoError(t, err)
+	assert.Equal(t, "/app", cookie.Path)
+	assert.Equal(t, "example.com", cookie.Domain)
+	assert.True(t, cookie.Secure)
+	assert.True(t, cookie.HttpOnly)
+	assert.Equal(t, http.SameSiteStrictMode, cookie.SameSite)
+	assert.Equal(t, now.Add(time.Hour), cookie.Expires)
+
+	expired := a.ExpiredCookie()
+	assert.Equal(t, sessionCookie, expired.Name)
+	assert.Equal(t, "/app", expired.Path, "the cookie is only deleted with the path it was set with")
+	assert.Equal(t, "example.com", expired.Domain)
+	assert.Equal(t, -1, expired.MaxAge)
+}
+
+func TestAuth_VerifyTokenLifetime(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	a := New("testSecret")
+	a.now = func() time.Time { return now }
+	a.SetCookieConfig(CookieConfig{TTL: 24 * time.Hour, RenewBefore: 12 * time.Hour})
+
+	token, err := a.NewToken(user.NewWithID("1"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		elapsed   time.Duration
+		wantRenew bool
+		wantErr   error
+	}{
+		{name: "fresh", elapsed: time.Hour},
+		{name: "within the renewal period", elapsed: 13 * time.Hour, wantRenew: true},
+		{name: "expired", elapsed: 25 * time.Hour, wantErr: ErrTokenExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a.now = func() time.Time { return now.Add(tt.elapsed) }
+
+			u, renew, err := a.VerifyToken(token)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "1", u.ID)
+			assert.Equal(t, tt.wantRenew, renew)
+		})
+	}
+
+	_, _, err = New("otherSecret").VerifyToken(token)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrTokenExpired, "tokens with a wrong signature are invalid, not expired")
+}
@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Signing algorithms of the JWT keys.
+const (
+	AlgHS256 = "HS256" // AlgHS256 signs with HMAC SHA-256 and a shared secret.
+	AlgRS256 = "RS256" // AlgRS256 signs with RSA PKCS #1 v1.5 SHA-256 and a PEM encoded RSA key.
+	AlgEdDSA = "EdDSA" // AlgEdDSA signs with Ed25519 and a PEM encoded Ed25519 key.
+)
+
+// LegacyKeyID identifies the key checking the tokens without a kid header,
+// issued before the tokens were signed with key IDs.
+const LegacyKeyID = "default"
+
+// Key represents a JWT key identified by the kid header of the tokens it signs.
+type Key struct {
+	ID        string            // ID is the kid header of the tokens signed with the key.
+	method    jwt.SigningMethod // Signing algorithm of the key
+	signKey   any               // Key signing the tokens, nil for verification-only keys
+	verifyKey any               // Key verifying the signature of the tokens
+}
+
+// NewHMACKey returns the HS256 key with the given ID and shared secret.
+func NewHMACKey(id string, secret []byte) *Key {
+	return &Key{
+		ID:        id,
+		method:    jwt.SigningMethodHS256,
+		signKey:   secret,
+		verifyKey: secret,
+	}
+}
+
+// CanSign checks if the key can sign tokens, verification-only public keys can not.
+func (k *Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+// KeySpec represents the configuration of a JWT key in the kid:alg:value form.
+// The value is the shared secret of HS256 keys, or the path to the PEM file of RS256 and EdDSA keys.
+// PEM files holding a private key sign and verify tokens, files holding a public key only verify them.
+type KeySpec struct {
+	ID    string // ID is the kid header of the tokens signed with the key.
+	Alg   string // Alg is the signing algorithm: HS256, RS256 or EdDSA.
+	Value string // Value is the secret or the PEM file path.
+}
+
+// String returns the key specification in the kid:alg:value form.
+func (s KeySpec) String() string {
+	return s.ID + ":" + s.Alg + ":" + s.Value
+}
+
+// ParseKeySpecs parses the comma separated list of kid:alg:value key specifications.
+// Key IDs must be unique.
+func ParseKeySpecs(s string) ([]KeySpec, error) {
+	var specs []KeySpec
+	seen := make(map[string]bool)
+
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("invalid key %q, expected kid:alg:value", entry)
+		}
+
+		spec := KeySpec{ID: parts[0], Alg: parts[1], Value: parts[2]}
+		switch spec.Alg {
+		case AlgHS256, AlgRS256, AlgEdDSA:
+		default:
+			return nil, fmt.Errorf("key %q: unknown algorithm %q, expected HS256, RS256 or EdDSA", spec.ID, spec.Alg)
+		}
+
+		if seen[spec.ID] {
+			return nil, fmt.Errorf("duplicate key %q", spec.ID)
+		}
+		seen[spec.ID] = true
+
+		specs = append(specs, spec)
+	}
+
+	return specs, nil
+}
+
+// LoadKey returns the key of the specification, reading the PEM file of RS256 and EdDSA keys.
+func LoadKey(spec KeySpec) (*Key, error) {
+	if spec.Alg == AlgHS256 {
+		return NewHMACKey(spec.ID, []byte(spec.Value)), nil
+	}
+
+	data, err := os.ReadFile(spec.Value)
+	if err != nil {
+		return nil, fmt.Errorf("key %q: %w", spec.ID, err)
+	}
+
+	key := &Key{ID: spec.ID}
+
+	switch spec.Alg {
+	case AlgRS256:
+		key.method = jwt.SigningMethodRS256
+		if private, errP := jwt.ParseRSAPrivateKeyFromPEM(data); errP == nil {
+			key.signKey, key.verifyKey = private, &private.PublicKey
+		} else if key.verifyKey, err = jwt.ParseRSAPublicKeyFromPEM(data); err != nil {
+			return nil, fmt.Errorf("key %q: %s is neither an RSA private nor public key", spec.ID, spec.Value)
+		}
+	case AlgEdDSA:
+		key.method = jwt.SigningMethodEdDSA
+		if private, errP := jwt.ParseEdPrivateKeyFromPEM(data); errP == nil {
+			key.signKey, key.verifyKey = private, private.(crypto.Signer).Public()
+		} else if key.verifyKey, err = jwt.ParseEdPublicKeyFromPEM(data); err != nil {
+			return nil, fmt.Errorf("key %q: %s is neither an Ed25519 private nor public key", spec.ID, spec.Value)
+		}
+	default:
+		return nil, fmt.Errorf("key %q: unknown algorithm %q", spec.ID, spec.Alg)
+	}
+
+	return key, nil
+}
+
+// Keyring holds the JWT keys: the active key signing new tokens,
+// and every key accepted for verifying tokens, the active one included.
+// A keyring is immutable, rotation replaces the keyring of Auth.
+type Keyring struct {
+	active *Key            // Key signing new tokens
+	keys   map[string]*Key // Keys verifying tokens keyed by ID
+}
+
+// NewKeyring returns the keyring of the keys signing with the key of the active ID,
+// or with the first key if the active ID is empty.
+func NewKeyring(activeID string, keys ...*Key) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no JWT keys")
+	}
+	if activeID == "" {
+		activeID = keys[0].ID
+	}
+
+	kr := &Keyring{keys: make(map[string]*Key, len(keys))}
+	for _, key := range keys {
+		if _, exist := kr.keys[key.ID]; exist {
+			return nil, fmt.Errorf("duplicate key %q", key.ID)
+		}
+		kr.keys[key.ID] = key
+	}
+
+	kr.active = kr.keys[activeID]
+	if kr.active == nil {
+		return nil, fmt.Errorf("unknown active key %q", activeID)
+	}
+	if !kr.active.CanSign() {
+		return nil, fmt.Errorf("active key %q is a public key and can not sign tokens", activeID)
+	}
+
+	return kr, nil
+}
+
+// LoadKeyring returns the keyring of the comma separated key specifications signing with the key of the active ID.
+// Without key specifications, the keyring holds the single HS256 key of the secret, checking legacy tokens too.
+func LoadKeyring(secret, keySpecs, activeID string) (*Keyring, error) {
+	specs, err := ParseKeySpecs(keySpecs)
+	if err != nil {
+		return nil, err
+	}
+	if len(specs) == 0 {
+		return NewKeyring("", NewHMACKey(LegacyKeyID, []byte(secret)))
+	}
+
+	keys := make([]*Key, 0, len(specs))
+	for _, spec := range specs {
+		key, err := LoadKey(spec)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return NewKeyring(activeID, keys...)
+}
+
+// ActiveID returns the ID of the key signing new tokens.
+func (kr *Keyring) ActiveID() string {
+	return kr.active.ID
+}
+
+// sign signs the token with the active key, setting its kid header.
+func (kr *Keyring) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(kr.active.method, claims)
+	token.Header["kid"] = kr.active.ID
+
+	return token.SignedString(kr.active.signKey)
+}
+
+// keyFunc returns the key verifying the token by its kid header, tokens without one are checked
+// with the legacy key. The token must be signed with the algorithm of its key.
+func (kr *Keyring) keyFunc(t *jwt.Token) (any, error) {
+	key, ok := kr.keys[keyID(t)]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", keyID(t))
+	}
+	if t.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+	}
+
+	return key.verifyKey, nil
+}
+
+// keyID returns the kid header of the token, the legacy key ID if it has none.
+func keyID(t *jwt.Token) string {
+	if kid, ok := t.Header["kid"].(string); ok && kid != "" {
+		return kid
+	}
+	return LegacyKeyID
+}
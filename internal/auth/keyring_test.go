@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Azzonya/go-shortener/internal/user"
+)
+
+// writePEM writes the PEM block of the DER encoded key to a file of the test directory and returns its path.
+func writePEM(t *testing.T, name, blockType string, der []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600))
+
+	return path
+}
+
+// testKeyFiles writes Ed25519 and RSA private keys and the public Ed25519 key and returns their paths.
+func testKeyFiles(t *testing.T) (edPrivate, edPublic, rsaPrivate string) {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	edPrivate = writePEM(t, "ed.pem", "PRIVATE KEY", der)
+
+	der, err = x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	edPublic = writePEM(t, "ed.pub.pem", "PUBLIC KEY", der)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaPrivate = writePEM(t, "rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+
+	return edPrivate, edPublic, rsaPrivate
+}
+
+func TestLoadKeyring(t *testing.T) {
+	edPrivate, edPublic, rsaPrivate := testKeyFiles(t)
+
+	tests := []struct {
+		name       string
+		keys       string
+		active     string
+		wantActive string
+		wantErr    bool
+	}{
+		{name: "secret only", wantActive: LegacyKeyID},
+		{name: "first key is active", keys: "ed:EdDSA:" + edPrivate + ",hs:HS256:secret", wantActive: "ed"},
+		{name: "explicit active key", keys: "ed:EdDSA:" + edPrivate + ",rsa:RS256:" + rsaPrivate, active: "rsa", wantActive: "rsa"},
+		{name: "public key can not sign", keys: "ed:EdDSA:" + edPublic, wantErr: true},
+		{name: "unknown active key", keys: "hs:HS256:secret", active: "other", wantErr: true},
+		{name: "missing PEM file", keys: "rsa:RS256:" + filepath.Join(t.TempDir(), "missing.pem"), wantErr: true},
+		{name: "wrong key type", keys: "rsa:RS256:" + edPrivate, wantErr: true},
+		{name: "duplicate key", keys: "hs:HS256:a,hs:HS256:b", wantErr: true},
+		{name: "malformed key", keys: "hs:HS256", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kr, err := LoadKeyring("supersecret", tt.keys, tt.active)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantActive, kr.ActiveID())
+		})
+	}
+}
+
+func TestAuth_Rotation(t *testing.T) {
+	edPrivate, edPublic, rsaPrivate := testKeyFiles(t)
+	u := user.NewWithID("testUserID")
+
+	a := New("oldsecret")
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{UID: u.ID}).SignedString([]byte("oldsecret"))
+	require.NoError(t, err)
+	old, err := a.NewToken(u)
+	require.NoError(t, err)
+
+	kr, err := LoadKeyring("", "ed:EdDSA:"+edPrivate+",rsa:RS256:"+rsaPrivate+","+LegacyKeyID+":HS256:oldsecret", "")
+	require.NoError(t, err)
+	a.SetKeyring(kr)
+
+	current, err := a.NewToken(u)
+	require.NoError(t, err)
+
+	for name, token := range map[string]string{"legacy": legacy, "retired": old} {
+		got, renew, err := a.VerifyToken(token)
+		require.NoError(t, err, name)
+		assert.Equal(t, u, got, name)
+		assert.True(t, renew, "%s token must be renewed", name)
+	}
+
+	got, renew, err := a.VerifyToken(current)
+	require.NoError(t, err)
+	assert.Equal(t, u, got)
+	assert.False(t, renew, "token signed with the active key must not be renewed")
+
+	// Once the signing key moves to RSA, Ed25519 tokens are still accepted with the public key only.
+	kr, err = LoadKeyring("", "rsa:RS256:"+rsaPrivate+",ed:EdDSA:"+edPublic, "")
+	require.NoError(t, err)
+	a.SetKeyring(kr)
+
+	_, renew, err = a.VerifyToken(current)
+	require.NoError(t, err)
+	assert.True(t, renew)
+
+	_, _, err = a.VerifyToken(old)
+	assert.Error(t, err, "token of a removed key must be rejected")
+}
+
+func TestAuth_VerifyTokenWrongAlgorithm(t *testing.T) {
+	_, edPublic, _ := testKeyFiles(t)
+
+	kr, err := LoadKeyring("", "hs:HS256:secret,ed:EdDSA:"+edPublic, "")
+	require.NoError(t, err)
+	a := NewWithKeyring(kr)
+
+	public, err := os.ReadFile(edPublic)
+	require.NoError(t, err)
+
+	// A token signed with HS256 using the public key as the secret must not pass as an EdDSA token.
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+		UID:              "attacker",
+	})
+	token.Header["kid"] = "ed"
+	forged, err := token.SignedString(public)
+	require.NoError(t, err)
+
+	_, _, err = a.VerifyToken(forged)
+	assert.Error(t, err)
+}
@@ -3,34 +3,79 @@ package cfg
 
 import (
 	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/caarlos0/env/v6"
 )
 
 // Conf represents the application configuration.
 type Conf struct {
-	HTTPListen      string `env:"SERVER_ADDRESS"`       // HTTPListen represents the address and port to run the HTTP server.
-	HTTPPprof       string `env:"PPROF_SERVER_ADDRESS"` // HTTPPprof represents the address and port to run the pprof server.
-	BaseURL         string `env:"BASE_URL"`             // BaseURL represents the base address of the resulting shortened URL.
-	LogLevel        string `env:"LOG_LEVEL"`            // LogLevel represents the log level for logging.
-	FileStoragePath string `env:"FILE_STORAGE_PATH"`    // FileStoragePath represents the file path for storing data.
-	PgDsn           string `env:"DATABASE_DSN"`         // PgDsn represents the database connection line for PostgreSQL.
-	JWTSecret       string `env:"JWT_SECRET"`           // JWTSecret represents the JWT cookie secret for authentication.
+	ConfigFile          string        `env:"CONFIG" json:"-"`                                      // ConfigFile represents the JSON or YAML configuration file.
+	PrintConfig         bool          `json:"-"`                                                   // PrintConfig represents whether the effective configuration is printed instead of starting the server.
+	HTTPListen          string        `env:"SERVER_ADDRESS" json:"server_address"`                 // HTTPListen represents the address and port to run the HTTP server.
+	HTTPPprof           string        `env:"PPROF_SERVER_ADDRESS" json:"pprof_server_address"`     // HTTPPprof represents the address and port to run the pprof server.
+	GRPCListen          string        `env:"GRPC_SERVER_ADDRESS" json:"grpc_server_address"`       // GRPCListen represents the address and port to run the gRPC server, empty to disable it.
+	BaseURL             string        `env:"BASE_URL" json:"base_url"`                             // BaseURL represents the base address of the resulting shortened URL.
+	LogLevel            string        `env:"LOG_LEVEL" json:"log_level"`                           // LogLevel represents the log level for logging.
+	FileStoragePath     string        `env:"FILE_STORAGE_PATH" json:"file_storage_path"`           // FileStoragePath represents the file path for storing data.
+	PgDsn               string        `env:"DATABASE_DSN" json:"database_dsn"`                     // PgDsn represents the database connection line for PostgreSQL.
+	JWTSecret           string        `env:"JWT_SECRET" json:"jwt_secret"`                         // JWTSecret represents the JWT cookie secret for authentication.
+	JWTKeys             string        `env:"JWT_KEYS" json:"jwt_keys"`                             // JWTKeys represents the comma separated kid:alg:value JWT keys replacing JWTSecret, the value is the secret of HS256 keys or the PEM file of RS256 and EdDSA keys.
+	JWTActiveKey        string        `env:"JWT_ACTIVE_KEY" json:"jwt_active_key"`                 // JWTActiveKey represents the ID of the JWT key signing new tokens, the first of JWTKeys if empty.
+	CookiePath          string        `env:"COOKIE_PATH" json:"cookie_path"`                       // CookiePath represents the URL path prefix the session cookie is sent to.
+	CookieDomain        string        `env:"COOKIE_DOMAIN" json:"cookie_domain"`                   // CookieDomain represents the domain the session cookie is sent to, empty for the host of the server only.
+	CookieSecure        bool          `env:"COOKIE_SECURE" json:"cookie_secure"`                   // CookieSecure represents whether the session cookie is restricted to HTTPS requests, always the case when EnableHTTPS is set.
+	CookieHTTPOnly      bool          `env:"COOKIE_HTTP_ONLY" json:"cookie_http_only"`             // CookieHTTPOnly represents whether the session cookie is hidden from scripts.
+	CookieSameSite      string        `env:"COOKIE_SAME_SITE" json:"cookie_same_site"`             // CookieSameSite represents the SameSite attribute of the session cookie: lax, strict or none, empty to leave it out.
+	SessionTTL          time.Duration `env:"SESSION_TTL" json:"session_ttl"`                       // SessionTTL represents the lifetime of the session token and cookie.
+	SessionRenewBefore  time.Duration `env:"SESSION_RENEW_BEFORE" json:"session_renew_before"`     // SessionRenewBefore represents the remaining lifetime under which the session token of an active user is renewed, 0 disables renewal.
+	AdminLogins         string        `env:"ADMIN_LOGINS" json:"admin_logins"`                     // AdminLogins represents the comma separated logins of the accounts granted the admin role.
+	EnableHTTPS         bool          `env:"ENABLE_HTTPS" json:"enable_https"`                     // EnableHTTPS represents whether the HTTP server serves HTTPS.
+	TLSCertFile         string        `env:"TLS_CERT_FILE" json:"tls_cert_file"`                   // TLSCertFile represents the certificate file of the HTTPS server, a self-signed one is generated if empty.
+	TLSKeyFile          string        `env:"TLS_KEY_FILE" json:"tls_key_file"`                     // TLSKeyFile represents the private key file of the HTTPS server.
+	TLSCacheDir         string        `env:"TLS_CACHE_DIR" json:"tls_cache_dir"`                   // TLSCacheDir represents the directory caching the generated self-signed certificate.
+	HTTPSRedirect       string        `env:"HTTPS_REDIRECT_ADDRESS" json:"https_redirect_address"` // HTTPSRedirect represents the address and port of the HTTP listener redirecting to HTTPS, empty to disable it.
+	TrustedSubnet       string        `env:"TRUSTED_SUBNET" json:"trusted_subnet"`                 // TrustedSubnet represents the CIDR of the clients allowed to call the internal endpoints.
+	ReadTimeout         time.Duration `env:"REPO_READ_TIMEOUT" json:"repo_read_timeout"`           // ReadTimeout represents the maximum duration of a single repository read.
+	WriteTimeout        time.Duration `env:"REPO_WRITE_TIMEOUT" json:"repo_write_timeout"`         // WriteTimeout represents the maximum duration of a single repository write.
+	CodeGenerator       string        `env:"SHORT_CODE_GENERATOR" json:"short_code_generator"`     // CodeGenerator represents the short code generation strategy: random, sequence or hashids.
+	CodeLength          int           `env:"SHORT_CODE_LENGTH" json:"short_code_length"`           // CodeLength represents the length of random codes and the minimum length of hashids codes.
+	CodeSalt            string        `env:"SHORT_CODE_SALT" json:"short_code_salt"`               // CodeSalt represents the salt obfuscating hashids codes.
+	SweepInterval       time.Duration `env:"EXPIRED_SWEEP_INTERVAL" json:"expired_sweep_interval"` // SweepInterval represents the time between two sweeps of expired links.
+	ExpiredRetention    time.Duration `env:"EXPIRED_RETENTION" json:"expired_retention"`           // ExpiredRetention represents the time expired links answer 410 Gone before being swept.
+	ArchiveExpired      bool          `env:"ARCHIVE_EXPIRED" json:"archive_expired"`               // ArchiveExpired represents whether swept links are archived instead of purged.
+	ClickBufferSize     int           `env:"CLICK_BUFFER_SIZE" json:"click_buffer_size"`           // ClickBufferSize represents the number of buffered clicks before new ones are dropped, 0 disables click tracking.
+	ClickFlushInterval  time.Duration `env:"CLICK_FLUSH_INTERVAL" json:"click_flush_interval"`     // ClickFlushInterval represents the maximum time a click waits in the buffer.
+	ClickHashSalt       string        `env:"CLICK_HASH_SALT" json:"click_hash_salt"`               // ClickHashSalt represents the key of the HMAC hashing visitor IP addresses.
+	ClickHashSaltFile   string        `env:"CLICK_HASH_SALT_FILE" json:"click_hash_salt_file"`     // ClickHashSaltFile represents the file keeping the random key generated when ClickHashSalt is not set.
+	DeleteQueueSize     int           `env:"DELETE_QUEUE_SIZE" json:"delete_queue_size"`           // DeleteQueueSize represents the number of queued deletion requests before new ones are rejected, 0 deletes synchronously.
+	DeleteWorkers       int           `env:"DELETE_WORKERS" json:"delete_workers"`                 // DeleteWorkers represents the number of workers writing the queued deletions.
+	DeleteBatchSize     int           `env:"DELETE_BATCH_SIZE" json:"delete_batch_size"`           // DeleteBatchSize represents the number of URLs a worker merges before writing them.
+	DeleteFlushInterval time.Duration `env:"DELETE_FLUSH_INTERVAL" json:"delete_flush_interval"`   // DeleteFlushInterval represents the maximum time a deletion waits in a worker batch.
+	TraceExporter       string        `env:"TRACE_EXPORTER" json:"trace_exporter"`                 // TraceExporter represents the span exporter: none, stdout, file or otlp.
+	TraceEndpoint       string        `env:"TRACE_ENDPOINT" json:"trace_endpoint"`                 // TraceEndpoint represents the URL of the OTLP/HTTP collector receiving the spans.
+	TraceFile           string        `env:"TRACE_FILE" json:"trace_file"`                         // TraceFile represents the path the file exporter appends the spans to.
+	CacheSize           int           `env:"CACHE_SIZE" json:"cache_size"`                         // CacheSize represents the number of short URLs kept in the lookup cache, 0 disables the cache.
+	CacheTTL            time.Duration `env:"CACHE_TTL" json:"cache_ttl"`                           // CacheTTL represents the lifetime of the cached existing short URLs.
+	CacheNegativeTTL    time.Duration `env:"CACHE_NEGATIVE_TTL" json:"cache_negative_ttl"`         // CacheNegativeTTL represents the lifetime of the cached unknown short URLs, 0 disables caching them.
+	CreateRateLimit     int           `env:"CREATE_RATE_LIMIT" json:"create_rate_limit"`           // CreateRateLimit represents the number of URL shortening requests a client can make per minute, 0 disables the limit and is the default.
+	CreateRateBurst     int           `env:"CREATE_RATE_BURST" json:"create_rate_burst"`           // CreateRateBurst represents the number of URL shortening requests a client can make at once.
+	RedirectRateLimit   int           `env:"REDIRECT_RATE_LIMIT" json:"redirect_rate_limit"`       // RedirectRateLimit represents the number of redirects a client can request per minute, 0 disables the limit and is the default.
+	RedirectRateBurst   int           `env:"REDIRECT_RATE_BURST" json:"redirect_rate_burst"`       // RedirectRateBurst represents the number of redirects a client can request at once.
+	DeleteRateLimit     int           `env:"DELETE_RATE_LIMIT" json:"delete_rate_limit"`           // DeleteRateLimit represents the number of deletion requests a client can make per minute, 0 disables the limit and is the default.
+	DeleteRateBurst     int           `env:"DELETE_RATE_BURST" json:"delete_rate_burst"`           // DeleteRateBurst represents the number of deletion requests a client can make at once.
+	AuthRateLimit       int           `env:"AUTH_RATE_LIMIT" json:"auth_rate_limit"`               // AuthRateLimit represents the number of signup and login requests an IP address can make per minute, 0 disables the limit.
+	AuthRateBurst       int           `env:"AUTH_RATE_BURST" json:"auth_rate_burst"`               // AuthRateBurst represents the number of signup and login requests an IP address can make at once.
+	RateLimitIdle       time.Duration `env:"RATE_LIMIT_IDLE" json:"rate_limit_idle"`               // RateLimitIdle represents the time after which the rate limit state of an idle client is dropped.
 }
 
-// InitConfig initializes the application configuration from environment variables and command-line flags.
+// InitConfig initializes the application configuration from the command-line flags,
+// environment variables and configuration file, see Load. It panics if the configuration is invalid.
 func InitConfig() Conf {
-	conf := Conf{}
-	flag.StringVar(&conf.HTTPListen, "a", "localhost:8080", "address and port to run server")
-	flag.StringVar(&conf.HTTPPprof, "p", "localhost:9595", "address and port to run server")
-	flag.StringVar(&conf.BaseURL, "b", "http://localhost:8080", "base address of the resulting shortened URL")
-	flag.StringVar(&conf.LogLevel, "l", "info", "log level")
-	flag.StringVar(&conf.FileStoragePath, "f", "/tmp/short-url-repo.json", "file path")
-	flag.StringVar(&conf.PgDsn, "d", "", "database connection line")
-	flag.StringVar(&conf.JWTSecret, "jwt_secret", "supersecret", "jwt cookie secret")
-	flag.Parse()
-
-	err := env.Parse(&conf)
+	conf, err := Load(flag.CommandLine, os.Args[1:])
 	if err != nil {
 		panic(err)
 	}
@@ -38,6 +83,200 @@ func InitConfig() Conf {
 	return conf
 }
 
+// Default returns the default application configuration.
+// Rate limits are disabled by default, setting create_rate_limit, redirect_rate_limit
+// or delete_rate_limit, or their environment variables, enables them. Only the signup and login
+// requests, each checking a password hash, are limited by default.
+// The gRPC server is disabled unless grpc_server_address, or its environment variable, is set.
+func Default() Conf {
+	return Conf{
+		HTTPListen:          "localhost:8080",
+		HTTPPprof:           "localhost:9595",
+		BaseURL:             "http://localhost:8080",
+		LogLevel:            "info",
+		FileStoragePath:     "/tmp/short-url-repo.json",
+		JWTSecret:           "supersecret",
+		CookiePath:          "/",
+		CookieHTTPOnly:      true,
+		CookieSameSite:      "lax",
+		SessionTTL:          24 * time.Hour,
+		SessionRenewBefore:  12 * time.Hour,
+		TLSCacheDir:         "/tmp/go-shortener-tls",
+		ReadTimeout:         3 * time.Second,
+		WriteTimeout:        5 * time.Second,
+		CodeGenerator:       "random",
+		CodeLength:          8,
+		SweepInterval:       time.Minute,
+		ExpiredRetention:    24 * time.Hour,
+		ClickBufferSize:     10000,
+		ClickFlushInterval:  time.Second,
+		ClickHashSaltFile:   "/tmp/go-shortener-click-salt",
+		DeleteQueueSize:     1000,
+		DeleteWorkers:       4,
+		DeleteBatchSize:     500,
+		DeleteFlushInterval: time.Second,
+		TraceExporter:       "none",
+		TraceFile:           "traces.json",
+		CacheSize:           10000,
+		CacheTTL:            time.Minute,
+		CacheNegativeTTL:    10 * time.Second,
+		CreateRateBurst:     20,
+		RedirectRateBurst:   100,
+		DeleteRateBurst:     10,
+		AuthRateLimit:       10,
+		AuthRateBurst:       5,
+		RateLimitIdle:       10 * time.Minute,
+	}
+}
+
+// Load parses the command-line arguments with the flag set and builds the configuration.
+// Settings are taken, from the highest precedence to the lowest, from the flags set in the arguments,
+// the environment variables, the configuration file given by -c or CONFIG, and the defaults.
+// The configuration is validated, all problems found are reported in the returned error.
+func Load(fs *flag.FlagSet, args []string) (Conf, error) {
+	parsed := Default()
+	bindFlags(fs, &parsed)
+
+	if err := fs.Parse(args); err != nil {
+		return Conf{}, err
+	}
+
+	conf := Default()
+
+	configFile := os.Getenv("CONFIG")
+	if isFlagSet(fs, "c") {
+		configFile = parsed.ConfigFile
+	}
+
+	if configFile != "" {
+		if err := loadFile(&conf, configFile); err != nil {
+			return Conf{}, err
+		}
+	}
+
+	if err := env.Parse(&conf); err != nil {
+		return Conf{}, err
+	}
+
+	explicit := flag.NewFlagSet(fs.Name(), flag.ContinueOnError)
+	bindFlags(explicit, &conf)
+
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		// The flag set may hold other flags, like the ones of the go test binary.
+		if err == nil && explicit.Lookup(f.Name) != nil {
+			err = explicit.Set(f.Name, f.Value.String())
+		}
+	})
+	if err != nil {
+		return Conf{}, err
+	}
+
+	conf.ConfigFile = configFile
+
+	if conf.EnableHTTPS {
+		conf.BaseURL = httpsURL(conf.BaseURL)
+	}
+
+	if err = conf.Validate(); err != nil {
+		return Conf{}, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	return conf, nil
+}
+
+// bindFlags defines the command-line flags of the configuration in the flag set,
+// with the current values of the configuration as defaults.
+func bindFlags(fs *flag.FlagSet, conf *Conf) {
+	fs.StringVar(&conf.ConfigFile, "c", conf.ConfigFile, "JSON or YAML configuration file")
+	fs.BoolVar(&conf.PrintConfig, "print-config", conf.PrintConfig, "print the effective configuration with secrets masked and exit")
+	fs.StringVar(&conf.HTTPListen, "a", conf.HTTPListen, "address and port to run server")
+	fs.StringVar(&conf.HTTPPprof, "p", conf.HTTPPprof, "address and port to run server")
+	fs.StringVar(&conf.GRPCListen, "g", conf.GRPCListen, "address and port to run gRPC server, empty to disable")
+	fs.StringVar(&conf.BaseURL, "b", conf.BaseURL, "base address of the resulting shortened URL")
+	fs.StringVar(&conf.LogLevel, "l", conf.LogLevel, "log level")
+	fs.StringVar(&conf.FileStoragePath, "f", conf.FileStoragePath, "file path")
+	fs.StringVar(&conf.PgDsn, "d", conf.PgDsn, "database connection line")
+	fs.StringVar(&conf.JWTSecret, "jwt_secret", conf.JWTSecret, "jwt cookie secret")
+	fs.StringVar(&conf.JWTKeys, "jwt_keys", conf.JWTKeys, "comma separated kid:alg:value jwt keys replacing the jwt secret, alg is HS256 with a secret value, or RS256 or EdDSA with a PEM file value")
+	fs.StringVar(&conf.JWTActiveKey, "jwt_active_key", conf.JWTActiveKey, "id of the jwt key signing new tokens, the first jwt key if empty")
+	fs.StringVar(&conf.CookiePath, "cookie_path", conf.CookiePath, "URL path prefix the session cookie is sent to")
+	fs.StringVar(&conf.CookieDomain, "cookie_domain", conf.CookieDomain, "domain the session cookie is sent to, empty for the host of the server only")
+	fs.BoolVar(&conf.CookieSecure, "cookie_secure", conf.CookieSecure, "restrict the session cookie to HTTPS requests, always done when serving HTTPS")
+	fs.BoolVar(&conf.CookieHTTPOnly, "cookie_http_only", conf.CookieHTTPOnly, "hide the session cookie from scripts")
+	fs.StringVar(&conf.CookieSameSite, "cookie_same_site", conf.CookieSameSite, "SameSite attribute of the session cookie: lax, strict or none, empty to leave it out")
+	fs.DurationVar(&conf.SessionTTL, "session_ttl", conf.SessionTTL, "lifetime of the session token and cookie")
+	fs.DurationVar(&conf.SessionRenewBefore, "session_renew_before", conf.SessionRenewBefore, "remaining lifetime under which the session token of an active user is renewed, 0 to disable renewal")
+	fs.StringVar(&conf.AdminLogins, "admin_logins", conf.AdminLogins, "comma separated logins of the accounts granted the admin role")
+	fs.BoolVar(&conf.EnableHTTPS, "s", conf.EnableHTTPS, "serve HTTPS")
+	fs.StringVar(&conf.TLSCertFile, "tls_cert", conf.TLSCertFile, "certificate file of the HTTPS server, a self-signed one is generated if empty")
+	fs.StringVar(&conf.TLSKeyFile, "tls_key", conf.TLSKeyFile, "private key file of the HTTPS server")
+	fs.StringVar(&conf.TLSCacheDir, "tls_cache_dir", conf.TLSCacheDir, "directory caching the generated self-signed certificate")
+	fs.StringVar(&conf.HTTPSRedirect, "https_redirect", conf.HTTPSRedirect, "address and port of the HTTP listener redirecting to HTTPS, empty to disable")
+	fs.StringVar(&conf.TrustedSubnet, "t", conf.TrustedSubnet, "CIDR of the clients allowed to call the internal endpoints, empty to forbid them")
+	fs.DurationVar(&conf.ReadTimeout, "read_timeout", conf.ReadTimeout, "timeout of a single repository read, 0 to disable")
+	fs.DurationVar(&conf.WriteTimeout, "write_timeout", conf.WriteTimeout, "timeout of a single repository write, 0 to disable")
+	fs.StringVar(&conf.CodeGenerator, "code_generator", conf.CodeGenerator, "short code generator: random, sequence or hashids")
+	fs.IntVar(&conf.CodeLength, "code_length", conf.CodeLength, "length of random short codes, minimum length of hashids short codes")
+	fs.StringVar(&conf.CodeSalt, "code_salt", conf.CodeSalt, "salt of hashids short codes")
+	fs.DurationVar(&conf.SweepInterval, "sweep_interval", conf.SweepInterval, "time between two sweeps of expired links, 0 to disable")
+	fs.DurationVar(&conf.ExpiredRetention, "expired_retention", conf.ExpiredRetention, "time expired links are kept before being swept")
+	fs.BoolVar(&conf.ArchiveExpired, "archive_expired", conf.ArchiveExpired, "archive swept links instead of purging them")
+	fs.IntVar(&conf.ClickBufferSize, "click_buffer", conf.ClickBufferSize, "number of buffered clicks, 0 to disable click tracking")
+	fs.DurationVar(&conf.ClickFlushInterval, "click_flush_interval", conf.ClickFlushInterval, "maximum time a click waits in the buffer")
+	fs.StringVar(&conf.ClickHashSalt, "click_hash_salt", conf.ClickHashSalt, "key of the HMAC hashing visitor IP addresses")
+	fs.StringVar(&conf.ClickHashSaltFile, "click_hash_salt_file", conf.ClickHashSaltFile, "file keeping the random key generated when click_hash_salt is not set")
+	fs.IntVar(&conf.DeleteQueueSize, "delete_queue", conf.DeleteQueueSize, "number of queued deletion requests, 0 to delete synchronously")
+	fs.IntVar(&conf.DeleteWorkers, "delete_workers", conf.DeleteWorkers, "number of workers writing the queued deletions")
+	fs.IntVar(&conf.DeleteBatchSize, "delete_batch", conf.DeleteBatchSize, "number of URLs merged into a single deletion write")
+	fs.DurationVar(&conf.DeleteFlushInterval, "delete_flush_interval", conf.DeleteFlushInterval, "maximum time a deletion waits in a worker batch")
+	fs.StringVar(&conf.TraceExporter, "trace_exporter", conf.TraceExporter, "span exporter: none, stdout, file or otlp")
+	fs.StringVar(&conf.TraceEndpoint, "trace_endpoint", conf.TraceEndpoint, "URL of the OTLP/HTTP collector receiving the spans, like http://localhost:4318")
+	fs.StringVar(&conf.TraceFile, "trace_file", conf.TraceFile, "path the file exporter appends the spans to")
+	fs.IntVar(&conf.CacheSize, "cache_size", conf.CacheSize, "number of short URLs kept in the lookup cache, 0 to disable the cache")
+	fs.DurationVar(&conf.CacheTTL, "cache_ttl", conf.CacheTTL, "lifetime of the cached existing short URLs")
+	fs.DurationVar(&conf.CacheNegativeTTL, "cache_negative_ttl", conf.CacheNegativeTTL, "lifetime of the cached unknown short URLs, 0 to not cache them")
+	fs.IntVar(&conf.CreateRateLimit, "create_rate_limit", conf.CreateRateLimit, "URL shortening requests per minute of a client, 0 to disable the limit")
+	fs.IntVar(&conf.CreateRateBurst, "create_rate_burst", conf.CreateRateBurst, "URL shortening requests a client can make at once")
+	fs.IntVar(&conf.RedirectRateLimit, "redirect_rate_limit", conf.RedirectRateLimit, "redirects per minute of a client, 0 to disable the limit")
+	fs.IntVar(&conf.RedirectRateBurst, "redirect_rate_burst", conf.RedirectRateBurst, "redirects a client can request at once")
+	fs.IntVar(&conf.DeleteRateLimit, "delete_rate_limit", conf.DeleteRateLimit, "deletion requests per minute of a client, 0 to disable the limit")
+	fs.IntVar(&conf.DeleteRateBurst, "delete_rate_burst", conf.DeleteRateBurst, "deletion requests a client can make at once")
+	fs.IntVar(&conf.AuthRateLimit, "auth_rate_limit", conf.AuthRateLimit, "signup and login requests per minute of an IP address, 0 to disable the limit")
+	fs.IntVar(&conf.AuthRateBurst, "auth_rate_burst", conf.AuthRateBurst, "signup and login requests an IP address can make at once")
+	fs.DurationVar(&conf.RateLimitIdle, "rate_limit_idle", conf.RateLimitIdle, "time after which the rate limit state of an idle client is dropped")
+}
+
+// isFlagSet checks if the flag was set in the parsed arguments.
+func isFlagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// httpsURL replaces the http scheme of the URL with https.
+func httpsURL(u string) string {
+	if rest, found := strings.CutPrefix(u, "http://"); found {
+		return "https://" + rest
+	}
+	return u
+}
+
+// SplitList returns the non-empty trimmed items of the comma separated list.
+func SplitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // UseDatabase checks if the application is configured to use a database.
 func (c *Conf) UseDatabase() bool {
 	return len(c.PgDsn) > 0
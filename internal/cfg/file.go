@@ -0,0 +1,131 @@
+package cfg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileKeys returns the index of every Conf field settable in the configuration file keyed by its file key,
+// the file key is the json tag of the field.
+func fileKeys() map[string]int {
+	t := reflect.TypeOf(Conf{})
+
+	keys := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if key := t.Field(i).Tag.Get("json"); key != "" && key != "-" {
+			keys[key] = i
+		}
+	}
+
+	return keys
+}
+
+// loadFile applies the settings of the JSON or YAML configuration file to the configuration.
+// The format is chosen by the file extension, files without a known extension are read as JSON.
+// Unknown keys and values of the wrong type are rejected, all of them are reported in the returned error.
+func loadFile(conf *Conf, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("cannot read config file: %w", err)
+	}
+
+	values := make(map[string]any)
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&values)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot parse config file %s: %w", path, err)
+	}
+
+	keys := fileKeys()
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	v := reflect.ValueOf(conf).Elem()
+
+	var errs []error
+	for _, name := range names {
+		index, ok := keys[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown key %q", name))
+			continue
+		}
+
+		if err = setField(v.Field(index), values[name]); err != nil {
+			errs = append(errs, fmt.Errorf("key %q: %w", name, err))
+		}
+	}
+
+	if err = errors.Join(errs...); err != nil {
+		return fmt.Errorf("invalid config file %s:\n%w", path, err)
+	}
+
+	return nil
+}
+
+// setField sets the configuration field to the value decoded from the configuration file.
+// Durations are written as strings like "1m30s".
+func setField(field reflect.Value, value any) error {
+	switch field.Interface().(type) {
+	case time.Duration:
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("expected a duration like \"5s\", got %v", value)
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+	case string:
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("expected a string, got %v", value)
+		}
+		field.SetString(s)
+	case bool:
+		b, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("expected a boolean, got %v", value)
+		}
+		field.SetBool(b)
+	case int:
+		var n int64
+		switch number := value.(type) {
+		case json.Number:
+			var err error
+			if n, err = number.Int64(); err != nil {
+				return fmt.Errorf("expected an integer, got %v", value)
+			}
+		case int:
+			n = int64(number)
+		default:
+			return fmt.Errorf("expected an integer, got %v", value)
+		}
+		field.SetInt(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package cfg
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Azzonya/go-shortener/internal/auth"
+)
+
+// secretMask replaces the secrets in the printed configuration.
+const secretMask = "******"
+
+// dsnPasswordRe matches the password of a key/value PostgreSQL connection line.
+var dsnPasswordRe = regexp.MustCompile(`(password=)(\S+)`)
+
+// Masked returns a copy of the configuration with the secrets masked.
+func (c Conf) Masked() Conf {
+	c.JWTSecret = mask(c.JWTSecret)
+	c.JWTKeys = maskJWTKeys(c.JWTKeys)
+	c.CodeSalt = mask(c.CodeSalt)
+	c.ClickHashSalt = mask(c.ClickHashSalt)
+	c.PgDsn = maskDSN(c.PgDsn)
+
+	return c
+}
+
+// Print writes the configuration with the secrets masked as JSON, in the configuration file format.
+func (c Conf) Print(w io.Writer) error {
+	masked := reflect.ValueOf(c.Masked())
+	t := masked.Type()
+
+	var buf bytes.Buffer
+	buf.WriteString("{")
+
+	first := true
+	for i := 0; i < t.NumField(); i++ {
+		key := t.Field(i).Tag.Get("json")
+		if key == "" || key == "-" {
+			continue
+		}
+
+		value := masked.Field(i).Interface()
+		if d, ok := value.(time.Duration); ok {
+			value = d.String()
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+
+		if !first {
+			buf.WriteString(",")
+		}
+		first = false
+
+		buf.WriteString("\n  \"" + key + "\": ")
+		buf.Write(data)
+	}
+
+	buf.WriteString("\n}\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// mask hides a non-empty secret.
+func mask(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return secretMask
+}
+
+// maskJWTKeys hides the secrets of the HS256 JWT keys, keeping the IDs and the PEM file paths of the other keys.
+// Keys which cannot be parsed are hidden entirely.
+func maskJWTKeys(keys string) string {
+	specs, err := auth.ParseKeySpecs(keys)
+	if err != nil {
+		return mask(keys)
+	}
+
+	masked := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		if spec.Alg == auth.AlgHS256 {
+			spec.Value = secretMask
+		}
+		masked = append(masked, spec.String())
+	}
+
+	return strings.Join(masked, ",")
+}
+
+// maskDSN hides the password of a PostgreSQL connection line in URL or key/value form.
+func maskDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		return u.Redacted()
+	}
+
+	return dsnPasswordRe.ReplaceAllString(dsn, "${1}"+secretMask)
+}
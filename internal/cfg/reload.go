@@ -0,0 +1,34 @@
+package cfg
+
+import (
+	"flag"
+	"os"
+	"reflect"
+)
+
+// Reload builds the configuration again from the command-line arguments of the process,
+// the environment variables and the configuration file, with the same precedence as InitConfig.
+func Reload() (Conf, error) {
+	return Load(flag.NewFlagSet(os.Args[0], flag.ContinueOnError), os.Args[1:])
+}
+
+// Changed returns the configuration file keys of the settings which differ between the configurations.
+func Changed(old, updated Conf) []string {
+	oldValue := reflect.ValueOf(old)
+	updatedValue := reflect.ValueOf(updated)
+	t := oldValue.Type()
+
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		key := t.Field(i).Tag.Get("json")
+		if key == "" || key == "-" {
+			continue
+		}
+
+		if !reflect.DeepEqual(oldValue.Field(i).Interface(), updatedValue.Field(i).Interface()) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
@@ -0,0 +1,20 @@
+package cfg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChanged(t *testing.T) {
+	old := Default()
+
+	updated := old
+	updated.LogLevel = "debug"
+	updated.ReadTimeout = time.Minute
+	updated.ConfigFile = "config.json"
+
+	assert.Equal(t, []string{"log_level", "repo_read_timeout"}, Changed(old, updated))
+	assert.Empty(t, Changed(old, old))
+}
@@ -0,0 +1,210 @@
+package cfg
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/Azzonya/go-shortener/internal/auth"
+	"github.com/Azzonya/go-shortener/internal/codegen"
+	"github.com/Azzonya/go-shortener/internal/tracing"
+)
+
+// Validate checks the configuration and returns an error listing every invalid setting.
+func (c *Conf) Validate() error {
+	var errs []error
+
+	check := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	check(validateAddress("server_address", c.HTTPListen, false))
+	check(validateAddress("pprof_server_address", c.HTTPPprof, false))
+	check(validateAddress("grpc_server_address", c.GRPCListen, true))
+	check(validateAddress("https_redirect_address", c.HTTPSRedirect, true))
+	check(validateBaseURL(c.BaseURL))
+
+	if _, err := zapcore.ParseLevel(c.LogLevel); err != nil {
+		check(fmt.Errorf("log_level: %w", err))
+	}
+
+	if c.TrustedSubnet != "" {
+		if _, _, err := net.ParseCIDR(c.TrustedSubnet); err != nil {
+			check(fmt.Errorf("trusted_subnet: %w", err))
+		}
+	}
+
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		check(errors.New("tls_cert_file and tls_key_file must be set together"))
+	}
+
+	switch c.CodeGenerator {
+	case codegen.RandomGenerator, codegen.SequenceGenerator, codegen.HashidsGenerator:
+	default:
+		check(fmt.Errorf("short_code_generator: unknown generator %q, expected random, sequence or hashids", c.CodeGenerator))
+	}
+
+	switch c.TraceExporter {
+	case tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP:
+	case tracing.ExporterFile:
+		if c.TraceFile == "" {
+			check(errors.New("trace_file must be set for the file trace exporter"))
+		}
+	default:
+		check(fmt.Errorf("trace_exporter: unknown exporter %q, expected none, stdout, file or otlp", c.TraceExporter))
+	}
+
+	check(validateJWTKeys(c.JWTKeys, c.JWTActiveKey))
+	check(validateCookie(c))
+
+	if c.TraceEndpoint != "" {
+		if u, err := url.Parse(c.TraceEndpoint); err != nil || u.Host == "" {
+			check(fmt.Errorf("trace_endpoint: invalid URL %q", c.TraceEndpoint))
+		}
+	}
+
+	check(positive("short_code_length", int64(c.CodeLength)))
+	check(notNegative("repo_read_timeout", int64(c.ReadTimeout)))
+	check(notNegative("repo_write_timeout", int64(c.WriteTimeout)))
+	check(notNegative("expired_sweep_interval", int64(c.SweepInterval)))
+	check(notNegative("expired_retention", int64(c.ExpiredRetention)))
+	check(notNegative("click_buffer_size", int64(c.ClickBufferSize)))
+	check(notNegative("delete_queue_size", int64(c.DeleteQueueSize)))
+	check(notNegative("cache_size", int64(c.CacheSize)))
+	check(notNegative("cache_negative_ttl", int64(c.CacheNegativeTTL)))
+	check(notNegative("create_rate_limit", int64(c.CreateRateLimit)))
+	check(notNegative("redirect_rate_limit", int64(c.RedirectRateLimit)))
+	check(notNegative("delete_rate_limit", int64(c.DeleteRateLimit)))
+	check(notNegative("auth_rate_limit", int64(c.AuthRateLimit)))
+	check(positive("create_rate_burst", int64(c.CreateRateBurst)))
+	check(positive("redirect_rate_burst", int64(c.RedirectRateBurst)))
+	check(positive("delete_rate_burst", int64(c.DeleteRateBurst)))
+	check(positive("auth_rate_burst", int64(c.AuthRateBurst)))
+	check(notNegative("rate_limit_idle", int64(c.RateLimitIdle)))
+
+	if c.CacheSize > 0 {
+		check(positive("cache_ttl", int64(c.CacheTTL)))
+	}
+
+	if c.ClickBufferSize > 0 {
+		check(positive("click_flush_interval", int64(c.ClickFlushInterval)))
+
+		// An empty HMAC key would let anyone reverse the IP hashes by hashing every address.
+		if c.ClickHashSalt == "" && c.ClickHashSaltFile == "" {
+			check(errors.New("click_hash_salt: must be set, or click_hash_salt_file to generate it, when click tracking is enabled"))
+		}
+	}
+
+	if c.DeleteQueueSize > 0 {
+		check(positive("delete_workers", int64(c.DeleteWorkers)))
+		check(positive("delete_batch_size", int64(c.DeleteBatchSize)))
+		check(positive("delete_flush_interval", int64(c.DeleteFlushInterval)))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateAddress checks that the address is a host and port, an empty address is valid if optional.
+func validateAddress(name, addr string, optional bool) error {
+	if addr == "" {
+		if optional {
+			return nil
+		}
+		return fmt.Errorf("%s: must be set", name)
+	}
+
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		return fmt.Errorf("%s: invalid port %q", name, port)
+	}
+
+	return nil
+}
+
+// validateJWTKeys checks the format of the JWT keys and that the active key is one of them.
+// The PEM files of the keys are only read when the keys are loaded.
+func validateJWTKeys(keys, activeID string) error {
+	specs, err := auth.ParseKeySpecs(keys)
+	if err != nil {
+		return fmt.Errorf("jwt_keys: %w", err)
+	}
+
+	if activeID == "" {
+		return nil
+	}
+	if len(specs) == 0 {
+		return errors.New("jwt_active_key: requires jwt_keys")
+	}
+	for _, spec := range specs {
+		if spec.ID == activeID {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("jwt_active_key: unknown key %q", activeID)
+}
+
+// validateCookie checks the SameSite attribute and the lifetimes of the session cookie.
+// A SameSite none cookie must be secure, browsers reject it otherwise.
+func validateCookie(c *Conf) error {
+	var errs []error
+
+	sameSite, err := auth.ParseSameSite(c.CookieSameSite)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("cookie_same_site: %w", err))
+	} else if sameSite == http.SameSiteNoneMode && !c.CookieSecure && !c.EnableHTTPS {
+		errs = append(errs, errors.New("cookie_same_site: none requires cookie_secure or enable_https"))
+	}
+
+	if err = positive("session_ttl", int64(c.SessionTTL)); err != nil {
+		errs = append(errs, err)
+	}
+	if err = notNegative("session_renew_before", int64(c.SessionRenewBefore)); err != nil {
+		errs = append(errs, err)
+	} else if c.SessionTTL > 0 && c.SessionRenewBefore >= c.SessionTTL {
+		errs = append(errs, fmt.Errorf("session_renew_before: must be shorter than session_ttl %s, got %s", c.SessionTTL, c.SessionRenewBefore))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateBaseURL checks that the base URL is an absolute http or https URL.
+func validateBaseURL(baseURL string) error {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return fmt.Errorf("base_url: %w", err)
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("base_url: %q must be an absolute http or https URL", baseURL)
+	}
+
+	return nil
+}
+
+// positive checks that the setting is greater than zero.
+func positive(name string, value int64) error {
+	if value <= 0 {
+		return fmt.Errorf("%s: must be positive, got %d", name, value)
+	}
+	return nil
+}
+
+// notNegative checks that the setting is not negative.
+func notNegative(name string, value int64) error {
+	if value < 0 {
+		return fmt.Errorf("%s: must not be negative, got %d", name, value)
+	}
+	return nil
+}
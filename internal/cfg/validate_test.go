@@ -0,0 +1,145 @@
+package cfg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConf_Validate(t *testing.T) {
+	tests := []struct {
+		name       string
+		modify     func(c *Conf)
+		wantErrors []string
+	}{
+		{
+			name:   "defaults",
+			modify: func(c *Conf) {},
+		},
+		{
+			name: "optional addresses disabled",
+			modify: func(c *Conf) {
+				c.GRPCListen = ""
+				c.HTTPSRedirect = ""
+			},
+		},
+		{
+			name: "every problem reported",
+			modify: func(c *Conf) {
+				c.HTTPListen = "localhost"
+				c.GRPCListen = "localhost:99999"
+				c.BaseURL = "/relative"
+				c.LogLevel = "loud"
+				c.TrustedSubnet = "10.0.0.0"
+				c.TLSCertFile = "cert.pem"
+				c.CodeGenerator = "uuid"
+				c.CodeLength = 0
+				c.DeleteWorkers = 0
+			},
+			wantErrors: []string{
+				"server_address", "grpc_server_address", "base_url", "log_level", "trusted_subnet",
+				"tls_cert_file", "short_code_generator", "short_code_length", "delete_workers",
+			},
+		},
+		{
+			name: "deletion workers unused without a queue",
+			modify: func(c *Conf) {
+				c.DeleteQueueSize = 0
+				c.DeleteWorkers = 0
+			},
+		},
+		{
+			name: "click hash salt missing",
+			modify: func(c *Conf) {
+				c.ClickHashSaltFile = ""
+			},
+			wantErrors: []string{"click_hash_salt"},
+		},
+		{
+			name: "click hash salt unused without click tracking",
+			modify: func(c *Conf) {
+				c.ClickBufferSize = 0
+				c.ClickHashSaltFile = ""
+			},
+		},
+		{
+			name: "unknown trace exporter",
+			modify: func(c *Conf) {
+				c.TraceExporter = "jaeger"
+				c.TraceEndpoint = "localhost"
+			},
+			wantErrors: []string{"trace_exporter", "trace_endpoint"},
+		},
+		{
+			name: "file trace exporter without a file",
+			modify: func(c *Conf) {
+				c.TraceExporter = "file"
+				c.TraceFile = ""
+			},
+			wantErrors: []string{"trace_file"},
+		},
+		{
+			name: "jwt keys",
+			modify: func(c *Conf) {
+				c.JWTKeys = "2024:EdDSA:/etc/shortener/jwt.pem, legacy:HS256:old:secret"
+				c.JWTActiveKey = "2024"
+			},
+		},
+		{
+			name: "unknown active jwt key",
+			modify: func(c *Conf) {
+				c.JWTKeys = "2024:RS256:/etc/shortener/jwt.pem"
+				c.JWTActiveKey = "2023"
+			},
+			wantErrors: []string{"jwt_active_key"},
+		},
+		{
+			name: "invalid jwt key",
+			modify: func(c *Conf) {
+				c.JWTKeys = "2024:ES256:/etc/shortener/jwt.pem"
+			},
+			wantErrors: []string{"jwt_keys"},
+		},
+		{
+			name: "same site none cookie over https",
+			modify: func(c *Conf) {
+				c.CookieSameSite = "none"
+				c.EnableHTTPS = true
+			},
+		},
+		{
+			name: "invalid session cookie",
+			modify: func(c *Conf) {
+				c.CookieSameSite = "none"
+				c.SessionTTL = time.Hour
+				c.SessionRenewBefore = 2 * time.Hour
+			},
+			wantErrors: []string{"cookie_same_site", "session_renew_before"},
+		},
+		{
+			name: "unknown same site",
+			modify: func(c *Conf) {
+				c.CookieSameSite = "relaxed"
+				c.SessionTTL = 0
+			},
+			wantErrors: []string{"cookie_same_site", "session_ttl"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := Default()
+			tt.modify(&conf)
+
+			err := conf.Validate()
+			if len(tt.wantErrors) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			for _, want := range tt.wantErrors {
+				assert.ErrorContains(t, err, want)
+			}
+		})
+	}
+}
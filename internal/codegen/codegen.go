@@ -0,0 +1,227 @@
+// Package codegen provides strategies for generating short codes of shortened URLs.
+//
+// A strategy is selected by name with New:
+//   - "random" generates random base62 codes of a fixed length;
+//   - "sequence" encodes the values of a storage sequence in base62;
+//   - "hashids" encodes the values of a storage sequence as obfuscated codes of a minimum length,
+//     so that consecutive links do not get consecutive codes.
+package codegen
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// Generator names accepted by New.
+const (
+	RandomGenerator   = "random"   // RandomGenerator selects the Random strategy.
+	SequenceGenerator = "sequence" // SequenceGenerator selects the Sequence strategy.
+	HashidsGenerator  = "hashids"  // HashidsGenerator selects the Hashids strategy.
+)
+
+// base62Alphabet contains the characters short codes are made of.
+const base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// ErrUnknownGenerator is returned by New for an unsupported generator name.
+var ErrUnknownGenerator = errors.New("unknown short code generator")
+
+// CodeGenerator generates short codes for shortened URLs.
+// Codes are not guaranteed to be unique, the caller must handle collisions.
+type CodeGenerator interface {
+	// Generate returns a new short code.
+	Generate(ctx context.Context) (string, error)
+}
+
+// Sequencer provides a monotonically increasing sequence of positive numbers.
+type Sequencer interface {
+	// NextSequence returns the next value of the sequence.
+	NextSequence(ctx context.Context) (int64, error)
+}
+
+// New creates the code generator with the given name.
+// The length is the code length of the random strategy and the minimum code length of the hashids strategy,
+// the salt shuffles the hashids alphabet and the sequencer backs the sequence and hashids strategies.
+func New(name string, length int, salt string, seq Sequencer) (CodeGenerator, error) {
+	switch name {
+	case RandomGenerator:
+		return NewRandom(length)
+	case SequenceGenerator:
+		return NewSequence(seq), nil
+	case HashidsGenerator:
+		return NewHashids(seq, length, salt)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownGenerator, name)
+	}
+}
+
+// Random generates random base62 codes of a fixed length.
+type Random struct {
+	length int // Length of the generated codes.
+}
+
+// NewRandom creates a new random code generator producing codes of the given length.
+func NewRandom(length int) (*Random, error) {
+	if length <= 0 {
+		return nil, fmt.Errorf("invalid short code length %d", length)
+	}
+	return &Random{length: length}, nil
+}
+
+// Generate returns a random base62 code.
+func (g *Random) Generate(_ context.Context) (string, error) {
+	// 248 is the largest multiple of 62 fitting in a byte, rejecting bigger bytes keeps the distribution uniform.
+	const maxByte = 256 - 256%len(base62Alphabet)
+
+	code := make([]byte, 0, g.length)
+	buf := make([]byte, g.length+g.length/4+1)
+
+	for len(code) < g.length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("cannot read random bytes: %w", err)
+		}
+
+		for _, b := range buf {
+			if int(b) >= maxByte {
+				continue
+			}
+
+			code = append(code, base62Alphabet[int(b)%len(base62Alphabet)])
+			if len(code) == g.length {
+				break
+			}
+		}
+	}
+
+	return string(code), nil
+}
+
+// Sequence encodes the values of a sequence in base62.
+type Sequence struct {
+	seq Sequencer // Source of the encoded values.
+}
+
+// NewSequence creates a new sequence-backed code generator.
+func NewSequence(seq Sequencer) *Sequence {
+	return &Sequence{seq: seq}
+}
+
+// Generate returns the base62 representation of the next sequence value.
+func (g *Sequence) Generate(ctx context.Context) (string, error) {
+	n, err := g.seq.NextSequence(ctx)
+	if err != nil {
+		return "", fmt.Errorf("cannot get next sequence value: %w", err)
+	}
+
+	return encode(uint64(n), base62Alphabet, 1), nil
+}
+
+// Hashids encodes the values of a sequence as obfuscated base62 codes.
+//
+// Values are scrambled with a bijection modulo 62^length and written with an alphabet
+// shuffled by the salt, so the codes are unique for unique values and look random,
+// while short links stay as short as the minimum length allows.
+type Hashids struct {
+	seq       Sequencer // Source of the encoded values.
+	minLength int       // Minimum length of the generated codes.
+	alphabet  string    // Alphabet shuffled by the salt.
+	offset    uint64    // Salt-derived offset added to the scrambled value.
+}
+
+// hashidsMultiplier is a prime coprime with 62, so multiplying by it is a bijection modulo 62^n.
+const hashidsMultiplier = 1_000_000_007
+
+// hashidsMaxLength is the longest code whose modulus 62^n fits into uint64.
+const hashidsMaxLength = 10
+
+// NewHashids creates a new obfuscated sequence-backed code generator.
+func NewHashids(seq Sequencer, minLength int, salt string) (*Hashids, error) {
+	if minLength <= 0 || minLength > hashidsMaxLength {
+		return nil, fmt.Errorf("invalid short code length %d, expected 1..%d", minLength, hashidsMaxLength)
+	}
+
+	var offset uint64
+	for i := 0; i < len(salt); i++ {
+		offset = offset*31 + uint64(salt[i])
+	}
+
+	return &Hashids{
+		seq:       seq,
+		minLength: minLength,
+		alphabet:  shuffle(base62Alphabet, salt),
+		offset:    offset,
+	}, nil
+}
+
+// Generate returns the obfuscated code of the next sequence value.
+func (g *Hashids) Generate(ctx context.Context) (string, error) {
+	n, err := g.seq.NextSequence(ctx)
+	if err != nil {
+		return "", fmt.Errorf("cannot get next sequence value: %w", err)
+	}
+
+	value := uint64(n)
+
+	length := g.minLength
+	modulus := pow62(length)
+	for value >= modulus && length < hashidsMaxLength {
+		length++
+		modulus = pow62(length)
+	}
+	if value >= modulus {
+		return "", fmt.Errorf("sequence value %d is too big to be encoded", n)
+	}
+
+	hi, lo := bits.Mul64(value, hashidsMultiplier%modulus)
+	_, scrambled := bits.Div64(hi, lo, modulus)
+	scrambled = (scrambled + g.offset%modulus) % modulus
+
+	return encode(scrambled, g.alphabet, length), nil
+}
+
+// encode writes the value in base62 with the given alphabet, left-padded to the minimum length.
+func encode(value uint64, alphabet string, minLength int) string {
+	base := uint64(len(alphabet))
+
+	var buf [16]byte
+	i := len(buf)
+
+	for value > 0 || len(buf)-i < minLength {
+		i--
+		buf[i] = alphabet[value%base]
+		value /= base
+	}
+
+	return string(buf[i:])
+}
+
+// pow62 returns 62 raised to the given power.
+func pow62(n int) uint64 {
+	result := uint64(1)
+	for i := 0; i < n; i++ {
+		result *= uint64(len(base62Alphabet))
+	}
+	return result
+}
+
+// shuffle deterministically permutes the alphabet using the salt, like the hashids consistent shuffle.
+func shuffle(alphabet, salt string) string {
+	if salt == "" {
+		return alphabet
+	}
+
+	result := []byte(alphabet)
+
+	for i, v, p := len(result)-1, 0, 0; i > 0; i-- {
+		v %= len(salt)
+		integer := int(salt[v])
+		p += integer
+		j := (integer + v + p) % i
+		result[i], result[j] = result[j], result[i]
+		v++
+	}
+
+	return string(result)
+}
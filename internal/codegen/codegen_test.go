@@ -0,0 +1,138 @@
+package codegen
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+)
+
+// counter is a Sequencer counting from one.
+type counter struct {
+	value int64
+}
+
+// NextSequence returns the next counter value.
+func (c *counter) NextSequence(_ context.Context) (int64, error) {
+	c.value++
+	return c.value, nil
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		length  int
+		wantErr bool
+	}{
+		{name: RandomGenerator, length: 8},
+		{name: SequenceGenerator, length: 8},
+		{name: HashidsGenerator, length: 8},
+		{name: RandomGenerator, length: 0, wantErr: true},
+		{name: HashidsGenerator, length: hashidsMaxLength + 1, wantErr: true},
+		{name: "uuid", length: 8, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.name, tt.length, "salt", &counter{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := New("uuid", 8, "", nil); !errors.Is(err, ErrUnknownGenerator) {
+		t.Errorf("New() error = %v, want %v", err, ErrUnknownGenerator)
+	}
+}
+
+func TestRandom_Generate(t *testing.T) {
+	g, err := NewRandom(8)
+	if err != nil {
+		t.Fatalf("NewRandom() error = %v", err)
+	}
+
+	re := regexp.MustCompile(`^[0-9A-Za-z]{8}$`)
+	seen := make(map[string]struct{})
+
+	for i := 0; i < 1000; i++ {
+		code, err := g.Generate(context.Background())
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		if !re.MatchString(code) {
+			t.Fatalf("Generate() = %q, want 8 base62 characters", code)
+		}
+		if _, exist := seen[code]; exist {
+			t.Fatalf("Generate() returned %q twice", code)
+		}
+		seen[code] = struct{}{}
+	}
+}
+
+func TestSequence_Generate(t *testing.T) {
+	g := NewSequence(&counter{value: 60})
+
+	want := []string{"z", "10", "11"}
+	for _, w := range want {
+		got, err := g.Generate(context.Background())
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		if got != w {
+			t.Errorf("Generate() = %v, want %v", got, w)
+		}
+	}
+}
+
+func TestHashids_Generate(t *testing.T) {
+	tests := []struct {
+		name       string
+		minLength  int
+		start      int64
+		wantLength int
+	}{
+		{name: "minimum length", minLength: 3, start: 0, wantLength: 3},
+		{name: "grows past the minimum length", minLength: 2, start: 62 * 62, wantLength: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := NewHashids(&counter{value: tt.start}, tt.minLength, "salt")
+			if err != nil {
+				t.Fatalf("NewHashids() error = %v", err)
+			}
+
+			seen := make(map[string]struct{})
+			previous := ""
+
+			for i := 0; i < 1000; i++ {
+				code, err := g.Generate(context.Background())
+				if err != nil {
+					t.Fatalf("Generate() error = %v", err)
+				}
+				if len(code) != tt.wantLength {
+					t.Fatalf("Generate() = %q, want length %v", code, tt.wantLength)
+				}
+				if _, exist := seen[code]; exist {
+					t.Fatalf("Generate() returned %q twice", code)
+				}
+				if previous != "" && code[:len(code)-1] == previous[:len(previous)-1] {
+					t.Errorf("Generate() = %q after %q, want unrelated codes", code, previous)
+				}
+				seen[code] = struct{}{}
+				previous = code
+			}
+		})
+	}
+}
+
+func TestHashids_Salt(t *testing.T) {
+	first, _ := NewHashids(&counter{}, 6, "first")
+	second, _ := NewHashids(&counter{}, 6, "second")
+
+	a, _ := first.Generate(context.Background())
+	b, _ := second.Generate(context.Background())
+
+	if a == b {
+		t.Errorf("Generate() = %q for different salts, want different codes", a)
+	}
+}
@@ -0,0 +1,14 @@
+package entities
+
+import "time"
+
+// Account represents a registered user account logging in with a login and password.
+// The account ID is the user ID owning the links, like the ID of an anonymous user.
+// The role is not stored, it is granted by the configuration when the account logs in.
+type Account struct {
+	ID           string    `json:"id" db:"id"`
+	Login        string    `json:"login" db:"login"`
+	PasswordHash string    `json:"-" db:"password_hash"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	Role         string    `json:"role,omitempty" db:"-"`
+}
@@ -0,0 +1,30 @@
+package entities
+
+import "time"
+
+// Actions recorded in the audit trail of the administrators.
+const (
+	AuditDisableURL  = "disable_url"  // AuditDisableURL records a link disabled by an administrator.
+	AuditEnableURL   = "enable_url"   // AuditEnableURL records a disabled link enabled again.
+	AuditReassignURL = "reassign_url" // AuditReassignURL records a link given to another owner.
+	AuditBanUser     = "ban_user"     // AuditBanUser records a banned user.
+	AuditUnbanUser   = "unban_user"   // AuditUnbanUser records a user no longer banned.
+)
+
+// Ban represents a user ID whose requests are rejected.
+type Ban struct {
+	UserID    string    `json:"user_id" db:"user_id"`
+	Reason    string    `json:"reason" db:"reason"`
+	BannedBy  string    `json:"banned_by" db:"banned_by"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// AuditEntry represents an action of an administrator in the audit trail.
+type AuditEntry struct {
+	ID        int64     `json:"id" db:"id"`
+	Actor     string    `json:"actor" db:"actor"`
+	Action    string    `json:"action" db:"action"`
+	Target    string    `json:"target" db:"target"`
+	Details   string    `json:"details,omitempty" db:"details"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,38 @@
+package entities
+
+import (
+	"slices"
+	"time"
+)
+
+// Scopes of the API keys, limiting the requests a key can authenticate.
+const (
+	ScopeShorten = "shorten" // ScopeShorten allows shortening URLs.
+	ScopeRead    = "read"    // ScopeRead allows listing the user's URLs and reading their statistics.
+	ScopeDelete  = "delete"  // ScopeDelete allows deleting the user's URLs.
+)
+
+// Scopes lists every scope an API key can be granted.
+var Scopes = []string{ScopeShorten, ScopeRead, ScopeDelete}
+
+// APIKey represents a named API key authenticating a programmatic client as its user.
+// Only the hash of the key's secret is stored.
+type APIKey struct {
+	ID        string     `json:"id" db:"id"`
+	UserID    string     `json:"-" db:"user_id"`
+	Name      string     `json:"name" db:"name"`
+	Hash      string     `json:"-" db:"hash"`
+	Scopes    []string   `json:"scopes" db:"scopes"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+// Revoked checks if the key has been revoked.
+func (k *APIKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+// HasScope checks if the key has been granted the scope.
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
@@ -0,0 +1,25 @@
+package entities
+
+import "time"
+
+// Click represents a single redirect through a short URL.
+type Click struct {
+	ShortURL  string    `json:"short_url" db:"shorturl"`
+	ClickedAt time.Time `json:"clicked_at" db:"clicked_at"`
+	Referrer  string    `json:"referrer,omitempty" db:"referrer"`
+	UserAgent string    `json:"user_agent,omitempty" db:"user_agent"`
+	IPHash    string    `json:"ip_hash,omitempty" db:"ip_hash"`
+}
+
+// LinkStats represents the click statistics of a short URL.
+type LinkStats struct {
+	TotalClicks    int64          `json:"total_clicks"`
+	UniqueVisitors int64          `json:"unique_visitors"`
+	Histogram      []*StatsBucket `json:"histogram"`
+}
+
+// StatsBucket represents the number of clicks in the time bucket starting at Start.
+type StatsBucket struct {
+	Start  time.Time `json:"start"`
+	Clicks int64     `json:"clicks"`
+}
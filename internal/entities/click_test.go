@@ -0,0 +1,25 @@
+package entities
+
+import "testing"
+
+func TestClick(t *testing.T) {
+	expectedFields := map[string]string{
+		"ShortURL":  "short_url",
+		"ClickedAt": "clicked_at",
+		"Referrer":  "referrer,omitempty",
+		"UserAgent": "user_agent,omitempty",
+		"IPHash":    "ip_hash,omitempty",
+	}
+
+	validateFields(t, Click{}, expectedFields)
+}
+
+func TestLinkStats(t *testing.T) {
+	expectedFields := map[string]string{
+		"TotalClicks":    "total_clicks",
+		"UniqueVisitors": "unique_visitors",
+		"Histogram":      "histogram",
+	}
+
+	validateFields(t, LinkStats{}, expectedFields)
+}
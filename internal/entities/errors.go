@@ -0,0 +1,28 @@
+package entities
+
+import "errors"
+
+// Errors shared by all repository backends, so callers can react to them
+// with errors.Is regardless of the storage in use.
+var (
+	// ErrOriginalURLExists is returned when the original URL has already been shortened.
+	ErrOriginalURLExists = errors.New("original URL already exists")
+
+	// ErrShortURLExists is returned when the short URL is already taken by another link.
+	ErrShortURLExists = errors.New("short URL already exists")
+
+	// ErrURLNotFound is returned when the short URL does not exist or belongs to another user.
+	ErrURLNotFound = errors.New("URL not found")
+
+	// ErrAPIKeyNotFound is returned when the API key does not exist or belongs to another user.
+	ErrAPIKeyNotFound = errors.New("API key not found")
+
+	// ErrAccountNotFound is returned when the user account does not exist.
+	ErrAccountNotFound = errors.New("account not found")
+
+	// ErrLoginExists is returned when the login is already taken by another account.
+	ErrLoginExists = errors.New("login already exists")
+
+	// ErrBanNotFound is returned when the user is not banned.
+	ErrBanNotFound = errors.New("ban not found")
+)
@@ -1,11 +1,16 @@
 // Package entities provides data structures used across the URL shortener application.
 package entities
 
+import "time"
+
 // ReqURL represents a request structure for URL shortening.
 type ReqURL struct {
-	ID          string `json:"correlation_id"`
-	OriginalURL string `json:"original_url,omitempty"`
-	ShortURL    string `json:"short_url"`
+	ID          string     `json:"correlation_id"`
+	OriginalURL string     `json:"original_url,omitempty"`
+	ShortURL    string     `json:"short_url"`
+	Alias       string     `json:"alias,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	TTL         int64      `json:"ttl,omitempty"`
 }
 
 // ReqListAll represents a structure for listing all shortened URLs.
@@ -16,9 +21,51 @@ type ReqListAll struct {
 
 // Storage represents a structure for storing URLs in a database.
 type Storage struct {
-	UUID        string `db:"id"`
-	ShortURL    string `db:"shorturl"`
-	OriginalURL string `db:"originalurl"`
-	UserID      string `db:"userid"`
-	DeletedFlag bool   `db:"deleted"`
+	UUID         string     `db:"id"`
+	ShortURL     string     `db:"shorturl"`
+	OriginalURL  string     `db:"originalurl"`
+	UserID       string     `db:"userid"`
+	DeletedFlag  bool       `db:"deleted"`
+	DisabledFlag bool       `db:"disabled"`
+	ExpiresAt    *time.Time `db:"expires_at"`
+}
+
+// DeleteRequest represents the short URLs a user asked to delete.
+type DeleteRequest struct {
+	UserID    string   // UserID is the owner of the URLs.
+	ShortURLs []string // ShortURLs are the short codes of the URLs to delete.
+}
+
+// ServiceStats represents the usage statistics of the whole service.
+type ServiceStats struct {
+	URLs  int64 `json:"urls"`
+	Users int64 `json:"users"`
+}
+
+// Link represents the stored state of a short URL.
+type Link struct {
+	ShortURL    string     `json:"short_url"`            // ShortURL is the short code of the link.
+	OriginalURL string     `json:"original_url"`         // OriginalURL is the URL the link redirects to.
+	UserID      string     `json:"user_id"`              // UserID is the owner of the link, empty for anonymous links.
+	Deleted     bool       `json:"deleted"`              // Deleted reports whether the owner deleted the link.
+	Disabled    bool       `json:"disabled"`             // Disabled reports whether an administrator disabled the link.
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"` // ExpiresAt is the time the link expires, nil if it never expires.
+}
+
+// LinkFilter represents the criteria of a search of the links of every user.
+type LinkFilter struct {
+	Query  string // Query matches the links whose short or original URL contains it, empty matches every link.
+	UserID string // UserID matches the links of the user, empty matches the links of every user.
+	Limit  int    // Limit is the maximum number of links returned.
+	Offset int    // Offset is the number of matching links skipped, in the order they were added.
+}
+
+// Expired reports whether the link has expired at the given time.
+func (l *Link) Expired(now time.Time) bool {
+	return l.ExpiresAt != nil && !l.ExpiresAt.After(now)
+}
+
+// Active reports whether the link redirects at the given time: it is neither deleted, disabled nor expired.
+func (l *Link) Active(now time.Time) bool {
+	return !l.Deleted && !l.Disabled && !l.Expired(now)
 }
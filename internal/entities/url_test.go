@@ -3,6 +3,7 @@ package entities
 import (
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestReqURL(t *testing.T) {
@@ -10,6 +11,9 @@ func TestReqURL(t *testing.T) {
 		"ID":          "correlation_id",
 		"OriginalURL": "original_url,omitempty",
 		"ShortURL":    "short_url",
+		"Alias":       "alias,omitempty",
+		"ExpiresAt":   "expires_at,omitempty",
+		"TTL":         "ttl,omitempty",
 	}
 
 	validateFields(t, ReqURL{}, expectedFields)
@@ -31,6 +35,7 @@ func TestStorage(t *testing.T) {
 		"OriginalURL": "originalurl",
 		"UserID":      "userid",
 		"DeletedFlag": "deleted",
+		"ExpiresAt":   "expires_at",
 	}
 
 	s := Storage{}
@@ -70,3 +75,27 @@ func validateFields(t *testing.T, s interface{}, expectedFields map[string]strin
 		}
 	}
 }
+
+func TestLink_Active(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+
+	tests := []struct {
+		name string
+		link Link
+		want bool
+	}{
+		{name: "never expires", link: Link{}, want: true},
+		{name: "expires later", link: Link{ExpiresAt: &future}, want: true},
+		{name: "expired", link: Link{ExpiresAt: &past}, want: false},
+		{name: "expires now", link: Link{ExpiresAt: &now}, want: false},
+		{name: "deleted", link: Link{Deleted: true}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.link.Active(now); got != tt.want {
+				t.Errorf("Active() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
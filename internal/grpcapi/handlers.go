@@ -0,0 +1,173 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+	"github.com/Azzonya/go-shortener/internal/grpcapi/pb"
+	"github.com/Azzonya/go-shortener/internal/session"
+	shortener_service "github.com/Azzonya/go-shortener/internal/shortener"
+)
+
+// Shorten shortens a single URL.
+func (s *Server) Shorten(ctx context.Context, req *pb.ShortenRequest) (*pb.ShortenResponse, error) {
+	var userID string
+	if u, ok := session.GetUserFromContext(ctx); ok {
+		userID = u.ID
+	}
+
+	expiresAt, err := shortener_service.ResolveExpiration(fromTimestamp(req.GetExpiresAt()), req.GetTtl())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	var result string
+	if req.GetAlias() != "" {
+		result, err = s.shortener.ShortenAndSaveAlias(ctx, req.GetUrl(), req.GetAlias(), userID, expiresAt)
+	} else {
+		result, err = s.shortener.ShortenAndSaveLink(ctx, req.GetUrl(), userID, expiresAt)
+	}
+
+	if errors.Is(err, entities.ErrOriginalURLExists) {
+		if existing, exist := s.shortener.GetOneByOriginalURL(ctx, req.GetUrl()); exist {
+			return nil, status.Errorf(codes.AlreadyExists, "URL is already shortened as %s", existing)
+		}
+	}
+	if err != nil {
+		return nil, shortenError(err)
+	}
+
+	return &pb.ShortenResponse{Result: result}, nil
+}
+
+// ShortenBatch shortens several URLs at once.
+func (s *Server) ShortenBatch(ctx context.Context, req *pb.ShortenBatchRequest) (*pb.ShortenBatchResponse, error) {
+	var userID string
+	if u, ok := session.GetUserFromContext(ctx); ok {
+		userID = u.ID
+	}
+
+	urls := make([]*entities.ReqURL, 0, len(req.GetUrls()))
+	for _, u := range req.GetUrls() {
+		urls = append(urls, &entities.ReqURL{
+			ID:          u.GetCorrelationId(),
+			OriginalURL: u.GetOriginalUrl(),
+			Alias:       u.GetAlias(),
+			ExpiresAt:   fromTimestamp(u.GetExpiresAt()),
+			TTL:         u.GetTtl(),
+		})
+	}
+
+	shortenedURLs, err := s.shortener.ShortenURLs(ctx, urls, userID)
+	if err != nil {
+		return nil, shortenError(err)
+	}
+
+	resp := &pb.ShortenBatchResponse{Urls: make([]*pb.BatchResult, 0, len(shortenedURLs))}
+	for _, u := range shortenedURLs {
+		result := &pb.BatchResult{
+			CorrelationId: u.ID,
+			ShortUrl:      u.ShortURL,
+		}
+		if u.ExpiresAt != nil {
+			result.ExpiresAt = timestamppb.New(*u.ExpiresAt)
+		}
+		resp.Urls = append(resp.Urls, result)
+	}
+
+	return resp, nil
+}
+
+// Resolve returns the original URL of a short URL.
+func (s *Server) Resolve(ctx context.Context, req *pb.ResolveRequest) (*pb.ResolveResponse, error) {
+	link, err := s.shortener.Resolve(ctx, req.GetShortUrl())
+	switch {
+	case errors.Is(err, shortener_service.ErrURLGone):
+		return nil, status.Error(codes.FailedPrecondition, "short URL is gone")
+	case errors.Is(err, entities.ErrURLNotFound):
+		return nil, status.Error(codes.NotFound, "short URL not found")
+	case err != nil:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.ResolveResponse{OriginalUrl: link.OriginalURL}, nil
+}
+
+// ListUserURLs returns the URLs shortened by the caller.
+func (s *Server) ListUserURLs(ctx context.Context, _ *emptypb.Empty) (*pb.ListUserURLsResponse, error) {
+	u, err := session.GetUser(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	list, err := s.shortener.ListAll(ctx, u.ID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &pb.ListUserURLsResponse{Urls: make([]*pb.UserURL, 0, len(list))}
+	for _, v := range list {
+		resp.Urls = append(resp.Urls, &pb.UserURL{
+			ShortUrl:    v.ShortURL,
+			OriginalUrl: v.OriginalURL,
+		})
+	}
+
+	return resp, nil
+}
+
+// DeleteURLs deletes the caller's short URLs, possibly in the background.
+func (s *Server) DeleteURLs(ctx context.Context, req *pb.DeleteURLsRequest) (*emptypb.Empty, error) {
+	u, err := session.GetUser(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	err = s.shortener.DeleteURLs(ctx, req.GetShortUrls(), u.ID)
+	if errors.Is(err, shortener_service.ErrDeletionQueueFull) {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// Ping checks the connectivity of the storage.
+func (s *Server) Ping(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	if err := s.shortener.PingDB(ctx); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// shortenError converts an error of shortening URLs into a gRPC status error.
+func shortenError(err error) error {
+	switch {
+	case errors.Is(err, shortener_service.ErrInvalidAlias), errors.Is(err, shortener_service.ErrInvalidExpiration):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, shortener_service.ErrAliasTaken), errors.Is(err, entities.ErrOriginalURLExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// fromTimestamp converts an optional timestamp into an optional time.
+func fromTimestamp(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+
+	t := ts.AsTime()
+	return &t
+}
@@ -0,0 +1,134 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/Azzonya/go-shortener/internal/apikey"
+	"github.com/Azzonya/go-shortener/internal/entities"
+	"github.com/Azzonya/go-shortener/internal/grpcapi/pb"
+	"github.com/Azzonya/go-shortener/internal/logger"
+	"github.com/Azzonya/go-shortener/internal/session"
+	"github.com/Azzonya/go-shortener/internal/user"
+)
+
+const (
+	authorizationKey = "authorization" // authorizationKey is the metadata key carrying the caller token.
+	apiKeyKey        = "x-api-key"     // apiKeyKey is the metadata key carrying the API key of programmatic callers.
+	bearerPrefix     = "Bearer "       // bearerPrefix precedes the token in the metadata value.
+)
+
+// methodScopes are the API key scopes required by the methods, the other methods require none.
+var methodScopes = map[string]string{
+	pb.Shortener_Shorten_FullMethodName:      entities.ScopeShorten,
+	pb.Shortener_ShortenBatch_FullMethodName: entities.ScopeShorten,
+	pb.Shortener_ListUserURLs_FullMethodName: entities.ScopeRead,
+	pb.Shortener_DeleteURLs_FullMethodName:   entities.ScopeDelete,
+}
+
+// openMethods are the methods served to banned callers too, so that resolving links costs no ban lookup.
+var openMethods = map[string]bool{
+	pb.Shortener_Resolve_FullMethodName: true,
+	pb.Shortener_Ping_FullMethodName:    true,
+}
+
+// AuthInterceptor identifies the caller by the JWT in the authorization metadata and
+// stores the user in the call context, like the REST AuthMiddleware does with the cookie.
+// Callers without a token get a new user, whose token is sent back in the authorization header metadata,
+// as is a token signed with the active key to callers whose token was signed with another key.
+// An API key, in the x-api-key metadata or as the bearer token, authenticates its owner restricted
+// to the scopes of the key, calls needing another scope are rejected with PERMISSION_DENIED.
+// Invalid tokens and keys are rejected with UNAUTHENTICATED, the calls of banned users with PERMISSION_DENIED
+// except for the methods resolving links and checking the storage.
+func (s *Server) AuthInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	token, ok := tokenFromMetadata(ctx)
+
+	var u *user.User
+	var renew bool
+	var err error
+
+	switch {
+	case ok && s.keys != nil && apikey.IsKey(token):
+		var key *entities.APIKey
+		key, err = s.keys.Authenticate(ctx, token)
+		if errors.Is(err, apikey.ErrInvalidKey) {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		if err != nil {
+			logger.Log.Error("cannot check API key", zap.Error(err))
+			return nil, status.Error(codes.Internal, "cannot check API key")
+		}
+
+		u = user.NewWithID(key.UserID)
+		ctx = session.SetScopesContext(ctx, key.Scopes)
+		if scope, scoped := methodScopes[info.FullMethod]; scoped && !session.HasScope(ctx, scope) {
+			return nil, status.Error(codes.PermissionDenied, "API key lacks the "+scope+" scope")
+		}
+	case ok:
+		u, renew, err = s.auth.VerifyToken(token)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+	default:
+		u, err = user.New()
+		if err != nil {
+			logger.Log.Debug("cannot create new user", zap.Error(err))
+			return nil, status.Error(codes.Internal, "cannot create user")
+		}
+		renew = true
+	}
+
+	if !u.IsNew() && s.moderator != nil && !openMethods[info.FullMethod] {
+		banned, errB := s.moderator.Banned(ctx, u.ID)
+		if errB != nil {
+			logger.Log.Error("cannot check ban", zap.Error(errB))
+			return nil, status.Error(codes.Internal, "cannot check ban")
+		}
+		if banned {
+			return nil, status.Error(codes.PermissionDenied, "user is banned")
+		}
+	}
+
+	if renew {
+		token, err = s.auth.NewToken(u)
+		if err != nil {
+			logger.Log.Debug("cannot create token for user", zap.Error(err))
+			return nil, status.Error(codes.Internal, "cannot create token")
+		}
+
+		if err = grpc.SetHeader(ctx, metadata.Pairs(authorizationKey, bearerPrefix+token)); err != nil {
+			return nil, status.Error(codes.Internal, "cannot set token header")
+		}
+	}
+
+	return handler(session.SetUserContext(ctx, u), req)
+}
+
+// tokenFromMetadata returns the API key or the bearer token from the incoming metadata.
+func tokenFromMetadata(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+
+	for _, value := range md.Get(apiKeyKey) {
+		if value != "" {
+			return value, true
+		}
+	}
+
+	for _, value := range md.Get(authorizationKey) {
+		if token, found := strings.CutPrefix(value, bearerPrefix); found && token != "" {
+			return token, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,53 @@
+package grpcapi
+
+import (
+	"context"
+	"math"
+	"net"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/Azzonya/go-shortener/internal/middleware"
+)
+
+// retryAfterKey is the header metadata key carrying the seconds until a rate limited call can be retried.
+const retryAfterKey = "retry-after"
+
+// RateLimitInterceptor limits the rate of calls of every caller with the limiter of the method,
+// like the REST RateLimit middleware does. Callers are identified by their user ID, or by their
+// peer address for new users, so it must run after AuthInterceptor.
+// Rejected calls get RESOURCE_EXHAUSTED and the retry-after header metadata.
+func (s *Server) RateLimitInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	limiter := s.limits[info.FullMethod]
+	if limiter == nil {
+		return handler(ctx, req)
+	}
+
+	allowed, retryAfter := limiter.Allow(middleware.ClientKey(ctx, peerIP(ctx)))
+	if !allowed {
+		seconds := strconv.FormatInt(int64(math.Ceil(retryAfter.Seconds())), 10)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(retryAfterKey, seconds))
+		return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded, retry after "+seconds+"s")
+	}
+
+	return handler(ctx, req)
+}
+
+// peerIP returns the IP address of the caller, or its whole address if it has no port.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	addr := p.Addr.String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
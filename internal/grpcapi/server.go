@@ -0,0 +1,113 @@
+// Package grpcapi provides the gRPC API of the URL shortener mirroring the REST API.
+package grpcapi
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative pb/shortener.proto
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/Azzonya/go-shortener/internal/admin"
+	"github.com/Azzonya/go-shortener/internal/apikey"
+	"github.com/Azzonya/go-shortener/internal/auth"
+	"github.com/Azzonya/go-shortener/internal/grpcapi/pb"
+	"github.com/Azzonya/go-shortener/internal/logger"
+	"github.com/Azzonya/go-shortener/internal/middleware"
+	shortener_service "github.com/Azzonya/go-shortener/internal/shortener"
+)
+
+// Server represents the gRPC API server.
+type Server struct {
+	pb.UnimplementedShortenerServer
+
+	server    *grpc.Server                       // Underlying gRPC server, nil until started.
+	shortener *shortener_service.Shortener       // Service handling the calls.
+	auth      *auth.Auth                         // Validator of the caller tokens.
+	keys      *apikey.Keys                       // Validator of the caller API keys, nil ignores them.
+	moderator *admin.Admin                       // Checker of the banned callers, nil bans nobody.
+	limits    map[string]*middleware.RateLimiter // Rate limiters of the methods keyed by full method name.
+
+	ErrorChan chan error // ErrorChan receives the error stopping the server unexpectedly.
+}
+
+// New creates a new instance of the gRPC API server authenticating callers with the authorizer.
+func New(shortener *shortener_service.Shortener, authorizer *auth.Auth) *Server {
+	return &Server{
+		shortener: shortener,
+		auth:      authorizer,
+		ErrorChan: make(chan error, 1),
+	}
+}
+
+// SetAPIKeys makes the gRPC API server authenticate programmatic callers with the API keys managed by keys,
+// like the REST API does.
+func (s *Server) SetAPIKeys(keys *apikey.Keys) {
+	s.keys = keys
+}
+
+// SetRateLimits limits the rate of calls of every caller to the shortening, resolving and deleting methods
+// with the limiters of the matching REST API routes, nil disables a limit.
+func (s *Server) SetRateLimits(create, redirect, del *middleware.RateLimiter) {
+	s.limits = map[string]*middleware.RateLimiter{
+		pb.Shortener_Shorten_FullMethodName:      create,
+		pb.Shortener_ShortenBatch_FullMethodName: create,
+		pb.Shortener_Resolve_FullMethodName:      redirect,
+		pb.Shortener_DeleteURLs_FullMethodName:   del,
+	}
+}
+
+// SetAdmin makes the gRPC API server reject the calls of the users banned by the moderator.
+func (s *Server) SetAdmin(moderator *admin.Admin) {
+	s.moderator = moderator
+}
+
+// Start starts the gRPC API server listening on the address.
+func (s *Server) Start(lAddr string) error {
+	logger.Log.Info("Running gRPC server", zap.String("address", lAddr))
+
+	listener, err := net.Listen("tcp", lAddr)
+	if err != nil {
+		return err
+	}
+
+	s.serve(listener)
+
+	return nil
+}
+
+// serve serves the gRPC API on the listener in the background.
+func (s *Server) serve(listener net.Listener) {
+	s.server = grpc.NewServer(grpc.ChainUnaryInterceptor(s.AuthInterceptor, s.RateLimitInterceptor))
+	pb.RegisterShortenerServer(s.server, s)
+
+	go func() {
+		err := s.server.Serve(listener)
+		if err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			s.ErrorChan <- err
+		}
+	}()
+}
+
+// Stop gracefully stops the gRPC API server, waiting for the running calls
+// until the context is done, then closing the remaining connections.
+func (s *Server) Stop(ctx context.Context) {
+	if s.server == nil {
+		return
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.server.Stop()
+	}
+}
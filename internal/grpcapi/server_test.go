@@ -0,0 +1,245 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/Azzonya/go-shortener/internal/admin"
+	"github.com/Azzonya/go-shortener/internal/apikey"
+	"github.com/Azzonya/go-shortener/internal/auth"
+	"github.com/Azzonya/go-shortener/internal/entities"
+	"github.com/Azzonya/go-shortener/internal/grpcapi/pb"
+	"github.com/Azzonya/go-shortener/internal/middleware"
+	"github.com/Azzonya/go-shortener/internal/repo/inmemory"
+	shortener_service "github.com/Azzonya/go-shortener/internal/shortener"
+	"github.com/Azzonya/go-shortener/internal/user"
+)
+
+func newTestClient(t *testing.T) pb.ShortenerClient {
+	t.Helper()
+
+	repo, err := inmemory.New(filepath.Join(t.TempDir(), "short-url-repo.json"))
+	require.NoError(t, err)
+
+	s := New(shortener_service.New("http://localhost:8080", repo, nil, 0, 0), auth.New("testsecret"))
+
+	return serveTestClient(t, s)
+}
+
+// serveTestClient starts the server on an in-memory listener and returns a client connected to it.
+func serveTestClient(t *testing.T, s *Server) pb.ShortenerClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+	s.serve(listener)
+	t.Cleanup(func() { s.Stop(context.Background()) })
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewShortenerClient(conn)
+}
+
+func TestServer_Auth(t *testing.T) {
+	client := newTestClient(t)
+
+	var header metadata.MD
+	_, err := client.Shorten(context.Background(), &pb.ShortenRequest{Url: "https://example.com"}, grpc.Header(&header))
+	require.NoError(t, err)
+
+	tokens := header.Get(authorizationKey)
+	require.Len(t, tokens, 1, "a new caller must get a token")
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authorizationKey, tokens[0])
+	resp, err := client.ListUserURLs(ctx, &emptypb.Empty{})
+	require.NoError(t, err)
+	require.Len(t, resp.GetUrls(), 1)
+	assert.Equal(t, "https://example.com", resp.GetUrls()[0].GetOriginalUrl())
+
+	_, err = client.ListUserURLs(context.Background(), &emptypb.Empty{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "a new caller has no URLs")
+
+	ctx = metadata.AppendToOutgoingContext(context.Background(), authorizationKey, bearerPrefix+"invalid")
+	_, err = client.Ping(ctx, &emptypb.Empty{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestServer_Banned(t *testing.T) {
+	repo, err := inmemory.New("")
+	require.NoError(t, err)
+
+	authorizer := auth.New("testsecret")
+	moderator := admin.New(repo)
+
+	s := New(shortener_service.New("http://localhost:8080", repo, nil, 0, 0), authorizer)
+	s.SetAdmin(moderator)
+	client := serveTestClient(t, s)
+
+	token, err := authorizer.NewToken(user.NewWithID("spammer"))
+	require.NoError(t, err)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authorizationKey, bearerPrefix+token)
+
+	_, err = client.Shorten(ctx, &pb.ShortenRequest{Url: "https://spam.example", Alias: "spam"})
+	require.NoError(t, err)
+	require.NoError(t, moderator.BanUser(context.Background(), "admin", "spammer", "spam"))
+
+	_, err = client.Shorten(ctx, &pb.ShortenRequest{Url: "https://spam.example/again"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = client.ListUserURLs(ctx, &emptypb.Empty{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.Resolve(ctx, &pb.ResolveRequest{ShortUrl: "spam"})
+	assert.NoError(t, err, "links are resolved without checking bans")
+}
+
+func TestServer_APIKey(t *testing.T) {
+	repo, err := inmemory.New("")
+	require.NoError(t, err)
+
+	keys := apikey.New(repo)
+	s := New(shortener_service.New("http://localhost:8080", repo, nil, 0, 0), auth.New("testsecret"))
+	s.SetAPIKeys(keys)
+	client := serveTestClient(t, s)
+
+	token, _, err := keys.Mint(context.Background(), "owner", "ci", []string{entities.ScopeShorten})
+	require.NoError(t, err)
+
+	for path, md := range map[string]metadata.MD{
+		"header": metadata.Pairs(apiKeyKey, token),
+		"bearer": metadata.Pairs(authorizationKey, bearerPrefix+token),
+	} {
+		ctx := metadata.NewOutgoingContext(context.Background(), md)
+
+		var header metadata.MD
+		_, err = client.Shorten(ctx, &pb.ShortenRequest{Url: "https://example.com/" + path}, grpc.Header(&header))
+		require.NoError(t, err)
+		assert.Empty(t, header.Get(authorizationKey), "API key callers get no session token")
+
+		_, err = client.ListUserURLs(ctx, &emptypb.Empty{})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err), "the key lacks the read scope")
+	}
+
+	urls, err := repo.ListAll(context.Background(), "owner")
+	require.NoError(t, err)
+	assert.Len(t, urls, 2, "the links belong to the owner of the key")
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), apiKeyKey, token+"tampered")
+	_, err = client.Shorten(ctx, &pb.ShortenRequest{Url: "https://example.com/tampered"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestServer_RateLimit(t *testing.T) {
+	repo, err := inmemory.New("")
+	require.NoError(t, err)
+
+	s := New(shortener_service.New("http://localhost:8080", repo, nil, 0, 0), auth.New("testsecret"))
+	s.SetRateLimits(middleware.NewRateLimiter(middleware.PerMinute(1, 1), time.Minute), nil, nil)
+	client := serveTestClient(t, s)
+
+	// New callers are limited by address, so dropping the token does not reset the limit.
+	_, err = client.Shorten(context.Background(), &pb.ShortenRequest{Url: "https://first.com"})
+	require.NoError(t, err)
+
+	var header metadata.MD
+	_, err = client.Shorten(context.Background(), &pb.ShortenRequest{Url: "https://second.com"}, grpc.Header(&header))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, []string{"60"}, header.Get(retryAfterKey))
+
+	_, err = client.ShortenBatch(context.Background(), &pb.ShortenBatchRequest{})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "batches share the shortening limit")
+
+	_, err = client.Ping(context.Background(), &emptypb.Empty{})
+	assert.NoError(t, err, "methods without a limiter are not limited")
+}
+
+func TestServer_Shorten(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		req      *pb.ShortenRequest
+		wantCode codes.Code
+	}{
+		{
+			name:     "generated code",
+			req:      &pb.ShortenRequest{Url: "https://first.com"},
+			wantCode: codes.OK,
+		},
+		{
+			name:     "already shortened",
+			req:      &pb.ShortenRequest{Url: "https://first.com"},
+			wantCode: codes.AlreadyExists,
+		},
+		{
+			name:     "alias",
+			req:      &pb.ShortenRequest{Url: "https://second.com", Alias: "second"},
+			wantCode: codes.OK,
+		},
+		{
+			name:     "alias taken",
+			req:      &pb.ShortenRequest{Url: "https://third.com", Alias: "second"},
+			wantCode: codes.AlreadyExists,
+		},
+		{
+			name:     "invalid alias",
+			req:      &pb.ShortenRequest{Url: "https://third.com", Alias: "api"},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "expiration in the past",
+			req:      &pb.ShortenRequest{Url: "https://third.com", ExpiresAt: timestamppb.New(time.Now().Add(-time.Hour))},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.Shorten(ctx, tt.req)
+			assert.Equal(t, tt.wantCode, status.Code(err))
+		})
+	}
+}
+
+func TestServer_Resolve(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.Shorten(ctx, &pb.ShortenRequest{Url: "https://example.com", Alias: "example"})
+	require.NoError(t, err)
+
+	batch, err := client.ShortenBatch(ctx, &pb.ShortenBatchRequest{Urls: []*pb.BatchURL{
+		{CorrelationId: "1", OriginalUrl: "https://first.com"},
+		{CorrelationId: "2", OriginalUrl: "https://second.com", Alias: "second", Ttl: 3600},
+	}})
+	require.NoError(t, err)
+	require.Len(t, batch.GetUrls(), 2)
+	assert.Equal(t, "http://localhost:8080/second", batch.GetUrls()[1].GetShortUrl())
+	assert.NotNil(t, batch.GetUrls()[1].GetExpiresAt())
+
+	resp, err := client.Resolve(ctx, &pb.ResolveRequest{ShortUrl: "example"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", resp.GetOriginalUrl())
+
+	_, err = client.Resolve(ctx, &pb.ResolveRequest{ShortUrl: "unknown"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -3,23 +3,29 @@ package logger
 
 import (
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 // Log represents the global logger instance used throughout the application.
 var Log = zap.NewNop()
 
+// level is the log level of the logger built by Initialize, it can be changed at any time with SetLevel.
+var level = zap.NewAtomicLevel()
+
 // Initialize initializes the logger with the specified log level.
 // It takes a log level string as input and sets up the logger accordingly.
 // It returns an error if there is any issue initializing the logger.
-func Initialize(level string) error {
-	lvl, err := zap.ParseAtomicLevel(level)
+func Initialize(lvl string) error {
+	parsed, err := zapcore.ParseLevel(lvl)
 	if err != nil {
 		return err
 	}
 
+	level.SetLevel(parsed)
+
 	cfg := zap.NewProductionConfig()
 
-	cfg.Level = lvl
+	cfg.Level = level
 
 	zl, err := cfg.Build()
 	if err != nil {
@@ -29,3 +35,14 @@ func Initialize(level string) error {
 	Log = zl
 	return nil
 }
+
+// SetLevel changes the log level of the logger without rebuilding it.
+func SetLevel(lvl string) error {
+	parsed, err := zapcore.ParseLevel(lvl)
+	if err != nil {
+		return err
+	}
+
+	level.SetLevel(parsed)
+	return nil
+}
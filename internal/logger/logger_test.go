@@ -1,6 +1,10 @@
 package logger
 
-import "testing"
+import (
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
 
 func TestInitialize(t *testing.T) {
 	type args struct {
@@ -27,3 +31,25 @@ func TestInitialize(t *testing.T) {
 		})
 	}
 }
+
+func TestSetLevel(t *testing.T) {
+	if err := Initialize("info"); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+
+	if Log.Core().Enabled(zapcore.DebugLevel) {
+		t.Errorf("debug enabled at info level")
+	}
+
+	if err := SetLevel("debug"); err != nil {
+		t.Fatalf("SetLevel() error = %v", err)
+	}
+
+	if !Log.Core().Enabled(zapcore.DebugLevel) {
+		t.Errorf("debug disabled after SetLevel(debug)")
+	}
+
+	if err := SetLevel("loud"); err == nil {
+		t.Errorf("SetLevel() error = nil, want error for an unknown level")
+	}
+}
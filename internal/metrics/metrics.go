@@ -0,0 +1,182 @@
+// Package metrics collects the Prometheus metrics of the URL shortener service
+// and exposes them in the Prometheus text format.
+//
+// The metrics are registered in the package registry, which is served by Handler.
+// HTTP requests are measured by Middleware, repository calls by the decorator
+// returned by NewRepo, redirects are counted with ObserveRedirect.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/Azzonya/go-shortener/internal/repo/cache"
+)
+
+// namespace prefixes the names of all metrics of the service.
+const namespace = "shortener"
+
+// Results of redirect requests counted by ObserveRedirect.
+const (
+	RedirectHit  = "hit"  // The short URL was redirected to the original URL
+	RedirectMiss = "miss" // The short URL does not exist
+	RedirectGone = "gone" // The short URL was deleted or has expired
+)
+
+// unmatchedRoute labels the requests not matching any route.
+const unmatchedRoute = "unmatched"
+
+// Registry holds every metric of the service.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of HTTP requests by route, method and status.",
+	}, []string{"route", "method", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of HTTP requests by route, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	redirects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "redirects_total",
+		Help:      "Number of redirect requests by result: hit, miss or gone.",
+	}, []string{"result"})
+
+	repoDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "repo",
+		Name:      "operation_duration_seconds",
+		Help:      "Latency of repository operations by backend and method.",
+		Buckets:   []float64{.0001, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"backend", "method"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		redirects,
+		repoDuration,
+	)
+
+	for _, result := range []string{RedirectHit, RedirectMiss, RedirectGone} {
+		redirects.WithLabelValues(result)
+	}
+}
+
+// Handler returns the HTTP handler serving the metrics of the registry in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// Middleware returns a gin middleware counting the requests and measuring their latency
+// by the matched route pattern, method and response status.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(c.Writer.Status())
+
+		httpRequests.WithLabelValues(route, c.Request.Method, status).Inc()
+		httpDuration.WithLabelValues(route, c.Request.Method, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// ObserveRedirect counts a redirect request with the given result.
+func ObserveRedirect(result string) {
+	redirects.WithLabelValues(result).Inc()
+}
+
+// observeRepo records the latency of a repository operation started at start.
+func observeRepo(backend, method string, start time.Time) {
+	repoDuration.WithLabelValues(backend, method).Observe(time.Since(start).Seconds())
+}
+
+// RegisterQueueDepth exposes the number of requests waiting in the deletion queue returned by depth.
+func RegisterQueueDepth(depth func() int) error {
+	return Registry.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "deletion",
+		Name:      "queue_depth",
+		Help:      "Number of deletion requests waiting in the queue.",
+	}, func() float64 {
+		return float64(depth())
+	}))
+}
+
+// RegisterCache exposes the hits, misses, hit ratio and size of the lookup cache returned by stats.
+func RegisterCache(stats func() cache.Stats) error {
+	ratio := func() float64 {
+		st := stats()
+		if st.Hits+st.Misses == 0 {
+			return 0
+		}
+		return float64(st.Hits) / float64(st.Hits+st.Misses)
+	}
+
+	return registerAll(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "hits_total",
+			Help:      "Number of short URL lookups answered from the cache.",
+		}, func() float64 { return float64(stats().Hits) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "misses_total",
+			Help:      "Number of short URL lookups passed to the repository.",
+		}, func() float64 { return float64(stats().Misses) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "hit_ratio",
+			Help:      "Share of the short URL lookups answered from the cache since the start.",
+		}, ratio),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "entries",
+			Help:      "Number of short URLs in the cache.",
+		}, func() float64 { return float64(stats().Size) }),
+	)
+}
+
+// registerAll registers the collectors in the registry, stopping at the first error.
+func registerAll(cs ...prometheus.Collector) error {
+	for _, c := range cs {
+		if err := Registry.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RegisterPool exposes the connection statistics of the PostgreSQL pool.
+func RegisterPool(pool *pgxpool.Pool) error {
+	return Registry.Register(newPoolCollector(pool))
+}
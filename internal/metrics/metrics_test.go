@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/Azzonya/go-shortener/internal/repo/inmemory"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(Middleware())
+	r.GET("/:id", func(c *gin.Context) {
+		c.Status(http.StatusTemporaryRedirect)
+	})
+
+	for _, path := range []string{"/abc", "/def", "/a/b"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	tests := []struct {
+		name   string
+		labels []string
+		want   float64
+	}{
+		{name: "matched route", labels: []string{"/:id", http.MethodGet, "307"}, want: 2},
+		{name: "unmatched route", labels: []string{unmatchedRoute, http.MethodGet, "404"}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := testutil.ToFloat64(httpRequests.WithLabelValues(tt.labels...)); got != tt.want {
+				t.Errorf("requests = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestObserveRedirect(t *testing.T) {
+	before := testutil.ToFloat64(redirects.WithLabelValues(RedirectGone))
+
+	ObserveRedirect(RedirectGone)
+
+	if got := testutil.ToFloat64(redirects.WithLabelValues(RedirectGone)); got != before+1 {
+		t.Errorf("gone redirects = %v, want %v", got, before+1)
+	}
+}
+
+func TestRepo(t *testing.T) {
+	storage, err := inmemory.New("")
+	if err != nil {
+		t.Fatalf("inmemory.New() error = %v", err)
+	}
+
+	r := NewRepo(storage, "test")
+
+	if err = r.Add(context.Background(), "example.com", "tst", "1", nil); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if got, _ := r.GetByShortURL(context.Background(), "tst"); got != "example.com" {
+		t.Errorf("GetByShortURL() = %v, want %v", got, "example.com")
+	}
+
+	for _, method := range []string{"Add", "GetByShortURL"} {
+		if got := observations(t, "test", method); got != 1 {
+			t.Errorf("%s observations = %v, want %v", method, got, 1)
+		}
+	}
+}
+
+// observations returns the number of latencies recorded for the repository method.
+func observations(t *testing.T, backend, method string) uint64 {
+	t.Helper()
+
+	var m dto.Metric
+	if err := repoDuration.WithLabelValues(backend, method).(prometheus.Histogram).Write(&m); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestHandler(t *testing.T) {
+	if err := RegisterQueueDepth(func() int { return 7 }); err != nil {
+		t.Fatalf("RegisterQueueDepth() error = %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %v, want %v", w.Code, http.StatusOK)
+	}
+	for _, want := range []string{"shortener_deletion_queue_depth 7", `shortener_redirects_total{result="hit"}`} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("body does not contain %q", want)
+		}
+	}
+}
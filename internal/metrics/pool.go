@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolCollector collects the statistics of a pgx connection pool on every scrape.
+type poolCollector struct {
+	pool *pgxpool.Pool // Pool the statistics are read from
+
+	acquiredConns        *prometheus.Desc
+	idleConns            *prometheus.Desc
+	constructingConns    *prometheus.Desc
+	totalConns           *prometheus.Desc
+	maxConns             *prometheus.Desc
+	acquireCount         *prometheus.Desc
+	acquireDuration      *prometheus.Desc
+	emptyAcquireCount    *prometheus.Desc
+	canceledAcquireCount *prometheus.Desc
+	newConnsCount        *prometheus.Desc
+}
+
+// newPoolCollector creates a collector of the statistics of the pool.
+func newPoolCollector(pool *pgxpool.Pool) *poolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "pgxpool", name), help, nil, nil)
+	}
+
+	return &poolCollector{
+		pool: pool,
+
+		acquiredConns:        desc("acquired_conns", "Number of currently acquired connections."),
+		idleConns:            desc("idle_conns", "Number of currently idle connections."),
+		constructingConns:    desc("constructing_conns", "Number of connections being established."),
+		totalConns:           desc("total_conns", "Total number of connections in the pool."),
+		maxConns:             desc("max_conns", "Maximum size of the pool."),
+		acquireCount:         desc("acquire_total", "Number of successful connection acquisitions."),
+		acquireDuration:      desc("acquire_duration_seconds_total", "Total time spent acquiring connections."),
+		emptyAcquireCount:    desc("empty_acquire_total", "Number of acquisitions that waited for a connection."),
+		canceledAcquireCount: desc("canceled_acquire_total", "Number of acquisitions canceled by their context."),
+		newConnsCount:        desc("new_conns_total", "Number of connections opened."),
+	}
+}
+
+// Describe sends the descriptors of the pool metrics.
+func (p *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(p, ch)
+}
+
+// Collect sends the current statistics of the pool.
+func (p *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := p.pool.Stat()
+
+	gauge := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value)
+	}
+	counter := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value)
+	}
+
+	gauge(p.acquiredConns, float64(stat.AcquiredConns()))
+	gauge(p.idleConns, float64(stat.IdleConns()))
+	gauge(p.constructingConns, float64(stat.ConstructingConns()))
+	gauge(p.totalConns, float64(stat.TotalConns()))
+	gauge(p.maxConns, float64(stat.MaxConns()))
+	counter(p.acquireCount, float64(stat.AcquireCount()))
+	counter(p.acquireDuration, stat.AcquireDuration().Seconds())
+	counter(p.emptyAcquireCount, float64(stat.EmptyAcquireCount()))
+	counter(p.canceledAcquireCount, float64(stat.CanceledAcquireCount()))
+	counter(p.newConnsCount, float64(stat.NewConnsCount()))
+}
@@ -0,0 +1,251 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+	"github.com/Azzonya/go-shortener/internal/repo"
+	"github.com/Azzonya/go-shortener/internal/repo/inmemory"
+)
+
+// Repo decorates a repository, measuring the latency of every call by backend and method.
+type Repo struct {
+	next    repo.Repo // Repository the calls are passed to
+	backend string    // Name of the storage backend used as the metric label
+}
+
+// NewRepo returns the repository decorated with latency metrics labeled with the backend name.
+func NewRepo(next repo.Repo, backend string) *Repo {
+	return &Repo{
+		next:    next,
+		backend: backend,
+	}
+}
+
+// observe records the latency of the method call started at start.
+func (r *Repo) observe(method string, start time.Time) {
+	observeRepo(r.backend, method, start)
+}
+
+// Initialize initializes the repository.
+func (r *Repo) Initialize(ctx context.Context) error {
+	defer r.observe("Initialize", time.Now())
+	return r.next.Initialize(ctx)
+}
+
+// TableExist checks if the necessary tables exist in the database.
+func (r *Repo) TableExist(ctx context.Context) bool {
+	defer r.observe("TableExist", time.Now())
+	return r.next.TableExist(ctx)
+}
+
+// Add adds a new URL entry to the repository.
+func (r *Repo) Add(ctx context.Context, originalURL, shortURL, userID string, expiresAt *time.Time) error {
+	defer r.observe("Add", time.Now())
+	return r.next.Add(ctx, originalURL, shortURL, userID, expiresAt)
+}
+
+// CreateShortURLs creates multiple short URLs in the repository for the given user.
+func (r *Repo) CreateShortURLs(ctx context.Context, urls []*entities.ReqURL, userID string) error {
+	defer r.observe("CreateShortURLs", time.Now())
+	return r.next.CreateShortURLs(ctx, urls, userID)
+}
+
+// Update updates the short URL for the given original URL.
+func (r *Repo) Update(ctx context.Context, originalURL, shortURL string) error {
+	defer r.observe("Update", time.Now())
+	return r.next.Update(ctx, originalURL, shortURL)
+}
+
+// GetByShortURL retrieves the original URL associated with the given short URL.
+func (r *Repo) GetByShortURL(ctx context.Context, shortURL string) (string, bool) {
+	defer r.observe("GetByShortURL", time.Now())
+	return r.next.GetByShortURL(ctx, shortURL)
+}
+
+// GetLink returns the stored state of the short URL.
+func (r *Repo) GetLink(ctx context.Context, shortURL string) (*entities.Link, error) {
+	defer r.observe("GetLink", time.Now())
+	return r.next.GetLink(ctx, shortURL)
+}
+
+// GetByOriginalURL retrieves the short URL associated with the given original URL.
+func (r *Repo) GetByOriginalURL(ctx context.Context, originalURL string) (string, bool) {
+	defer r.observe("GetByOriginalURL", time.Now())
+	return r.next.GetByOriginalURL(ctx, originalURL)
+}
+
+// ListAll retrieves all short URLs associated with the given user.
+func (r *Repo) ListAll(ctx context.Context, userID string) ([]*entities.ReqListAll, error) {
+	defer r.observe("ListAll", time.Now())
+	return r.next.ListAll(ctx, userID)
+}
+
+// DeleteURLs deletes multiple URLs associated with the given user.
+func (r *Repo) DeleteURLs(ctx context.Context, urls []string, userID string) error {
+	defer r.observe("DeleteURLs", time.Now())
+	return r.next.DeleteURLs(ctx, urls, userID)
+}
+
+// DeleteURLsBatch deletes the URLs of several deletion requests at once.
+func (r *Repo) DeleteURLsBatch(ctx context.Context, requests []*entities.DeleteRequest) error {
+	defer r.observe("DeleteURLsBatch", time.Now())
+	return r.next.DeleteURLsBatch(ctx, requests)
+}
+
+// URLDeleted checks if the URL with the given short URL is deleted.
+func (r *Repo) URLDeleted(ctx context.Context, shortURL string) bool {
+	defer r.observe("URLDeleted", time.Now())
+	return r.next.URLDeleted(ctx, shortURL)
+}
+
+// Stats returns the total number of stored URLs and of distinct users owning them.
+func (r *Repo) Stats(ctx context.Context) (*entities.ServiceStats, error) {
+	defer r.observe("Stats", time.Now())
+	return r.next.Stats(ctx)
+}
+
+// AddClicks stores the click events.
+func (r *Repo) AddClicks(ctx context.Context, clicks []*entities.Click) error {
+	defer r.observe("AddClicks", time.Now())
+	return r.next.AddClicks(ctx, clicks)
+}
+
+// ClickStats returns the click statistics of the short URL owned by the user.
+func (r *Repo) ClickStats(ctx context.Context, shortURL, userID string, bucket time.Duration) (*entities.LinkStats, error) {
+	defer r.observe("ClickStats", time.Now())
+	return r.next.ClickStats(ctx, shortURL, userID, bucket)
+}
+
+// AddAPIKey stores a new API key.
+func (r *Repo) AddAPIKey(ctx context.Context, key *entities.APIKey) error {
+	defer r.observe("AddAPIKey", time.Now())
+	return r.next.AddAPIKey(ctx, key)
+}
+
+// GetAPIKey returns the API key with the given ID.
+func (r *Repo) GetAPIKey(ctx context.Context, id string) (*entities.APIKey, error) {
+	defer r.observe("GetAPIKey", time.Now())
+	return r.next.GetAPIKey(ctx, id)
+}
+
+// ListAPIKeys returns the API keys of the user.
+func (r *Repo) ListAPIKeys(ctx context.Context, userID string) ([]*entities.APIKey, error) {
+	defer r.observe("ListAPIKeys", time.Now())
+	return r.next.ListAPIKeys(ctx, userID)
+}
+
+// RevokeAPIKey revokes the user's API key.
+func (r *Repo) RevokeAPIKey(ctx context.Context, id, userID string, revokedAt time.Time) error {
+	defer r.observe("RevokeAPIKey", time.Now())
+	return r.next.RevokeAPIKey(ctx, id, userID, revokedAt)
+}
+
+// AddAccount stores a new user account.
+func (r *Repo) AddAccount(ctx context.Context, account *entities.Account) error {
+	defer r.observe("AddAccount", time.Now())
+	return r.next.AddAccount(ctx, account)
+}
+
+// GetAccount returns the user account with the given ID.
+func (r *Repo) GetAccount(ctx context.Context, id string) (*entities.Account, error) {
+	defer r.observe("GetAccount", time.Now())
+	return r.next.GetAccount(ctx, id)
+}
+
+// GetAccountByLogin returns the user account with the given login.
+func (r *Repo) GetAccountByLogin(ctx context.Context, login string) (*entities.Account, error) {
+	defer r.observe("GetAccountByLogin", time.Now())
+	return r.next.GetAccountByLogin(ctx, login)
+}
+
+// TransferURLs makes the user toUserID the owner of every URL of the user fromUserID.
+func (r *Repo) TransferURLs(ctx context.Context, fromUserID, toUserID string) (int64, error) {
+	defer r.observe("TransferURLs", time.Now())
+	return r.next.TransferURLs(ctx, fromUserID, toUserID)
+}
+
+// SearchLinks returns the links of every user matching the filter.
+func (r *Repo) SearchLinks(ctx context.Context, filter *entities.LinkFilter) ([]*entities.Link, error) {
+	defer r.observe("SearchLinks", time.Now())
+	return r.next.SearchLinks(ctx, filter)
+}
+
+// SetURLDisabled disables or enables again the short URL, whoever owns it.
+func (r *Repo) SetURLDisabled(ctx context.Context, shortURL string, disabled bool) error {
+	defer r.observe("SetURLDisabled", time.Now())
+	return r.next.SetURLDisabled(ctx, shortURL, disabled)
+}
+
+// ReassignURL makes the user the owner of the short URL.
+func (r *Repo) ReassignURL(ctx context.Context, shortURL, userID string) error {
+	defer r.observe("ReassignURL", time.Now())
+	return r.next.ReassignURL(ctx, shortURL, userID)
+}
+
+// BanUser bans the user.
+func (r *Repo) BanUser(ctx context.Context, ban *entities.Ban) error {
+	defer r.observe("BanUser", time.Now())
+	return r.next.BanUser(ctx, ban)
+}
+
+// UnbanUser lifts the ban of the user.
+func (r *Repo) UnbanUser(ctx context.Context, userID string) error {
+	defer r.observe("UnbanUser", time.Now())
+	return r.next.UnbanUser(ctx, userID)
+}
+
+// GetBan returns the ban of the user.
+func (r *Repo) GetBan(ctx context.Context, userID string) (*entities.Ban, error) {
+	defer r.observe("GetBan", time.Now())
+	return r.next.GetBan(ctx, userID)
+}
+
+// AddAuditEntry appends the entry to the audit trail.
+func (r *Repo) AddAuditEntry(ctx context.Context, entry *entities.AuditEntry) error {
+	defer r.observe("AddAuditEntry", time.Now())
+	return r.next.AddAuditEntry(ctx, entry)
+}
+
+// ListAuditEntries returns the most recent entries of the audit trail.
+func (r *Repo) ListAuditEntries(ctx context.Context, limit int) ([]*entities.AuditEntry, error) {
+	defer r.observe("ListAuditEntries", time.Now())
+	return r.next.ListAuditEntries(ctx, limit)
+}
+
+// NextSequence returns the next value of the sequence backing the sequential short code generators.
+func (r *Repo) NextSequence(ctx context.Context) (int64, error) {
+	defer r.observe("NextSequence", time.Now())
+	return r.next.NextSequence(ctx)
+}
+
+// URLExpired checks if the URL with the given short URL has expired.
+func (r *Repo) URLExpired(ctx context.Context, shortURL string) bool {
+	defer r.observe("URLExpired", time.Now())
+	return r.next.URLExpired(ctx, shortURL)
+}
+
+// DeleteExpired removes the URLs which expired before the given time and returns their number.
+func (r *Repo) DeleteExpired(ctx context.Context, before time.Time, archive bool) (int64, error) {
+	defer r.observe("DeleteExpired", time.Now())
+	return r.next.DeleteExpired(ctx, before, archive)
+}
+
+// WriteEvent writes an event to the storage.
+func (r *Repo) WriteEvent(event *inmemory.Event) error {
+	defer r.observe("WriteEvent", time.Now())
+	return r.next.WriteEvent(event)
+}
+
+// SyncData synchronizes data.
+func (r *Repo) SyncData() {
+	defer r.observe("SyncData", time.Now())
+	r.next.SyncData()
+}
+
+// Ping pings the database to check its availability.
+func (r *Repo) Ping(ctx context.Context) error {
+	defer r.observe("Ping", time.Now())
+	return r.next.Ping(ctx)
+}
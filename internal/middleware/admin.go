@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/Azzonya/go-shortener/internal/account"
+	"github.com/Azzonya/go-shortener/internal/admin"
+	"github.com/Azzonya/go-shortener/internal/logger"
+	"github.com/Azzonya/go-shortener/internal/session"
+)
+
+// RejectBanned is a Gin middleware rejecting with 403 Forbidden the requests of banned users,
+// whether authenticated by a session cookie or an API key, so it must run after Authenticate.
+// Each check is a lookup in the repository, so it is only set on the routes creating links or acting
+// on behalf of their owner, never on redirects. A nil moderator bans nobody.
+func RejectBanned(moderator *admin.Admin) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if moderator == nil {
+			return
+		}
+
+		u, ok := session.GetUserFromContext(c.Request.Context())
+		if !ok || u.IsNew() {
+			return
+		}
+
+		banned, err := moderator.Banned(c.Request.Context(), u.ID)
+		if err != nil {
+			logger.Log.Error("cannot check ban", zap.Error(err))
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		if banned {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"message": "User is banned",
+			})
+		}
+	}
+}
+
+// RequireAdmin is a Gin middleware rejecting with 403 Forbidden the requests of users
+// without the admin role in their session token. The role is confirmed against the accounts,
+// so that an account no longer configured as an administrator loses it before its token expires.
+// API keys never carry a role and are always rejected.
+func RequireAdmin(accounts *account.Accounts) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		u, ok := session.GetUserFromContext(c.Request.Context())
+		if !ok || !u.IsAdmin() || accounts == nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"message": "Admin role required",
+			})
+			return
+		}
+
+		isAdmin, err := accounts.IsAdmin(c.Request.Context(), u.ID)
+		if err != nil {
+			logger.Log.Error("cannot check admin role", zap.Error(err))
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		if !isAdmin {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"message": "Admin role required",
+			})
+		}
+	}
+}
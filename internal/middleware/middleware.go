@@ -2,6 +2,7 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -20,9 +21,27 @@ import (
 // a new user is created, a JWT cookie is set, and the user context is added to the request.
 // If any errors occur during these processes, an internal server error response is sent.
 func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
+	return Authenticate(auth.New(jwtSecret))
+}
+
+// Authenticate is AuthMiddleware checking the JWT cookies with the given authorizer,
+// so that its keys can be replaced while the server is running.
+// Cookies signed with a key other than the active one, or close to their expiry, are replaced by new cookies
+// signed with the active key, so that active users keep their identity.
+// Requests with an expired cookie get a new user too, but are flagged so that RequireSession rejects them.
+// Requests already authenticated, like by AuthenticateAPIKey, are left alone.
+func Authenticate(authorizer *auth.Auth) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authorizer := auth.New(jwtSecret)
-		u, err := authorizer.GetUserFromCookie(c)
+		if _, ok := session.GetUserFromContext(c.Request.Context()); ok {
+			return
+		}
+
+		ctx := c.Request.Context()
+
+		u, renew, err := authorizer.VerifyCookie(c)
+		if errors.Is(err, auth.ErrTokenExpired) {
+			ctx = session.SetExpiredContext(ctx)
+		}
 		if err != nil {
 			u, err = user.New()
 			if err != nil {
@@ -31,7 +50,10 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 				c.AbortWithStatus(http.StatusInternalServerError)
 				return
 			}
+			renew = true
+		}
 
+		if renew {
 			sessionCookie, errS := authorizer.CreateJWTCookie(u)
 			if errS != nil {
 				logger.Log.Debug("cannot create session cookie for user", zap.Error(errS))
@@ -41,6 +63,23 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 
 			c.Header("Set-Cookie", sessionCookie.String())
 		}
-		c.Request = c.Request.WithContext(session.SetUserContext(c.Request.Context(), u))
+		c.Request = c.Request.WithContext(session.SetUserContext(ctx, u))
+	}
+}
+
+// RequireSession is a Gin middleware rejecting with 401 Unauthorized the requests made with an expired
+// session cookie, for the routes acting on the links of the user. The cookie of the new user issued by
+// Authenticate is withdrawn, so that the client can log in again instead of silently getting a new identity.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !session.IsExpired(c.Request.Context()) {
+			return
+		}
+
+		c.Writer.Header().Del("Set-Cookie")
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "Session expired",
+			"error":   auth.ErrTokenExpired.Error(),
+		})
 	}
 }
@@ -1,11 +1,19 @@
 package middleware
 
 import (
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/Azzonya/go-shortener/internal/auth"
 	"github.com/Azzonya/go-shortener/internal/logger"
+	"github.com/Azzonya/go-shortener/internal/session"
+	"github.com/Azzonya/go-shortener/internal/user"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
 
@@ -90,3 +98,196 @@ func TestRequestLogger(t *testing.T) {
 		})
 	}
 }
+
+func TestTrustedSubnet(t *testing.T) {
+	_, subnet, err := net.ParseCIDR("192.168.1.0/24")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		subnet     *net.IPNet
+		realIP     string
+		remoteAddr string
+		wantStatus int
+	}{
+		{name: "real IP inside", subnet: subnet, realIP: "192.168.1.10", remoteAddr: "10.0.0.1:1234", wantStatus: http.StatusOK},
+		{name: "real IP outside", subnet: subnet, realIP: "192.168.2.10", remoteAddr: "192.168.1.1:1234", wantStatus: http.StatusForbidden},
+		{name: "socket address inside", subnet: subnet, remoteAddr: "192.168.1.20:1234", wantStatus: http.StatusOK},
+		{name: "socket address outside", subnet: subnet, remoteAddr: "10.0.0.1:1234", wantStatus: http.StatusForbidden},
+		{name: "invalid real IP", subnet: subnet, realIP: "invalid", remoteAddr: "192.168.1.1:1234", wantStatus: http.StatusForbidden},
+		{name: "subnet not configured", realIP: "192.168.1.10", wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/", TrustedSubnet(tt.subnet), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.RemoteAddr = tt.remoteAddr
+			if tt.realIP != "" {
+				request.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, request)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	now := time.Now()
+	limiter := NewRateLimiter(PerMinute(60, 2), time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		switch id := c.GetHeader("X-User"); id {
+		case "":
+		case "new":
+			u, err := user.New()
+			require.NoError(t, err)
+			c.Request = c.Request.WithContext(session.SetUserContext(c.Request.Context(), u))
+		default:
+			c.Request = c.Request.WithContext(session.SetUserContext(c.Request.Context(), user.NewWithID(id)))
+		}
+	})
+	r.GET("/", RateLimit(limiter), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	request := func(userID, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		if userID != "" {
+			req.Header.Set("X-User", userID)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, request("1", "10.0.0.1:1").Code)
+
+	w := request("1", "10.0.0.2:1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Reset"))
+
+	w = request("1", "10.0.0.3:1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "the bucket of the user is shared across addresses")
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, request("2", "10.0.0.1:1").Code, "other users have their own bucket")
+	assert.Equal(t, http.StatusOK, request("", "10.0.0.1:1").Code, "anonymous clients are limited by address")
+	assert.Equal(t, http.StatusOK, request("new", "10.0.0.1:1").Code)
+	assert.Equal(t, http.StatusTooManyRequests, request("new", "10.0.0.1:1").Code, "discarding the cookie does not reset the limit")
+
+	now = now.Add(time.Second)
+	assert.Equal(t, http.StatusOK, request("1", "10.0.0.1:1").Code, "a token is refilled every second")
+
+	now = now.Add(time.Minute)
+	request("3", "10.0.0.1:1")
+	assert.Equal(t, 1, limiter.Len(), "idle buckets are evicted")
+
+	limiter.SetLimit(PerMinute(0, 0))
+	for i := 0; i < 5; i++ {
+		w = request("3", "10.0.0.1:1")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("RateLimit-Limit"), "disabled limits set no headers")
+	}
+}
+
+func TestAuthenticate_RenewsRetiredKeyCookie(t *testing.T) {
+	authorizer := auth.New("oldsecret")
+	retired, err := authorizer.CreateJWTCookie(user.NewWithID("1"))
+	require.NoError(t, err)
+
+	keyring, err := auth.LoadKeyring("", "new:HS256:newsecret,"+auth.LegacyKeyID+":HS256:oldsecret", "")
+	require.NoError(t, err)
+	authorizer.SetKeyring(keyring)
+
+	current, err := authorizer.CreateJWTCookie(user.NewWithID("1"))
+	require.NoError(t, err)
+
+	r := gin.New()
+	r.Use(Authenticate(authorizer))
+	r.GET("/", func(c *gin.Context) {
+		u, err := session.GetUser(c.Request.Context())
+		require.NoError(t, err)
+		c.String(http.StatusOK, u.ID)
+	})
+
+	request := func(cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(cookie)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(retired)
+	assert.Equal(t, "1", w.Body.String())
+	if cookies := w.Result().Cookies(); assert.Len(t, cookies, 1) {
+		u, renew, err := authorizer.VerifyToken(cookies[0].Value)
+		require.NoError(t, err)
+		assert.Equal(t, "1", u.ID)
+		assert.False(t, renew, "renewed cookie must be signed with the active key")
+	}
+
+	w = request(current)
+	assert.Equal(t, "1", w.Body.String())
+	assert.Empty(t, w.Result().Cookies(), "cookie signed with the active key must be kept")
+}
+
+func TestRequireSession(t *testing.T) {
+	authorizer := auth.New("secret")
+
+	conf := auth.DefaultCookieConfig()
+	conf.TTL = -time.Minute
+	authorizer.SetCookieConfig(conf)
+	expired, err := authorizer.CreateJWTCookie(user.NewWithID("1"))
+	require.NoError(t, err)
+
+	authorizer.SetCookieConfig(auth.DefaultCookieConfig())
+	valid, err := authorizer.CreateJWTCookie(user.NewWithID("1"))
+	require.NoError(t, err)
+
+	r := gin.New()
+	r.Use(Authenticate(authorizer))
+	r.GET("/public", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/owner", RequireSession(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name       string
+		path       string
+		cookie     *http.Cookie
+		wantStatus int
+		wantCookie bool
+	}{
+		{name: "valid session", path: "/owner", cookie: valid, wantStatus: http.StatusOK},
+		{name: "expired session", path: "/owner", cookie: expired, wantStatus: http.StatusUnauthorized},
+		{name: "expired session on public route", path: "/public", cookie: expired, wantStatus: http.StatusOK, wantCookie: true},
+		{name: "no session", path: "/owner", wantStatus: http.StatusOK, wantCookie: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.cookie != nil {
+				req.AddCookie(tt.cookie)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantCookie, len(w.Result().Cookies()) > 0, "new user cookie")
+		})
+	}
+}
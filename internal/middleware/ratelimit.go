@@ -0,0 +1,205 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Azzonya/go-shortener/internal/session"
+)
+
+// Limit represents the rate of a token bucket.
+type Limit struct {
+	Rate  float64 // Rate is the number of tokens added per second, zero disables the limit.
+	Burst int     // Burst is the capacity of the bucket.
+}
+
+// PerMinute returns the limit allowing the given number of requests per minute
+// with bursts of up to burst requests. Zero requests disable the limit.
+func PerMinute(requests, burst int) Limit {
+	return Limit{
+		Rate:  float64(requests) / 60,
+		Burst: max(burst, 1),
+	}
+}
+
+// RateLimiter limits the rate of requests of every client with a token bucket per client.
+// Buckets of clients idle for longer than the idle timeout are evicted.
+// It is safe for concurrent use.
+type RateLimiter struct {
+	mu        sync.Mutex
+	limit     Limit              // Rate and capacity of every bucket
+	idle      time.Duration      // Time after which the bucket of an idle client is evicted
+	buckets   map[string]*bucket // Buckets keyed by client
+	lastSweep time.Time          // Last time idle buckets were evicted
+	now       func() time.Time   // Current time, replaced in tests
+}
+
+// bucket represents the tokens left to a client.
+type bucket struct {
+	tokens float64   // Tokens available at the time of the last update
+	last   time.Time // Time of the last update
+}
+
+// decision represents the outcome of a rate limited request.
+type decision struct {
+	allowed    bool          // Whether the request is allowed
+	limit      int           // Capacity of the bucket
+	remaining  int           // Whole tokens left after the request
+	reset      time.Duration // Time until the bucket is full again
+	retryAfter time.Duration // Time until a rejected request can be retried
+}
+
+// NewRateLimiter creates a new rate limiter evicting buckets idle for longer than idle.
+func NewRateLimiter(limit Limit, idle time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:   limit,
+		idle:    idle,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// SetLimit replaces the limit of every client. Existing buckets keep their tokens up to the new capacity.
+func (l *RateLimiter) SetLimit(limit Limit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limit = limit
+}
+
+// Len returns the number of clients tracked by the limiter.
+func (l *RateLimiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.buckets)
+}
+
+// Allow takes a token from the bucket of the client identified by the key, see ClientKey.
+// It returns whether the request is allowed and, if not, the time until it can be retried.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	d := l.allow(key)
+	return d.allowed, d.retryAfter
+}
+
+// allow takes a token from the bucket of the client if one is available.
+func (l *RateLimiter) allow(key string) decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	limit := l.limit
+	if limit.Rate <= 0 {
+		return decision{allowed: true}
+	}
+	capacity := float64(limit.Burst)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+
+	d := decision{limit: limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		d.allowed = true
+	} else {
+		d.retryAfter = seconds((1 - b.tokens) / limit.Rate)
+	}
+
+	d.remaining = int(b.tokens)
+	d.reset = seconds((capacity - b.tokens) / limit.Rate)
+
+	return d
+}
+
+// sweep evicts the buckets idle for longer than the idle timeout, at most once per idle timeout.
+func (l *RateLimiter) sweep(now time.Time) {
+	if l.idle <= 0 || now.Sub(l.lastSweep) < l.idle {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= l.idle {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// seconds converts a number of seconds to a duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// RateLimit is a Gin middleware limiting the rate of requests with the limiter.
+// Clients are identified by the user ID of their session, or by their IP address
+// if the request has no valid session. The RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers are set on every response, rejected requests are answered
+// with 429 Too Many Requests and a Retry-After header. A nil limiter allows every request.
+// It must run after Authenticate.
+func RateLimit(limiter *RateLimiter) gin.HandlerFunc {
+	return rateLimit(limiter, clientKey)
+}
+
+// RateLimitByAddress is a Gin middleware limiting the rate of requests with the limiter like RateLimit,
+// but identifying every client by its IP address, so that switching sessions does not reset the limit.
+func RateLimitByAddress(limiter *RateLimiter) gin.HandlerFunc {
+	return rateLimit(limiter, func(c *gin.Context) string {
+		return "ip:" + c.ClientIP()
+	})
+}
+
+// rateLimit returns the middleware limiting the rate of requests of the clients identified by key.
+func rateLimit(limiter *RateLimiter, key func(c *gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limiter == nil {
+			return
+		}
+
+		d := limiter.allow(key(c))
+		if d.limit == 0 {
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(d.limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(d.remaining))
+		c.Header("RateLimit-Reset", ceilSeconds(d.reset))
+
+		if !d.allowed {
+			c.Header("Retry-After", ceilSeconds(d.retryAfter))
+			c.AbortWithStatus(http.StatusTooManyRequests)
+		}
+	}
+}
+
+// clientKey identifies the client of the request by its user ID, or by its IP address for new users.
+func clientKey(c *gin.Context) string {
+	return ClientKey(c.Request.Context(), c.ClientIP())
+}
+
+// ClientKey identifies the client by the user ID of the context, or by its IP address for new users,
+// so that the clients of the REST and gRPC APIs share their buckets.
+func ClientKey(ctx context.Context, ip string) string {
+	if u, err := session.GetUser(ctx); err == nil {
+		return "user:" + u.ID
+	}
+	return "ip:" + ip
+}
+
+// ceilSeconds formats the duration as a whole number of seconds rounded up.
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
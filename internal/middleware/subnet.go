@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// realIPHeader is the header carrying the client IP address set by the reverse proxy.
+const realIPHeader = "X-Real-IP"
+
+// TrustedSubnet returns a Gin middleware function that only lets through the requests
+// of clients whose IP address is inside the subnet, other requests get 403 Forbidden.
+// The client address is taken from the X-Real-IP header if present, otherwise from the connection.
+// A nil subnet forbids every request.
+func TrustedSubnet(subnet *net.IPNet) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip := clientIP(c.Request)
+		if subnet == nil || ip == nil || !subnet.Contains(ip) {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// clientIP returns the client IP address of the request, nil if it cannot be parsed.
+func clientIP(r *http.Request) net.IP {
+	if realIP := r.Header.Get(realIPHeader); realIP != "" {
+		return net.ParseIP(realIP)
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return net.ParseIP(host)
+}
@@ -0,0 +1,287 @@
+// Package cache provides a read-through LRU cache of redirect lookups decorating a repository.
+//
+// The cache keeps the link records of the recently resolved short URLs, so that
+// a redirect is answered without a round-trip to the storage. Unknown short URLs are cached too, for a shorter time.
+// Entries are dropped when the repository changes the short URLs through the decorator.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+	"github.com/Azzonya/go-shortener/internal/repo"
+)
+
+// Repo decorates a repository with a bounded cache of the short URL lookups.
+// The methods not overridden are passed to the repository unchanged.
+type Repo struct {
+	repo.Repo
+
+	size        int              // Maximum number of cached short URLs
+	ttl         time.Duration    // Lifetime of the entries of existing short URLs
+	negativeTTL time.Duration    // Lifetime of the entries of unknown short URLs
+	now         func() time.Time // Current time, replaced in tests
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // Cached entries keyed by short URL
+	lru     *list.List               // Entries ordered from the most to the least recently used
+	gen     uint64                   // Incremented on every invalidation, so that stale loads are not cached
+
+	hits   atomic.Uint64 // Number of lookups answered from the cache
+	misses atomic.Uint64 // Number of lookups passed to the repository
+}
+
+// entry represents the cached state of a short URL.
+type entry struct {
+	shortURL  string         // Short URL the entry is keyed by
+	link      *entities.Link // Stored link, nil for unknown short URLs
+	expiresAt time.Time      // Time the entry becomes stale
+}
+
+// Stats represents the usage counters of the cache.
+type Stats struct {
+	Hits   uint64 // Hits is the number of lookups answered from the cache.
+	Misses uint64 // Misses is the number of lookups passed to the repository.
+	Size   int    // Size is the number of cached short URLs.
+}
+
+// New returns the repository decorated with a cache of up to size short URLs.
+// Entries of existing short URLs live for ttl, entries of unknown ones for negativeTTL,
+// a zero negativeTTL disables caching unknown short URLs. Changes made to the storage
+// bypassing the decorator are seen once the entry becomes stale.
+func New(next repo.Repo, size int, ttl, negativeTTL time.Duration) *Repo {
+	return &Repo{
+		Repo:        next,
+		size:        max(size, 1),
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		now:         time.Now,
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+	}
+}
+
+// GetLink returns the stored state of the short URL, loading it into the cache if needed.
+func (r *Repo) GetLink(ctx context.Context, shortURL string) (*entities.Link, error) {
+	e, err := r.lookup(ctx, shortURL)
+	if err != nil {
+		return nil, err
+	}
+	if e.link == nil {
+		return nil, entities.ErrURLNotFound
+	}
+
+	link := *e.link
+
+	return &link, nil
+}
+
+// GetByShortURL returns the original URL of the short URL, loading it into the cache if needed.
+// Deleted and expired short URLs are still returned, like the repository does.
+func (r *Repo) GetByShortURL(ctx context.Context, shortURL string) (string, bool) {
+	link, err := r.GetLink(ctx, shortURL)
+	if err != nil {
+		return "", false
+	}
+	return link.OriginalURL, true
+}
+
+// URLDeleted checks if the short URL is deleted, loading it into the cache if needed.
+func (r *Repo) URLDeleted(ctx context.Context, shortURL string) bool {
+	link, err := r.GetLink(ctx, shortURL)
+	return err == nil && link.Deleted
+}
+
+// URLExpired checks if the short URL has expired, loading it into the cache if needed.
+func (r *Repo) URLExpired(ctx context.Context, shortURL string) bool {
+	link, err := r.GetLink(ctx, shortURL)
+	return err == nil && link.Expired(r.now())
+}
+
+// Add adds a new URL entry to the repository and drops the cached state of its short URL.
+func (r *Repo) Add(ctx context.Context, originalURL, shortURL, userID string, expiresAt *time.Time) error {
+	defer r.Invalidate(shortURL)
+	return r.Repo.Add(ctx, originalURL, shortURL, userID, expiresAt)
+}
+
+// CreateShortURLs creates multiple short URLs and drops their cached state.
+func (r *Repo) CreateShortURLs(ctx context.Context, urls []*entities.ReqURL, userID string) error {
+	defer func() {
+		for _, u := range urls {
+			r.Invalidate(u.ShortURL)
+		}
+	}()
+	return r.Repo.CreateShortURLs(ctx, urls, userID)
+}
+
+// Update updates the short URL of the original URL and drops the cached state of the old and new short URLs.
+func (r *Repo) Update(ctx context.Context, originalURL, shortURL string) error {
+	if old, ok := r.Repo.GetByOriginalURL(ctx, originalURL); ok {
+		defer r.Invalidate(old)
+	}
+	defer r.Invalidate(shortURL)
+
+	return r.Repo.Update(ctx, originalURL, shortURL)
+}
+
+// DeleteURLs deletes the user's URLs and drops their cached state.
+func (r *Repo) DeleteURLs(ctx context.Context, urls []string, userID string) error {
+	defer r.Invalidate(urls...)
+	return r.Repo.DeleteURLs(ctx, urls, userID)
+}
+
+// DeleteURLsBatch deletes the URLs of the deletion requests and drops their cached state.
+func (r *Repo) DeleteURLsBatch(ctx context.Context, requests []*entities.DeleteRequest) error {
+	defer func() {
+		for _, req := range requests {
+			r.Invalidate(req.ShortURLs...)
+		}
+	}()
+	return r.Repo.DeleteURLsBatch(ctx, requests)
+}
+
+// DeleteExpired removes the expired URLs and empties the cache.
+func (r *Repo) DeleteExpired(ctx context.Context, before time.Time, archive bool) (int64, error) {
+	defer r.Purge()
+	return r.Repo.DeleteExpired(ctx, before, archive)
+}
+
+// TransferURLs transfers the URLs of the user to another user and empties the cache.
+func (r *Repo) TransferURLs(ctx context.Context, fromUserID, toUserID string) (int64, error) {
+	defer r.Purge()
+	return r.Repo.TransferURLs(ctx, fromUserID, toUserID)
+}
+
+// SetURLDisabled disables or enables again the short URL and drops its cached state.
+func (r *Repo) SetURLDisabled(ctx context.Context, shortURL string, disabled bool) error {
+	defer r.Invalidate(shortURL)
+	return r.Repo.SetURLDisabled(ctx, shortURL, disabled)
+}
+
+// ReassignURL makes the user the owner of the short URL and drops its cached state.
+func (r *Repo) ReassignURL(ctx context.Context, shortURL, userID string) error {
+	defer r.Invalidate(shortURL)
+	return r.Repo.ReassignURL(ctx, shortURL, userID)
+}
+
+// Invalidate drops the cached state of the short URLs.
+func (r *Repo) Invalidate(shortURLs ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.gen++
+	for _, shortURL := range shortURLs {
+		if el, ok := r.entries[shortURL]; ok {
+			r.remove(el)
+		}
+	}
+}
+
+// Purge empties the cache.
+func (r *Repo) Purge() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.gen++
+	clear(r.entries)
+	r.lru.Init()
+}
+
+// CacheStats returns the usage counters of the cache.
+func (r *Repo) CacheStats() Stats {
+	r.mu.Lock()
+	size := r.lru.Len()
+	r.mu.Unlock()
+
+	return Stats{
+		Hits:   r.hits.Load(),
+		Misses: r.misses.Load(),
+		Size:   size,
+	}
+}
+
+// lookup returns the cached state of the short URL, loading it from the repository on a miss.
+// The loaded state is not cached if the cache was invalidated meanwhile.
+func (r *Repo) lookup(ctx context.Context, shortURL string) (*entry, error) {
+	e, gen, ok := r.get(shortURL)
+	if ok {
+		r.hits.Add(1)
+		return e, nil
+	}
+	r.misses.Add(1)
+
+	link, err := r.Repo.GetLink(ctx, shortURL)
+	if err != nil && !errors.Is(err, entities.ErrURLNotFound) {
+		return nil, err
+	}
+
+	e = &entry{shortURL: shortURL, link: link}
+	r.put(e, gen)
+
+	return e, nil
+}
+
+// get returns the fresh cached entry of the short URL and marks it as recently used.
+// It also returns the current generation of the cache.
+func (r *Repo) get(shortURL string) (*entry, uint64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	el, ok := r.entries[shortURL]
+	if !ok {
+		return nil, r.gen, false
+	}
+
+	e := el.Value.(*entry)
+	if !r.now().Before(e.expiresAt) {
+		r.remove(el)
+		return nil, r.gen, false
+	}
+
+	r.lru.MoveToFront(el)
+
+	return e, r.gen, true
+}
+
+// put caches the entry loaded at the given generation, evicting the least recently used one if the cache is full.
+// Entries loaded before the last invalidation are discarded.
+func (r *Repo) put(e *entry, gen uint64) {
+	ttl := r.ttl
+	if e.link == nil {
+		ttl = r.negativeTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+	e.expiresAt = r.now().Add(ttl)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if gen != r.gen {
+		return
+	}
+
+	if el, ok := r.entries[e.shortURL]; ok {
+		el.Value = e
+		r.lru.MoveToFront(el)
+		return
+	}
+
+	r.entries[e.shortURL] = r.lru.PushFront(e)
+
+	for r.lru.Len() > r.size {
+		r.remove(r.lru.Back())
+	}
+}
+
+// remove drops the list element and its entry from the cache.
+func (r *Repo) remove(el *list.Element) {
+	r.lru.Remove(el)
+	delete(r.entries, el.Value.(*entry).shortURL)
+}
@@ -0,0 +1,183 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+	"github.com/Azzonya/go-shortener/internal/repo/inmemory"
+)
+
+// countingRepo counts the short URL lookups reaching the repository.
+type countingRepo struct {
+	*inmemory.St
+
+	lookups int
+}
+
+func (c *countingRepo) GetLink(ctx context.Context, shortURL string) (*entities.Link, error) {
+	c.lookups++
+	return c.St.GetLink(ctx, shortURL)
+}
+
+// newTestCache returns a cache of the given size over an in-memory repository holding the short URL "tst".
+func newTestCache(t *testing.T, size int) (*Repo, *countingRepo, *time.Time) {
+	t.Helper()
+
+	storage, err := inmemory.New("")
+	if err != nil {
+		t.Fatalf("inmemory.New() error = %v", err)
+	}
+	if err = storage.Add(context.Background(), "example.com", "tst", "1", nil); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	next := &countingRepo{St: storage}
+
+	now := time.Now()
+	c := New(next, size, time.Minute, time.Second)
+	c.now = func() time.Time { return now }
+
+	return c, next, &now
+}
+
+func TestRepo_ReadThrough(t *testing.T) {
+	c, next, now := newTestCache(t, 10)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if got, ok := c.GetByShortURL(ctx, "tst"); !ok || got != "example.com" {
+			t.Fatalf("GetByShortURL() = %v, %v, want %v, true", got, ok, "example.com")
+		}
+		if c.URLDeleted(ctx, "tst") {
+			t.Fatalf("URLDeleted() = true, want false")
+		}
+	}
+
+	if next.lookups != 1 {
+		t.Errorf("repository lookups = %v, want %v", next.lookups, 1)
+	}
+	if st := c.CacheStats(); st.Hits != 5 || st.Misses != 1 || st.Size != 1 {
+		t.Errorf("CacheStats() = %+v, want 5 hits, 1 miss and 1 entry", st)
+	}
+
+	*now = now.Add(time.Minute)
+	c.GetByShortURL(ctx, "tst")
+
+	if next.lookups != 2 {
+		t.Errorf("repository lookups after the TTL = %v, want %v", next.lookups, 2)
+	}
+}
+
+func TestRepo_Expiry(t *testing.T) {
+	c, next, now := newTestCache(t, 10)
+	ctx := context.Background()
+
+	expiresAt := now.Add(time.Second)
+	if err := c.Add(ctx, "example.org", "exp", "1", &expiresAt); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	if c.URLExpired(ctx, "exp") {
+		t.Fatalf("URLExpired() = true, want false")
+	}
+
+	*now = now.Add(time.Second)
+
+	if !c.URLExpired(ctx, "exp") {
+		t.Errorf("URLExpired() after the expiry = false, want true")
+	}
+	if next.lookups != 1 {
+		t.Errorf("repository lookups = %v, want %v", next.lookups, 1)
+	}
+}
+
+func TestRepo_NegativeCaching(t *testing.T) {
+	c, next, now := newTestCache(t, 10)
+	ctx := context.Background()
+
+	c.GetByShortURL(ctx, "unknown")
+	if _, ok := c.GetByShortURL(ctx, "unknown"); ok {
+		t.Fatalf("GetByShortURL() of an unknown short URL exists")
+	}
+	if next.lookups != 1 {
+		t.Errorf("repository lookups = %v, want %v", next.lookups, 1)
+	}
+
+	*now = now.Add(time.Second)
+	c.GetByShortURL(ctx, "unknown")
+
+	if next.lookups != 2 {
+		t.Errorf("repository lookups after the negative TTL = %v, want %v", next.lookups, 2)
+	}
+
+	if err := c.Add(ctx, "example.org", "unknown", "1", nil); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if got, ok := c.GetByShortURL(ctx, "unknown"); !ok || got != "example.org" {
+		t.Errorf("GetByShortURL() after Add() = %v, %v, want %v, true", got, ok, "example.org")
+	}
+}
+
+func TestRepo_Invalidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		delete func(c *Repo) error
+	}{
+		{
+			name: "DeleteURLs",
+			delete: func(c *Repo) error {
+				return c.DeleteURLs(context.Background(), []string{"tst"}, "1")
+			},
+		},
+		{
+			name: "DeleteURLsBatch",
+			delete: func(c *Repo) error {
+				return c.DeleteURLsBatch(context.Background(), []*entities.DeleteRequest{{UserID: "1", ShortURLs: []string{"tst"}}})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _, _ := newTestCache(t, 10)
+
+			if c.URLDeleted(context.Background(), "tst") {
+				t.Fatalf("URLDeleted() = true, want false")
+			}
+
+			if err := tt.delete(c); err != nil {
+				t.Fatalf("delete error = %v", err)
+			}
+
+			if !c.URLDeleted(context.Background(), "tst") {
+				t.Errorf("URLDeleted() after deletion = false, want true")
+			}
+		})
+	}
+}
+
+func TestRepo_Eviction(t *testing.T) {
+	c, next, _ := newTestCache(t, 2)
+	ctx := context.Background()
+
+	c.GetByShortURL(ctx, "tst")
+	c.GetByShortURL(ctx, "a")
+	c.GetByShortURL(ctx, "tst")
+	c.GetByShortURL(ctx, "b")
+
+	if st := c.CacheStats(); st.Size != 2 {
+		t.Errorf("CacheStats().Size = %v, want %v", st.Size, 2)
+	}
+
+	lookups := next.lookups
+	c.GetByShortURL(ctx, "tst")
+	if next.lookups != lookups {
+		t.Errorf("recently used short URL was evicted")
+	}
+
+	c.GetByShortURL(ctx, "a")
+	if next.lookups != lookups+1 {
+		t.Errorf("least recently used short URL was not evicted")
+	}
+}
@@ -0,0 +1,178 @@
+package inmemory
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+)
+
+// accountsSuffix is appended to the log file path to get the path of the accounts file.
+const accountsSuffix = ".users"
+
+// accountStore keeps the user accounts by ID and by login.
+// Every change of an account appends its whole state to the accounts file, the last record of an account wins on restore.
+type accountStore struct {
+	mu      sync.RWMutex
+	byID    map[string]*entities.Account // Accounts keyed by ID.
+	byLogin map[string]*entities.Account // Accounts keyed by login.
+	file    *os.File                     // Accounts file opened for appending.
+}
+
+// accountRecord represents the state of a user account in the accounts file.
+// Unlike entities.Account, it keeps the password hash when encoded.
+type accountRecord struct {
+	ID           string    `json:"id"`
+	Login        string    `json:"login"`
+	PasswordHash string    `json:"password_hash"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// loadAccounts restores the user accounts from the accounts file.
+// Lines that can not be decoded, like a torn final line, are skipped.
+func (s *St) loadAccounts() error {
+	as := &s.accounts
+
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	as.byID = make(map[string]*entities.Account)
+	as.byLogin = make(map[string]*entities.Account)
+
+	if s.filePath == "" {
+		return nil
+	}
+
+	file, err := os.Open(s.filePath + accountsSuffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record accountRecord
+		if err = json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			log.Printf("skipping malformed account record in %s: %v", file.Name(), err)
+			continue
+		}
+
+		as.put(&entities.Account{
+			ID:           record.ID,
+			Login:        record.Login,
+			PasswordHash: record.PasswordHash,
+			CreatedAt:    record.CreatedAt,
+		})
+	}
+
+	return scanner.Err()
+}
+
+// AddAccount stores a new user account.
+// It returns entities.ErrLoginExists if the login is already taken.
+func (s *St) AddAccount(_ context.Context, account *entities.Account) error {
+	as := &s.accounts
+
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if _, exist := as.byLogin[account.Login]; exist {
+		return entities.ErrLoginExists
+	}
+
+	stored := *account
+	if err := as.save(s.filePath, &stored); err != nil {
+		return err
+	}
+	as.put(&stored)
+
+	return nil
+}
+
+// GetAccount returns the user account with the given ID.
+func (s *St) GetAccount(_ context.Context, id string) (*entities.Account, error) {
+	as := &s.accounts
+
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	account, exist := as.byID[id]
+	if !exist {
+		return nil, entities.ErrAccountNotFound
+	}
+
+	c := *account
+
+	return &c, nil
+}
+
+// GetAccountByLogin returns the user account with the given login.
+func (s *St) GetAccountByLogin(_ context.Context, login string) (*entities.Account, error) {
+	as := &s.accounts
+
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	account, exist := as.byLogin[login]
+	if !exist {
+		return nil, entities.ErrAccountNotFound
+	}
+
+	c := *account
+
+	return &c, nil
+}
+
+// put indexes the account, replacing its previous state.
+// It must be called with mu held.
+func (as *accountStore) put(account *entities.Account) {
+	if old, exist := as.byID[account.ID]; exist {
+		delete(as.byLogin, old.Login)
+	}
+
+	as.byID[account.ID] = account
+	as.byLogin[account.Login] = account
+}
+
+// save durably appends the state of the account to the accounts file, if the storage has one.
+// It must be called with mu held.
+func (as *accountStore) save(filePath string, account *entities.Account) error {
+	if filePath == "" {
+		return nil
+	}
+
+	if as.file == nil {
+		file, err := os.OpenFile(filePath+accountsSuffix, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return fmt.Errorf("write accounts file: %w", err)
+		}
+		as.file = file
+	}
+
+	data, err := json.Marshal(accountRecord{
+		ID:           account.ID,
+		Login:        account.Login,
+		PasswordHash: account.PasswordHash,
+		CreatedAt:    account.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err = as.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write accounts file: %w", err)
+	}
+
+	return as.file.Sync()
+}
@@ -0,0 +1,77 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+)
+
+func TestSt_Accounts(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "short-url-repo.json")
+	ctx := context.Background()
+
+	s, err := New(filePath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	account := &entities.Account{ID: "acc", Login: "alice", PasswordHash: "hash", CreatedAt: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)}
+	if err = s.AddAccount(ctx, account); err != nil {
+		t.Fatalf("AddAccount() error = %v", err)
+	}
+	if err = s.AddAccount(ctx, &entities.Account{ID: "other", Login: "alice"}); !errors.Is(err, entities.ErrLoginExists) {
+		t.Errorf("AddAccount() of a taken login error = %v, want %v", err, entities.ErrLoginExists)
+	}
+
+	for _, shortURL := range []string{"a1", "a2"} {
+		if err = s.Add(ctx, "https://example.com/"+shortURL, shortURL, "anonymous", nil); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	if err = s.Add(ctx, "https://example.com/b1", "b1", "bob", nil); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	n, err := s.TransferURLs(ctx, "anonymous", "acc")
+	if err != nil {
+		t.Fatalf("TransferURLs() error = %v", err)
+	}
+	if n != 2 {
+		t.Errorf("TransferURLs() = %v, want %v", n, 2)
+	}
+
+	restored, err := New(filePath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for name, storage := range map[string]*St{"live": s, "restored": restored} {
+		got, err := storage.GetAccountByLogin(ctx, "alice")
+		if err != nil {
+			t.Fatalf("%s GetAccountByLogin() error = %v", name, err)
+		}
+		if !reflect.DeepEqual(got, account) {
+			t.Errorf("%s GetAccountByLogin() got = %+v, want %+v", name, got, account)
+		}
+
+		if _, err = storage.GetAccount(ctx, "missing"); !errors.Is(err, entities.ErrAccountNotFound) {
+			t.Errorf("%s GetAccount() of a missing account error = %v, want %v", name, err, entities.ErrAccountNotFound)
+		}
+
+		urls, err := storage.ListAll(ctx, "acc")
+		if err != nil {
+			t.Fatalf("%s ListAll() error = %v", name, err)
+		}
+		if len(urls) != 2 {
+			t.Errorf("%s ListAll() of the account = %v, want 2 URLs", name, len(urls))
+		}
+		if urls, _ = storage.ListAll(ctx, "bob"); len(urls) != 1 {
+			t.Errorf("%s ListAll() of another user = %v, want 1 URL", name, len(urls))
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package inmemory
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+)
+
+const (
+	bansSuffix  = ".bans"  // bansSuffix is appended to the log file path to get the path of the bans file.
+	auditSuffix = ".audit" // auditSuffix is appended to the log file path to get the path of the audit trail file.
+)
+
+// banStore keeps the banned users by ID.
+// Every ban and lifted ban appends a record to the bans file, the last record of a user wins on restore.
+type banStore struct {
+	mu   sync.RWMutex
+	bans map[string]*entities.Ban // Bans keyed by user ID.
+	file *os.File                 // Bans file opened for appending.
+}
+
+// banRecord represents a ban, or a lifted ban, in the bans file.
+type banRecord struct {
+	UserID    string    `json:"user_id"`
+	Reason    string    `json:"reason,omitempty"`
+	BannedBy  string    `json:"banned_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Lifted    bool      `json:"lifted,omitempty"`
+}
+
+// auditStore keeps the audit trail in the order the entries were added.
+// The audit trail file is only ever appended to.
+type auditStore struct {
+	mu      sync.RWMutex
+	entries []*entities.AuditEntry // Entries ordered by ID.
+	file    *os.File               // Audit trail file opened for appending.
+}
+
+// loadBans restores the banned users from the bans file.
+// Lines that can not be decoded, like a torn final line, are skipped.
+func (s *St) loadBans() error {
+	bs := &s.bans
+
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	bs.bans = make(map[string]*entities.Ban)
+
+	return readRecords(s.filePath, bansSuffix, func(record *banRecord) {
+		if record.Lifted {
+			delete(bs.bans, record.UserID)
+			return
+		}
+
+		bs.bans[record.UserID] = &entities.Ban{
+			UserID:    record.UserID,
+			Reason:    record.Reason,
+			BannedBy:  record.BannedBy,
+			CreatedAt: record.CreatedAt,
+		}
+	})
+}
+
+// loadAudit restores the audit trail from the audit trail file.
+// Lines that can not be decoded, like a torn final line, are skipped.
+func (s *St) loadAudit() error {
+	as := &s.audit
+
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	as.entries = nil
+
+	return readRecords(s.filePath, auditSuffix, func(entry *entities.AuditEntry) {
+		as.entries = append(as.entries, entry)
+	})
+}
+
+// BanUser bans the user, replacing the previous ban of the user.
+func (s *St) BanUser(_ context.Context, ban *entities.Ban) error {
+	bs := &s.bans
+
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	record := banRecord{
+		UserID:    ban.UserID,
+		Reason:    ban.Reason,
+		BannedBy:  ban.BannedBy,
+		CreatedAt: ban.CreatedAt,
+	}
+	if err := appendRecord(&bs.file, s.filePath, bansSuffix, record); err != nil {
+		return fmt.Errorf("write bans file: %w", err)
+	}
+
+	stored := *ban
+	bs.bans[ban.UserID] = &stored
+
+	return nil
+}
+
+// UnbanUser lifts the ban of the user.
+func (s *St) UnbanUser(_ context.Context, userID string) error {
+	bs := &s.bans
+
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	if _, exist := bs.bans[userID]; !exist {
+		return entities.ErrBanNotFound
+	}
+
+	record := banRecord{UserID: userID, CreatedAt: time.Now().UTC(), Lifted: true}
+	if err := appendRecord(&bs.file, s.filePath, bansSuffix, record); err != nil {
+		return fmt.Errorf("write bans file: %w", err)
+	}
+
+	delete(bs.bans, userID)
+
+	return nil
+}
+
+// GetBan returns the ban of the user.
+func (s *St) GetBan(_ context.Context, userID string) (*entities.Ban, error) {
+	bs := &s.bans
+
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+
+	ban, exist := bs.bans[userID]
+	if !exist {
+		return nil, entities.ErrBanNotFound
+	}
+
+	c := *ban
+
+	return &c, nil
+}
+
+// AddAuditEntry appends the entry to the audit trail, setting its ID.
+func (s *St) AddAuditEntry(_ context.Context, entry *entities.AuditEntry) error {
+	as := &s.audit
+
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	stored := *entry
+	stored.ID = 1
+	if len(as.entries) > 0 {
+		stored.ID = as.entries[len(as.entries)-1].ID + 1
+	}
+
+	if err := appendRecord(&as.file, s.filePath, auditSuffix, &stored); err != nil {
+		return fmt.Errorf("write audit trail file: %w", err)
+	}
+
+	as.entries = append(as.entries, &stored)
+	entry.ID = stored.ID
+
+	return nil
+}
+
+// ListAuditEntries returns up to limit entries of the audit trail, the most recent first.
+func (s *St) ListAuditEntries(_ context.Context, limit int) ([]*entities.AuditEntry, error) {
+	as := &s.audit
+
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	entries := make([]*entities.AuditEntry, 0, min(limit, len(as.entries)))
+	for i := len(as.entries) - 1; i >= 0 && len(entries) < limit; i-- {
+		c := *as.entries[i]
+		entries = append(entries, &c)
+	}
+
+	return entries, nil
+}
+
+// readRecords calls fn with every record of the file next to the log file, in the order they were written.
+// A missing file has no records, lines that can not be decoded are skipped.
+func readRecords[T any](filePath, suffix string, fn func(record *T)) error {
+	if filePath == "" {
+		return nil
+	}
+
+	file, err := os.Open(filePath + suffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record T
+		if err = json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			log.Printf("skipping malformed record in %s: %v", file.Name(), err)
+			continue
+		}
+		fn(&record)
+	}
+
+	return scanner.Err()
+}
+
+// appendRecord durably appends the record to the file next to the log file, if the storage has one.
+// The file is opened on the first record and kept open in *file.
+func appendRecord(file **os.File, filePath, suffix string, record any) error {
+	if filePath == "" {
+		return nil
+	}
+
+	if *file == nil {
+		f, err := os.OpenFile(filePath+suffix, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return err
+		}
+		*file = f
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	if _, err = (*file).Write(append(data, '\n')); err != nil {
+		return err
+	}
+
+	return (*file).Sync()
+}
@@ -0,0 +1,116 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+)
+
+func TestSt_Moderation(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "short-url-repo.json")
+	ctx := context.Background()
+
+	s, err := New(filePath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for _, link := range []struct{ shortURL, originalURL, userID string }{
+		{"spam1", "https://spam.example/1", "spammer"},
+		{"spam2", "https://spam.example/2", "spammer"},
+		{"good", "https://good.example", "alice"},
+	} {
+		if err = s.Add(ctx, link.originalURL, link.shortURL, link.userID, nil); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	if err = s.SetURLDisabled(ctx, "spam1", true); err != nil {
+		t.Fatalf("SetURLDisabled() error = %v", err)
+	}
+	if err = s.ReassignURL(ctx, "spam2", "moderators"); err != nil {
+		t.Fatalf("ReassignURL() error = %v", err)
+	}
+	if err = s.SetURLDisabled(ctx, "missing", true); !errors.Is(err, entities.ErrURLNotFound) {
+		t.Errorf("SetURLDisabled() of a missing URL error = %v, want %v", err, entities.ErrURLNotFound)
+	}
+	if err = s.ReassignURL(ctx, "missing", "moderators"); !errors.Is(err, entities.ErrURLNotFound) {
+		t.Errorf("ReassignURL() of a missing URL error = %v, want %v", err, entities.ErrURLNotFound)
+	}
+
+	ban := &entities.Ban{UserID: "spammer", Reason: "spam", BannedBy: "admin", CreatedAt: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)}
+	for _, b := range []*entities.Ban{ban, {UserID: "bob", BannedBy: "admin"}} {
+		if err = s.BanUser(ctx, b); err != nil {
+			t.Fatalf("BanUser() error = %v", err)
+		}
+	}
+	if err = s.UnbanUser(ctx, "bob"); err != nil {
+		t.Fatalf("UnbanUser() error = %v", err)
+	}
+	if err = s.UnbanUser(ctx, "bob"); !errors.Is(err, entities.ErrBanNotFound) {
+		t.Errorf("UnbanUser() of a user not banned error = %v, want %v", err, entities.ErrBanNotFound)
+	}
+
+	for _, action := range []string{entities.AuditDisableURL, entities.AuditBanUser} {
+		entry := &entities.AuditEntry{Actor: "admin", Action: action, Target: "spam1"}
+		if err = s.AddAuditEntry(ctx, entry); err != nil {
+			t.Fatalf("AddAuditEntry() error = %v", err)
+		}
+		if entry.ID == 0 {
+			t.Errorf("AddAuditEntry() did not set the ID")
+		}
+	}
+
+	restored, err := New(filePath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for name, storage := range map[string]*St{"live": s, "restored": restored} {
+		link, err := storage.GetLink(ctx, "spam1")
+		if err != nil {
+			t.Fatalf("%s GetLink() error = %v", name, err)
+		}
+		if !link.Disabled || link.Active(time.Now()) {
+			t.Errorf("%s GetLink() of the disabled URL = %+v, want it disabled", name, link)
+		}
+
+		links, err := storage.SearchLinks(ctx, &entities.LinkFilter{Query: "spam.example", Limit: 10})
+		if err != nil {
+			t.Fatalf("%s SearchLinks() error = %v", name, err)
+		}
+		if len(links) != 2 || links[0].ShortURL != "spam1" || links[1].UserID != "moderators" {
+			t.Errorf("%s SearchLinks() = %+v, want spam1 and the reassigned spam2", name, links)
+		}
+		if links, _ = storage.SearchLinks(ctx, &entities.LinkFilter{UserID: "alice", Limit: 10}); len(links) != 1 {
+			t.Errorf("%s SearchLinks() of a user = %v links, want 1", name, len(links))
+		}
+		if links, _ = storage.SearchLinks(ctx, &entities.LinkFilter{Limit: 1, Offset: 2}); len(links) != 1 || links[0].ShortURL != "good" {
+			t.Errorf("%s SearchLinks() of the last page = %+v, want good", name, links)
+		}
+
+		got, err := storage.GetBan(ctx, "spammer")
+		if err != nil {
+			t.Fatalf("%s GetBan() error = %v", name, err)
+		}
+		if !reflect.DeepEqual(got, ban) {
+			t.Errorf("%s GetBan() got = %+v, want %+v", name, got, ban)
+		}
+		if _, err = storage.GetBan(ctx, "bob"); !errors.Is(err, entities.ErrBanNotFound) {
+			t.Errorf("%s GetBan() of an unbanned user error = %v, want %v", name, err, entities.ErrBanNotFound)
+		}
+
+		entries, err := storage.ListAuditEntries(ctx, 10)
+		if err != nil {
+			t.Fatalf("%s ListAuditEntries() error = %v", name, err)
+		}
+		if len(entries) != 2 || entries[0].Action != entities.AuditBanUser || entries[0].ID != 2 {
+			t.Errorf("%s ListAuditEntries() = %+v, want the ban first", name, entries)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package inmemory
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+)
+
+// keysSuffix is appended to the log file path to get the path of the API keys file.
+const keysSuffix = ".keys"
+
+// keyStore keeps the API keys by ID.
+// Every change of a key appends its whole state to the keys file, the last record of a key wins on restore.
+type keyStore struct {
+	mu   sync.RWMutex
+	keys map[string]*entities.APIKey // API keys keyed by ID.
+	file *os.File                    // Keys file opened for appending.
+}
+
+// keyRecord represents the state of an API key in the keys file.
+// Unlike entities.APIKey, it keeps the owner and the hash of the key when encoded.
+type keyRecord struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id"`
+	Name      string     `json:"name"`
+	Hash      string     `json:"hash"`
+	Scopes    []string   `json:"scopes"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// loadAPIKeys restores the API keys from the keys file.
+// Lines that can not be decoded, like a torn final line, are skipped.
+func (s *St) loadAPIKeys() error {
+	ks := &s.keyStore
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	ks.keys = make(map[string]*entities.APIKey)
+
+	if s.filePath == "" {
+		return nil
+	}
+
+	file, err := os.Open(s.filePath + keysSuffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record keyRecord
+		if err = json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			log.Printf("skipping malformed API key record in %s: %v", file.Name(), err)
+			continue
+		}
+
+		ks.keys[record.ID] = &entities.APIKey{
+			ID:        record.ID,
+			UserID:    record.UserID,
+			Name:      record.Name,
+			Hash:      record.Hash,
+			Scopes:    record.Scopes,
+			CreatedAt: record.CreatedAt,
+			RevokedAt: record.RevokedAt,
+		}
+	}
+
+	return scanner.Err()
+}
+
+// AddAPIKey stores a new API key.
+func (s *St) AddAPIKey(_ context.Context, key *entities.APIKey) error {
+	ks := &s.keyStore
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	stored := copyAPIKey(key)
+	if err := ks.save(s.filePath, stored); err != nil {
+		return err
+	}
+	ks.keys[stored.ID] = stored
+
+	return nil
+}
+
+// GetAPIKey returns the API key with the given ID.
+func (s *St) GetAPIKey(_ context.Context, id string) (*entities.APIKey, error) {
+	ks := &s.keyStore
+
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	key, exist := ks.keys[id]
+	if !exist {
+		return nil, entities.ErrAPIKeyNotFound
+	}
+
+	return copyAPIKey(key), nil
+}
+
+// ListAPIKeys returns the API keys of the user ordered by creation time.
+func (s *St) ListAPIKeys(_ context.Context, userID string) ([]*entities.APIKey, error) {
+	ks := &s.keyStore
+
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	result := make([]*entities.APIKey, 0)
+	for _, key := range ks.keys {
+		if key.UserID == userID {
+			result = append(result, copyAPIKey(key))
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+
+	return result, nil
+}
+
+// RevokeAPIKey revokes the user's API key at the given time.
+func (s *St) RevokeAPIKey(_ context.Context, id, userID string, revokedAt time.Time) error {
+	ks := &s.keyStore
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key, exist := ks.keys[id]
+	if !exist || key.UserID != userID {
+		return entities.ErrAPIKeyNotFound
+	}
+	if key.Revoked() {
+		return nil
+	}
+
+	revoked := copyAPIKey(key)
+	revoked.RevokedAt = &revokedAt
+	if err := ks.save(s.filePath, revoked); err != nil {
+		return err
+	}
+	ks.keys[id] = revoked
+
+	return nil
+}
+
+// save durably appends the state of the key to the keys file, if the storage has one.
+// It must be called with mu held.
+func (ks *keyStore) save(filePath string, key *entities.APIKey) error {
+	if filePath == "" {
+		return nil
+	}
+
+	if ks.file == nil {
+		file, err := os.OpenFile(filePath+keysSuffix, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return fmt.Errorf("write keys file: %w", err)
+		}
+		ks.file = file
+	}
+
+	data, err := json.Marshal(keyRecord{
+		ID:        key.ID,
+		UserID:    key.UserID,
+		Name:      key.Name,
+		Hash:      key.Hash,
+		Scopes:    key.Scopes,
+		CreatedAt: key.CreatedAt,
+		RevokedAt: key.RevokedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err = ks.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write keys file: %w", err)
+	}
+
+	return ks.file.Sync()
+}
+
+// copyAPIKey returns a copy of the key sharing nothing with it.
+func copyAPIKey(key *entities.APIKey) *entities.APIKey {
+	c := *key
+	c.Scopes = slices.Clone(key.Scopes)
+	if key.RevokedAt != nil {
+		revokedAt := *key.RevokedAt
+		c.RevokedAt = &revokedAt
+	}
+	return &c
+}
@@ -0,0 +1,76 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+)
+
+func TestSt_APIKeys(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "short-url-repo.json")
+	ctx := context.Background()
+
+	s, err := New(filePath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	createdAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	keys := []*entities.APIKey{
+		{ID: "b", UserID: "1", Name: "second", Hash: "hb", Scopes: []string{entities.ScopeRead}, CreatedAt: createdAt.Add(time.Minute)},
+		{ID: "a", UserID: "1", Name: "first", Hash: "ha", Scopes: []string{entities.ScopeShorten}, CreatedAt: createdAt},
+		{ID: "c", UserID: "2", Name: "other", Hash: "hc", Scopes: []string{entities.ScopeDelete}, CreatedAt: createdAt},
+	}
+	for _, key := range keys {
+		if err = s.AddAPIKey(ctx, key); err != nil {
+			t.Fatalf("AddAPIKey() error = %v", err)
+		}
+	}
+
+	revokedAt := createdAt.Add(time.Hour)
+	if err = s.RevokeAPIKey(ctx, "a", "2", revokedAt); !errors.Is(err, entities.ErrAPIKeyNotFound) {
+		t.Errorf("RevokeAPIKey() of another user's key error = %v, want %v", err, entities.ErrAPIKeyNotFound)
+	}
+	if err = s.RevokeAPIKey(ctx, "a", "1", revokedAt); err != nil {
+		t.Fatalf("RevokeAPIKey() error = %v", err)
+	}
+	if err = s.RevokeAPIKey(ctx, "a", "1", revokedAt.Add(time.Hour)); err != nil {
+		t.Fatalf("RevokeAPIKey() of a revoked key error = %v", err)
+	}
+
+	revoked := *keys[1]
+	revoked.RevokedAt = &revokedAt
+	want := []*entities.APIKey{&revoked, keys[0]}
+
+	restored, err := New(filePath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for name, storage := range map[string]*St{"live": s, "restored": restored} {
+		got, err := storage.ListAPIKeys(ctx, "1")
+		if err != nil {
+			t.Fatalf("%s ListAPIKeys() error = %v", name, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s ListAPIKeys() got = %+v, want %+v", name, got, want)
+		}
+
+		key, err := storage.GetAPIKey(ctx, "c")
+		if err != nil {
+			t.Fatalf("%s GetAPIKey() error = %v", name, err)
+		}
+		if !reflect.DeepEqual(key, keys[2]) {
+			t.Errorf("%s GetAPIKey() got = %+v, want %+v", name, key, keys[2])
+		}
+
+		if _, err = storage.GetAPIKey(ctx, "missing"); !errors.Is(err, entities.ErrAPIKeyNotFound) {
+			t.Errorf("%s GetAPIKey() of a missing key error = %v, want %v", name, err, entities.ErrAPIKeyNotFound)
+		}
+	}
+}
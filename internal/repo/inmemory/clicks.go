@@ -0,0 +1,173 @@
+package inmemory
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+)
+
+// clicksSuffix is appended to the log file path to get the path of the clicks file.
+const clicksSuffix = ".clicks"
+
+// clickStore keeps the click events grouped by short URL.
+// Clicks never change once recorded, so they are appended to their own file
+// instead of the log file, which keeps log compaction independent of the traffic.
+type clickStore struct {
+	mu     sync.RWMutex
+	clicks map[string][]entities.Click // Clicks keyed by short URL.
+	file   *os.File                    // Clicks file opened for appending.
+}
+
+// loadClicks restores the clicks from the clicks file.
+// Lines that can not be decoded, like a torn final line, are skipped.
+func (s *St) loadClicks() error {
+	cs := &s.clickStore
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	cs.clicks = make(map[string][]entities.Click)
+
+	if s.filePath == "" {
+		return nil
+	}
+
+	file, err := os.Open(s.filePath + clicksSuffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var click entities.Click
+		if err = json.Unmarshal(scanner.Bytes(), &click); err != nil {
+			log.Printf("skipping malformed click record in %s: %v", file.Name(), err)
+			continue
+		}
+
+		cs.clicks[click.ShortURL] = append(cs.clicks[click.ShortURL], click)
+	}
+
+	return scanner.Err()
+}
+
+// AddClicks appends the clicks to the clicks file and keeps them in memory.
+func (s *St) AddClicks(_ context.Context, clicks []*entities.Click) error {
+	cs := &s.clickStore
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if s.filePath != "" && len(clicks) > 0 {
+		if err := cs.append(s.filePath+clicksSuffix, clicks); err != nil {
+			return fmt.Errorf("write clicks file: %w", err)
+		}
+	}
+
+	for _, click := range clicks {
+		cs.clicks[click.ShortURL] = append(cs.clicks[click.ShortURL], *click)
+	}
+
+	return nil
+}
+
+// ClickStats returns the click statistics of the short URL owned by the user.
+func (s *St) ClickStats(_ context.Context, shortURL, userID string, bucket time.Duration) (*entities.LinkStats, error) {
+	record, exist := s.shards.get(shortURL)
+	if !exist || record.UserID != userID {
+		return nil, entities.ErrURLNotFound
+	}
+
+	cs := &s.clickStore
+
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	stats := &entities.LinkStats{Histogram: make([]*entities.StatsBucket, 0)}
+	visitors := make(map[string]struct{})
+	buckets := make(map[time.Time]*entities.StatsBucket)
+
+	for _, click := range cs.clicks[shortURL] {
+		stats.TotalClicks++
+
+		if click.IPHash != "" {
+			visitors[click.IPHash] = struct{}{}
+		}
+
+		start := bucketStart(click.ClickedAt, bucket)
+		b, exist := buckets[start]
+		if !exist {
+			b = &entities.StatsBucket{Start: start}
+			buckets[start] = b
+			stats.Histogram = append(stats.Histogram, b)
+		}
+		b.Clicks++
+	}
+
+	stats.UniqueVisitors = int64(len(visitors))
+
+	sort.Slice(stats.Histogram, func(i, j int) bool {
+		return stats.Histogram[i].Start.Before(stats.Histogram[j].Start)
+	})
+
+	return stats, nil
+}
+
+// bucketStart returns the start of the bucket containing the time.
+// Buckets are aligned to the Unix epoch, like in the PostgreSQL storage.
+func bucketStart(t time.Time, bucket time.Duration) time.Time {
+	seconds := int64(bucket / time.Second)
+	if seconds <= 0 {
+		seconds = 1
+	}
+
+	unix := t.Unix()
+	offset := unix % seconds
+	if offset < 0 {
+		offset += seconds
+	}
+
+	return time.Unix(unix-offset, 0).UTC()
+}
+
+// append durably writes the clicks to the end of the clicks file.
+// It must be called with mu held.
+func (cs *clickStore) append(filePath string, clicks []*entities.Click) error {
+	if cs.file == nil {
+		file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+		if err != nil {
+			return err
+		}
+		cs.file = file
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+
+	for _, click := range clicks {
+		if err := encoder.Encode(click); err != nil {
+			return err
+		}
+	}
+
+	if _, err := cs.file.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	return cs.file.Sync()
+}
@@ -0,0 +1,64 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+)
+
+func TestSt_ClickStats(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "short-url-repo.json")
+
+	s, err := New(filePath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if err = s.Add(context.Background(), "example.com", "tst", "1", nil); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	clicks := []*entities.Click{
+		{ShortURL: "tst", ClickedAt: start.Add(5 * time.Minute), IPHash: "a"},
+		{ShortURL: "tst", ClickedAt: start.Add(50 * time.Minute), IPHash: "b"},
+		{ShortURL: "tst", ClickedAt: start.Add(3 * time.Hour), IPHash: "a"},
+		{ShortURL: "other", ClickedAt: start, IPHash: "c"},
+	}
+	if err = s.AddClicks(context.Background(), clicks); err != nil {
+		t.Fatalf("AddClicks() error = %v", err)
+	}
+
+	want := &entities.LinkStats{
+		TotalClicks:    3,
+		UniqueVisitors: 2,
+		Histogram: []*entities.StatsBucket{
+			{Start: start, Clicks: 2},
+			{Start: start.Add(3 * time.Hour), Clicks: 1},
+		},
+	}
+
+	restored, err := New(filePath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for name, storage := range map[string]*St{"live": s, "restored": restored} {
+		got, err := storage.ClickStats(context.Background(), "tst", "1", time.Hour)
+		if err != nil {
+			t.Fatalf("%s ClickStats() error = %v", name, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s ClickStats() got = %+v, want %+v", name, got, want)
+		}
+	}
+
+	if _, err = s.ClickStats(context.Background(), "tst", "2", time.Hour); !errors.Is(err, entities.ErrURLNotFound) {
+		t.Errorf("ClickStats() of another user error = %v, want %v", err, entities.ErrURLNotFound)
+	}
+}
@@ -0,0 +1,182 @@
+package inmemory
+
+import (
+	"sync"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+)
+
+// shardCount is the number of shards the storage maps are split into.
+const shardCount = 32
+
+// shard holds a part of the storage maps protected by its own lock.
+// A URL record is stored in the shard of its short URL and
+// the index entry in the shard of its original URL.
+type shard struct {
+	sync.RWMutex
+	urls      map[string]*entities.Storage // URL records keyed by short URL.
+	originals map[string]string            // Short URLs keyed by original URL.
+}
+
+// shards is a fixed set of shards addressed by key hash.
+type shards [shardCount]*shard
+
+// newShards creates an empty set of shards.
+func newShards() *shards {
+	var sh shards
+	for i := range sh {
+		sh[i] = &shard{
+			urls:      make(map[string]*entities.Storage),
+			originals: make(map[string]string),
+		}
+	}
+	return &sh
+}
+
+// of returns the shard responsible for the key.
+func (sh *shards) of(key string) *shard {
+	// 32-bit FNV-1a, inlined to avoid allocating a hash.Hash32 per lookup.
+	hash := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= 16777619
+	}
+	return sh[hash%shardCount]
+}
+
+// get returns a copy of the record stored under the short URL.
+func (sh *shards) get(shortURL string) (entities.Storage, bool) {
+	s := sh.of(shortURL)
+
+	s.RLock()
+	defer s.RUnlock()
+
+	record, exist := s.urls[shortURL]
+	if !exist {
+		return entities.Storage{}, false
+	}
+	return *record, true
+}
+
+// shortURL returns the short URL stored for the original URL.
+func (sh *shards) shortURL(originalURL string) (string, bool) {
+	s := sh.of(originalURL)
+
+	s.RLock()
+	defer s.RUnlock()
+
+	shortURL, exist := s.originals[originalURL]
+	return shortURL, exist
+}
+
+// put stores the record in both indexes.
+func (sh *shards) put(record *entities.Storage) {
+	s := sh.of(record.ShortURL)
+	s.Lock()
+	s.urls[record.ShortURL] = record
+	s.Unlock()
+
+	s = sh.of(record.OriginalURL)
+	s.Lock()
+	s.originals[record.OriginalURL] = record.ShortURL
+	s.Unlock()
+}
+
+// rename moves the record stored under the old short URL to the new one.
+func (sh *shards) rename(oldShortURL, newShortURL string) {
+	s := sh.of(oldShortURL)
+	s.Lock()
+	record, exist := s.urls[oldShortURL]
+	if !exist {
+		s.Unlock()
+		return
+	}
+	delete(s.urls, oldShortURL)
+	s.Unlock()
+
+	updated := *record
+	updated.ShortURL = newShortURL
+	sh.put(&updated)
+}
+
+// remove deletes the record stored under the short URL from both indexes.
+func (sh *shards) remove(shortURL string) {
+	s := sh.of(shortURL)
+	s.Lock()
+	record, exist := s.urls[shortURL]
+	delete(s.urls, shortURL)
+	s.Unlock()
+
+	if !exist {
+		return
+	}
+
+	s = sh.of(record.OriginalURL)
+	s.Lock()
+	if s.originals[record.OriginalURL] == shortURL {
+		delete(s.originals, record.OriginalURL)
+	}
+	s.Unlock()
+}
+
+// markDeleted sets the deleted flag of the record stored under the short URL.
+func (sh *shards) markDeleted(shortURL string) {
+	s := sh.of(shortURL)
+
+	s.Lock()
+	defer s.Unlock()
+
+	if record, exist := s.urls[shortURL]; exist {
+		record.DeletedFlag = true
+	}
+}
+
+// setOwner sets the user owning the record stored under the short URL.
+func (sh *shards) setOwner(shortURL, userID string) {
+	s := sh.of(shortURL)
+
+	s.Lock()
+	defer s.Unlock()
+
+	if record, exist := s.urls[shortURL]; exist {
+		record.UserID = userID
+	}
+}
+
+// setDisabled sets the disabled flag of the record stored under the short URL.
+func (sh *shards) setDisabled(shortURL string, disabled bool) {
+	s := sh.of(shortURL)
+
+	s.Lock()
+	defer s.Unlock()
+
+	if record, exist := s.urls[shortURL]; exist {
+		record.DisabledFlag = disabled
+	}
+}
+
+// filter returns copies of all records matching the filter.
+func (sh *shards) filter(match func(*entities.Storage) bool) []entities.Storage {
+	records := make([]entities.Storage, 0)
+	for _, s := range sh {
+		s.RLock()
+		for _, record := range s.urls {
+			if match(record) {
+				records = append(records, *record)
+			}
+		}
+		s.RUnlock()
+	}
+	return records
+}
+
+// each calls fn with every record, fn must not modify the record.
+func (sh *shards) each(fn func(*entities.Storage)) {
+	for _, s := range sh {
+		s.RLock()
+		for _, record := range s.urls {
+			fn(record)
+		}
+		s.RUnlock()
+	}
+}
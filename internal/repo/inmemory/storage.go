@@ -3,28 +3,51 @@
 package inmemory
 
 import (
-	"bufio"
-	"encoding/json"
-	"io"
+	"context"
+	"fmt"
 	"log"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/Azzonya/go-shortener/internal/entities"
 )
 
 // St represents the in-memory storage structure for shortened URLs.
+// It is safe for concurrent use: reads only lock the shards they touch,
+// while changes are serialized so that uniqueness checks and the log file stay consistent.
 type St struct {
-	URLMap   map[string]string // Map to store shortened URLs as key-value pairs.
-	filePath string            // File path to store the JSON data.
-	lastID   int               // Last ID used for the storage.
+	shards   *shards    // Sharded maps storing URL records and the original URL index.
+	writeMu  sync.Mutex // Serializes changes of the storage and writes to the log file.
+	filePath string     // File path to store the JSON data, empty to keep data in memory only.
+	lastID   int        // Last ID used for the storage.
+	size     int        // Number of stored URL records.
+	file     *os.File   // Log file opened for appending events.
+	pending  int        // Number of events in the log file since the last compaction.
+	seq      int64      // Last value returned by NextSequence.
+	reserved int64      // Highest sequence value reserved in the log file.
+
+	clickStore clickStore   // Click events of the stored URLs.
+	keyStore   keyStore     // API keys of the users.
+	accounts   accountStore // Registered user accounts.
+	bans       banStore     // Banned users.
+	audit      auditStore   // Audit trail of the administrators.
 }
 
 // Event represents the event structure used for JSON encoding and decoding.
+// Every change of the storage is appended to the log file as an event.
 type Event struct {
-	NumberUUID  string `json:"uuid"`
-	ShortURL    string `json:"short_url"`
-	OriginalURL string `json:"original_url"`
+	Action       string     `json:"action,omitempty"`
+	NumberUUID   string     `json:"uuid"`
+	ShortURL     string     `json:"short_url"`
+	OriginalURL  string     `json:"original_url"`
+	UserID       string     `json:"user_id,omitempty"`
+	DeletedFlag  bool       `json:"is_deleted,omitempty"`
+	DisabledFlag bool       `json:"is_disabled,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
 }
 
 // New creates and initializes a new in-memory storage instance with the provided file path.
@@ -32,7 +55,7 @@ func New(filePath string) (*St, error) {
 	s := &St{}
 
 	s.filePath = filePath
-	err := s.Initialize()
+	err := s.Initialize(context.Background())
 	if err != nil {
 		return nil, err
 	}
@@ -40,133 +63,471 @@ func New(filePath string) (*St, error) {
 	return s, nil
 }
 
-// Initialize initializes the in-memory storage by reading data from the provided file.
-func (s *St) Initialize() error {
-	file, err := os.OpenFile(s.filePath, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0666)
-	if err != nil {
-		return err
+// Initialize initializes the in-memory storage by replaying the events from the provided file.
+func (s *St) Initialize(_ context.Context) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.shards = newShards()
+	s.lastID = 0
+	s.size = 0
+	s.pending = 0
+	s.seq = 0
+	s.reserved = 0
+
+	if err := s.loadClicks(); err != nil {
+		return fmt.Errorf("load clicks: %w", err)
 	}
 
-	defer file.Close()
+	if err := s.loadAPIKeys(); err != nil {
+		return fmt.Errorf("load API keys: %w", err)
+	}
 
-	newDecoder := json.NewDecoder(file)
+	if err := s.loadAccounts(); err != nil {
+		return fmt.Errorf("load accounts: %w", err)
+	}
 
-	s.URLMap = make(map[string]string)
-	s.lastID = 0
+	if err := s.loadBans(); err != nil {
+		return fmt.Errorf("load bans: %w", err)
+	}
 
-	for {
-		var event Event
-		if err := newDecoder.Decode(&event); err != nil {
-			if err != io.EOF {
-				log.Println("error decode JSON:", err)
-			}
-			break
-		}
-		s.lastID++
-		s.URLMap[event.OriginalURL] = event.ShortURL
+	if err := s.loadAudit(); err != nil {
+		return fmt.Errorf("load audit trail: %w", err)
+	}
+
+	if s.filePath == "" {
+		return nil
+	}
+
+	if err := s.replay(); err != nil {
+		return err
 	}
 
+	// Values handed out before the restart are unknown, continue after everything reserved.
+	s.seq = s.reserved
+
 	return nil
 }
 
 // TableExist checks if the table exists in the in-memory storage (always returns true).
-func (s *St) TableExist() bool {
+func (s *St) TableExist(_ context.Context) bool {
 	return true
 }
 
 // Add adds a new URL mapping to the in-memory storage.
-func (s *St) Add(originalURL, shortURL, _ string) error {
-	s.URLMap[shortURL] = originalURL
-	s.lastID++
+// It returns entities.ErrOriginalURLExists or entities.ErrShortURLExists
+// if either URL is already stored.
+func (s *St) Add(_ context.Context, originalURL, shortURL, userID string, expiresAt *time.Time) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
 
-	return nil
+	if err := s.checkUnique(originalURL, shortURL); err != nil {
+		return err
+	}
+
+	event := &Event{
+		Action:      actionAdd,
+		NumberUUID:  strconv.Itoa(s.lastID + 1),
+		ShortURL:    shortURL,
+		OriginalURL: originalURL,
+		UserID:      userID,
+		ExpiresAt:   expiresAt,
+	}
+
+	return s.commit(event)
 }
 
-// Update always returns nil.
-func (s *St) Update(_, _ string) error {
-	return nil
+// Update updates the short URL associated with the given original URL in the in-memory storage.
+func (s *St) Update(_ context.Context, originalURL, shortURL string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	oldShortURL, exist := s.shards.shortURL(originalURL)
+	if !exist || oldShortURL == shortURL {
+		return nil
+	}
+
+	if _, taken := s.shards.get(shortURL); taken {
+		return entities.ErrShortURLExists
+	}
+
+	event := &Event{
+		Action:      actionUpdate,
+		ShortURL:    shortURL,
+		OriginalURL: originalURL,
+	}
+
+	return s.commit(event)
 }
 
 // GetByShortURL retrieves the original URL associated with the given short URL.
-func (s *St) GetByShortURL(shortURL string) (string, bool) {
-	URL, exist := s.URLMap[shortURL]
-	return URL, exist
+func (s *St) GetByShortURL(_ context.Context, shortURL string) (string, bool) {
+	record, exist := s.shards.get(shortURL)
+	if !exist {
+		return "", false
+	}
+	return record.OriginalURL, true
+}
+
+// GetLink returns the stored state of the short URL.
+func (s *St) GetLink(_ context.Context, shortURL string) (*entities.Link, error) {
+	record, exist := s.shards.get(shortURL)
+	if !exist {
+		return nil, entities.ErrURLNotFound
+	}
+
+	return &entities.Link{
+		ShortURL:    record.ShortURL,
+		OriginalURL: record.OriginalURL,
+		UserID:      record.UserID,
+		Deleted:     record.DeletedFlag,
+		Disabled:    record.DisabledFlag,
+		ExpiresAt:   record.ExpiresAt,
+	}, nil
 }
 
 // GetByOriginalURL retrieves the short URL associated with the given original URL.
-func (s *St) GetByOriginalURL(originalURL string) (string, bool) {
-	for key, val := range s.URLMap {
-		if val == originalURL {
-			return key, true
+func (s *St) GetByOriginalURL(_ context.Context, originalURL string) (string, bool) {
+	return s.shards.shortURL(originalURL)
+}
+
+// ListAll retrieves all shortened URLs associated with a user from the in-memory storage
+// in the order they were added.
+func (s *St) ListAll(_ context.Context, userID string) ([]*entities.ReqListAll, error) {
+	records := s.sortedRecords(func(record *entities.Storage) bool {
+		return record.UserID == userID
+	})
+
+	result := make([]*entities.ReqListAll, 0, len(records))
+	for _, record := range records {
+		result = append(result, &entities.ReqListAll{
+			ShortURL:    record.ShortURL,
+			OriginalURL: record.OriginalURL,
+		})
+	}
+
+	return result, nil
+}
+
+// Stats returns the total number of stored URLs and of distinct users owning them.
+func (s *St) Stats(_ context.Context) (*entities.ServiceStats, error) {
+	stats := &entities.ServiceStats{}
+	users := make(map[string]struct{})
+
+	s.shards.each(func(record *entities.Storage) {
+		stats.URLs++
+		if record.UserID != "" {
+			users[record.UserID] = struct{}{}
+		}
+	})
+
+	stats.Users = int64(len(users))
+
+	return stats, nil
+}
+
+// CreateShortURLs creates multiple shortened URLs in the in-memory storage.
+// Like a transaction, either all URLs are stored or none of them.
+func (s *St) CreateShortURLs(_ context.Context, urls []*entities.ReqURL, userID string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	originals := make(map[string]struct{}, len(urls))
+	shorts := make(map[string]struct{}, len(urls))
+
+	for _, v := range urls {
+		if err := s.checkUnique(v.OriginalURL, v.ShortURL); err != nil {
+			return fmt.Errorf("statement exec error: %w", err)
+		}
+
+		if _, exist := originals[v.OriginalURL]; exist {
+			return fmt.Errorf("statement exec error: %w", entities.ErrOriginalURLExists)
 		}
+		if _, exist := shorts[v.ShortURL]; exist {
+			return fmt.Errorf("statement exec error: %w", entities.ErrShortURLExists)
+		}
+
+		originals[v.OriginalURL] = struct{}{}
+		shorts[v.ShortURL] = struct{}{}
+	}
+
+	events := make([]*Event, 0, len(urls))
+	for i, v := range urls {
+		events = append(events, &Event{
+			Action:      actionAdd,
+			NumberUUID:  strconv.Itoa(s.lastID + i + 1),
+			ShortURL:    v.ShortURL,
+			OriginalURL: v.OriginalURL,
+			UserID:      userID,
+			ExpiresAt:   v.ExpiresAt,
+		})
 	}
-	return "", false
+
+	return s.commit(events...)
 }
 
-// ListAll always returns nil.
-func (s *St) ListAll(_ string) ([]*entities.ReqListAll, error) {
-	return nil, nil
+// DeleteURLs marks the given short URLs as deleted if they belong to the user.
+// URLs owned by other users or missing from the storage are skipped.
+func (s *St) DeleteURLs(ctx context.Context, urls []string, userID string) error {
+	return s.DeleteURLsBatch(ctx, []*entities.DeleteRequest{{UserID: userID, ShortURLs: urls}})
 }
 
-// CreateShortURLs always returns nil.
-func (s *St) CreateShortURLs(_ []*entities.ReqURL, _ string) error {
-	return nil
+// DeleteURLsBatch marks the short URLs of every request as deleted if they belong to the user of the request.
+// The deletions of all requests are written to the log in a single append.
+func (s *St) DeleteURLsBatch(_ context.Context, requests []*entities.DeleteRequest) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	var events []*Event
+	for _, req := range requests {
+		for _, shortURL := range req.ShortURLs {
+			record, exist := s.shards.get(shortURL)
+			if !exist || record.UserID != req.UserID || record.DeletedFlag {
+				continue
+			}
+
+			events = append(events, &Event{
+				Action:   actionDelete,
+				ShortURL: shortURL,
+				UserID:   req.UserID,
+			})
+		}
+	}
+
+	return s.commit(events...)
 }
 
-// DeleteURLs always returns nil.
-func (s *St) DeleteURLs(_ []string, _ string) error {
-	return nil
+// TransferURLs makes the user toUserID the owner of every URL of the user fromUserID.
+// The transfers are written to the log in a single append.
+func (s *St) TransferURLs(_ context.Context, fromUserID, toUserID string) (int64, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	records := s.sortedRecords(func(record *entities.Storage) bool {
+		return record.UserID == fromUserID
+	})
+
+	events := make([]*Event, 0, len(records))
+	for _, record := range records {
+		events = append(events, &Event{
+			Action:   actionTransfer,
+			ShortURL: record.ShortURL,
+			UserID:   toUserID,
+		})
+	}
+
+	if err := s.commit(events...); err != nil {
+		return 0, err
+	}
+
+	return int64(len(events)), nil
 }
 
-// URLDeleted always returns false.
-func (s *St) URLDeleted(_ string) bool {
-	return false
+// SearchLinks returns the links of every user matching the filter in the order they were added.
+func (s *St) SearchLinks(_ context.Context, filter *entities.LinkFilter) ([]*entities.Link, error) {
+	records := s.sortedRecords(func(record *entities.Storage) bool {
+		if filter.UserID != "" && record.UserID != filter.UserID {
+			return false
+		}
+		return filter.Query == "" ||
+			strings.Contains(record.ShortURL, filter.Query) ||
+			strings.Contains(record.OriginalURL, filter.Query)
+	})
+
+	records = records[min(filter.Offset, len(records)):]
+	records = records[:min(filter.Limit, len(records))]
+
+	links := make([]*entities.Link, 0, len(records))
+	for _, record := range records {
+		links = append(links, &entities.Link{
+			ShortURL:    record.ShortURL,
+			OriginalURL: record.OriginalURL,
+			UserID:      record.UserID,
+			Deleted:     record.DeletedFlag,
+			Disabled:    record.DisabledFlag,
+			ExpiresAt:   record.ExpiresAt,
+		})
+	}
+
+	return links, nil
 }
 
-// WriteEvent writes an event to the JSON file.
-func (s *St) WriteEvent(event *Event) error {
-	file, err := os.OpenFile(s.filePath, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0666)
-	if err != nil {
-		return err
+// SetURLDisabled disables or enables again the short URL, whoever owns it.
+func (s *St) SetURLDisabled(_ context.Context, shortURL string, disabled bool) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	record, exist := s.shards.get(shortURL)
+	if !exist {
+		return entities.ErrURLNotFound
+	}
+	if record.DisabledFlag == disabled {
+		return nil
 	}
 
-	writer := bufio.NewWriter(file)
+	action := actionEnable
+	if disabled {
+		action = actionDisable
+	}
 
-	defer file.Close()
+	return s.commit(&Event{Action: action, ShortURL: shortURL})
+}
 
-	data, err := json.Marshal(&event)
-	if err != nil {
-		return err
+// ReassignURL makes the user the owner of the short URL.
+func (s *St) ReassignURL(_ context.Context, shortURL, userID string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	record, exist := s.shards.get(shortURL)
+	if !exist {
+		return entities.ErrURLNotFound
+	}
+	if record.UserID == userID {
+		return nil
 	}
 
-	if _, err = writer.Write(data); err != nil {
-		return err
+	return s.commit(&Event{
+		Action:   actionTransfer,
+		ShortURL: shortURL,
+		UserID:   userID,
+	})
+}
+
+// URLDeleted checks if the URL with the given short URL is deleted.
+func (s *St) URLDeleted(_ context.Context, shortURL string) bool {
+	record, exist := s.shards.get(shortURL)
+	if !exist {
+		return false
 	}
+	return record.DeletedFlag
+}
 
-	if err = writer.WriteByte('\n'); err != nil {
-		return err
+// URLExpired checks if the URL with the given short URL has expired.
+func (s *St) URLExpired(_ context.Context, shortURL string) bool {
+	record, exist := s.shards.get(shortURL)
+	if !exist || record.ExpiresAt == nil {
+		return false
+	}
+	return !record.ExpiresAt.After(time.Now())
+}
+
+// DeleteExpired removes the URLs which expired before the given time.
+// With archive set, the removed records are appended to the archive file next to the log file,
+// the in-memory only storage has no archive and just removes them.
+func (s *St) DeleteExpired(_ context.Context, before time.Time, archive bool) (int64, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	records := s.sortedRecords(func(record *entities.Storage) bool {
+		return record.ExpiresAt != nil && record.ExpiresAt.Before(before)
+	})
+	if len(records) == 0 {
+		return 0, nil
+	}
+
+	if archive {
+		if err := s.appendArchive(records); err != nil {
+			return 0, fmt.Errorf("write archive file: %w", err)
+		}
+	}
+
+	events := make([]*Event, 0, len(records))
+	for _, record := range records {
+		events = append(events, &Event{
+			Action:   actionPurge,
+			ShortURL: record.ShortURL,
+		})
 	}
 
-	return writer.Flush()
+	if err := s.commit(events...); err != nil {
+		return 0, err
+	}
+
+	return int64(len(records)), nil
 }
 
-// SyncData synchronizes the in-memory storage data by writing events to the JSON file.
-func (s *St) SyncData() {
-	for k, v := range s.URLMap {
-		event := Event{
-			strconv.Itoa(s.lastID),
-			k,
-			v,
+// NextSequence returns the next value of the short code sequence.
+// Values are reserved in the log file in blocks, so the sequence never goes back after a restart,
+// although the values left in the block are skipped.
+func (s *St) NextSequence(_ context.Context) (int64, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if s.seq >= s.reserved {
+		event := &Event{
+			Action:     actionSequence,
+			NumberUUID: strconv.FormatInt(s.seq+sequenceBlock, 10),
 		}
 
-		err := s.WriteEvent(&event)
-		if err != nil {
-			log.Fatalf("Sync data - %s", err.Error())
+		if err := s.commit(event); err != nil {
+			return 0, err
 		}
 	}
+
+	s.seq++
+
+	return s.seq, nil
+}
+
+// WriteEvent appends an event to the log file and applies it to the in-memory storage.
+func (s *St) WriteEvent(event *Event) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	return s.commit(event)
+}
+
+// SyncData synchronizes the in-memory storage data by compacting the log file into a snapshot.
+func (s *St) SyncData() {
+	if err := s.Compact(); err != nil {
+		log.Fatalf("Sync data - %s", err.Error())
+	}
 }
 
 // Ping pings the in-memory storage (always returns nil).
-func (s *St) Ping() error {
+func (s *St) Ping(_ context.Context) error {
 	return nil
 }
+
+// checkUnique reports whether the original or short URL is already stored.
+// It must be called with writeMu held.
+func (s *St) checkUnique(originalURL, shortURL string) error {
+	if _, exist := s.shards.shortURL(originalURL); exist {
+		return entities.ErrOriginalURLExists
+	}
+
+	if _, exist := s.shards.get(shortURL); exist {
+		return entities.ErrShortURLExists
+	}
+
+	return nil
+}
+
+// commit durably appends the events to the log file and then applies them to the in-memory storage.
+// It must be called with writeMu held.
+func (s *St) commit(events ...*Event) error {
+	if err := s.appendEvents(events...); err != nil {
+		return fmt.Errorf("write log file: %w", err)
+	}
+
+	for _, event := range events {
+		s.apply(event)
+	}
+
+	s.compactIfNeeded()
+
+	return nil
+}
+
+// sortedRecords returns copies of the records matching the filter in the order they were added.
+func (s *St) sortedRecords(filter func(*entities.Storage) bool) []entities.Storage {
+	records := s.shards.filter(filter)
+
+	sort.Slice(records, func(i, j int) bool {
+		idI, _ := strconv.Atoi(records[i].UUID)
+		idJ, _ := strconv.Atoi(records[j].UUID)
+		return idI < idJ
+	})
+
+	return records
+}
@@ -1,13 +1,19 @@
 package inmemory
 
 import (
+	"context"
+	"errors"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/Azzonya/go-shortener/internal/entities"
 )
 
 func TestNew(t *testing.T) {
+	dir := t.TempDir()
+
 	type args struct {
 		filePath string
 	}
@@ -20,12 +26,10 @@ func TestNew(t *testing.T) {
 		{
 			name: "New inmemory test",
 			args: args{
-				filePath: "/tmp/short-url-repo.json",
+				filePath: filepath.Join(dir, "short-url-repo.json"),
 			},
 			want: &St{
-				filePath: "/tmp/short-url-repo.json",
-				URLMap:   make(map[string]string),
-				lastID:   0,
+				filePath: filepath.Join(dir, "short-url-repo.json"),
 			},
 			wantErr: false,
 		},
@@ -45,10 +49,10 @@ func TestNew(t *testing.T) {
 }
 
 func TestSt_Add(t *testing.T) {
+	dir := t.TempDir()
+
 	type fields struct {
-		URLMap   map[string]string
 		filePath string
-		lastID   int
 	}
 	type args struct {
 		originalURL string
@@ -64,9 +68,7 @@ func TestSt_Add(t *testing.T) {
 		{
 			name: "Add inmemory test",
 			fields: fields{
-				filePath: "/tmp/short-url-repo.json",
-				URLMap:   make(map[string]string),
-				lastID:   0,
+				filePath: filepath.Join(dir, "short-url-repo.json"),
 			},
 			args: args{
 				shortURL:    "tst",
@@ -77,12 +79,12 @@ func TestSt_Add(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := &St{
-				URLMap:   tt.fields.URLMap,
-				filePath: tt.fields.filePath,
-				lastID:   tt.fields.lastID,
+			s, err := New(tt.fields.filePath)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
 			}
-			if err := s.Add(tt.args.originalURL, tt.args.shortURL, tt.args.userID); (err != nil) != tt.wantErr {
+
+			if err := s.Add(context.Background(), tt.args.originalURL, tt.args.shortURL, tt.args.userID, nil); (err != nil) != tt.wantErr {
 				t.Errorf("Add() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -90,10 +92,10 @@ func TestSt_Add(t *testing.T) {
 }
 
 func TestSt_CreateShortURLs(t *testing.T) {
+	dir := t.TempDir()
+
 	type fields struct {
-		URLMap   map[string]string
 		filePath string
-		lastID   int
 	}
 	type args struct {
 		urls   []*entities.ReqURL
@@ -108,9 +110,7 @@ func TestSt_CreateShortURLs(t *testing.T) {
 		{
 			name: "Create short urls inmemory test",
 			fields: fields{
-				filePath: "/tmp/short-url-repo.json",
-				URLMap:   make(map[string]string),
-				lastID:   0,
+				filePath: filepath.Join(dir, "short-url-repo.json"),
 			},
 			args:    args{},
 			wantErr: false,
@@ -118,12 +118,12 @@ func TestSt_CreateShortURLs(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := &St{
-				URLMap:   tt.fields.URLMap,
-				filePath: tt.fields.filePath,
-				lastID:   tt.fields.lastID,
+			s, err := New(tt.fields.filePath)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
 			}
-			if err := s.CreateShortURLs(tt.args.urls, tt.args.userID); (err != nil) != tt.wantErr {
+
+			if err := s.CreateShortURLs(context.Background(), tt.args.urls, tt.args.userID); (err != nil) != tt.wantErr {
 				t.Errorf("CreateShortURLs() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -131,10 +131,10 @@ func TestSt_CreateShortURLs(t *testing.T) {
 }
 
 func TestSt_DeleteURLs(t *testing.T) {
+	dir := t.TempDir()
+
 	type fields struct {
-		URLMap   map[string]string
 		filePath string
-		lastID   int
 	}
 	type args struct {
 		urls   []string
@@ -149,9 +149,7 @@ func TestSt_DeleteURLs(t *testing.T) {
 		{
 			name: "Create short urls inmemory test",
 			fields: fields{
-				filePath: "/tmp/short-url-repo.json",
-				URLMap:   make(map[string]string),
-				lastID:   0,
+				filePath: filepath.Join(dir, "short-url-repo.json"),
 			},
 			args:    args{},
 			wantErr: false,
@@ -159,12 +157,12 @@ func TestSt_DeleteURLs(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := &St{
-				URLMap:   tt.fields.URLMap,
-				filePath: tt.fields.filePath,
-				lastID:   tt.fields.lastID,
+			s, err := New(tt.fields.filePath)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
 			}
-			if err := s.DeleteURLs(tt.args.urls, tt.args.userID); (err != nil) != tt.wantErr {
+
+			if err := s.DeleteURLs(context.Background(), tt.args.urls, tt.args.userID); (err != nil) != tt.wantErr {
 				t.Errorf("DeleteURLs() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -172,10 +170,10 @@ func TestSt_DeleteURLs(t *testing.T) {
 }
 
 func TestSt_GetByOriginalURL(t *testing.T) {
+	dir := t.TempDir()
+
 	type fields struct {
-		URLMap   map[string]string
 		filePath string
-		lastID   int
 	}
 	type args struct {
 		originalURL string
@@ -190,9 +188,7 @@ func TestSt_GetByOriginalURL(t *testing.T) {
 		{
 			name: "Get by original url inmemory test",
 			fields: fields{
-				filePath: "/tmp/short-url-repo.json",
-				URLMap:   make(map[string]string),
-				lastID:   0,
+				filePath: filepath.Join(dir, "short-url-repo.json"),
 			},
 			args: args{
 				"test.com",
@@ -203,16 +199,16 @@ func TestSt_GetByOriginalURL(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := &St{
-				URLMap:   tt.fields.URLMap,
-				filePath: tt.fields.filePath,
-				lastID:   tt.fields.lastID,
+			s, err := New(tt.fields.filePath)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
 			}
-			if err := s.Add(tt.args.originalURL, "tst", ""); (err != nil) != false {
+
+			if err := s.Add(context.Background(), tt.args.originalURL, "tst", "", nil); (err != nil) != false {
 				t.Errorf("Add() error = %v, wantErr %v", err, false)
 			}
 
-			got, got1 := s.GetByOriginalURL(tt.args.originalURL)
+			got, got1 := s.GetByOriginalURL(context.Background(), tt.args.originalURL)
 			if got != tt.want {
 				t.Errorf("GetByOriginalURL() got = %v, want %v", got, tt.want)
 			}
@@ -224,10 +220,10 @@ func TestSt_GetByOriginalURL(t *testing.T) {
 }
 
 func TestSt_GetByShortURL(t *testing.T) {
+	dir := t.TempDir()
+
 	type fields struct {
-		URLMap   map[string]string
 		filePath string
-		lastID   int
 	}
 	type args struct {
 		shortURL string
@@ -242,9 +238,7 @@ func TestSt_GetByShortURL(t *testing.T) {
 		{
 			name: "Get by short url inmemory test",
 			fields: fields{
-				filePath: "/tmp/short-url-repo.json",
-				URLMap:   make(map[string]string),
-				lastID:   0,
+				filePath: filepath.Join(dir, "short-url-repo.json"),
 			},
 			args: args{
 				"tst",
@@ -255,16 +249,16 @@ func TestSt_GetByShortURL(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := &St{
-				URLMap:   tt.fields.URLMap,
-				filePath: tt.fields.filePath,
-				lastID:   tt.fields.lastID,
+			s, err := New(tt.fields.filePath)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
 			}
-			if err := s.Add(tt.want, tt.args.shortURL, ""); (err != nil) != false {
+
+			if err := s.Add(context.Background(), tt.want, tt.args.shortURL, "", nil); (err != nil) != false {
 				t.Errorf("Add() error = %v, wantErr %v", err, false)
 			}
 
-			got, got1 := s.GetByShortURL(tt.args.shortURL)
+			got, got1 := s.GetByShortURL(context.Background(), tt.args.shortURL)
 			if got != tt.want {
 				t.Errorf("GetByShortURL() got = %v, want %v", got, tt.want)
 			}
@@ -276,10 +270,10 @@ func TestSt_GetByShortURL(t *testing.T) {
 }
 
 func TestSt_Initialize(t *testing.T) {
+	dir := t.TempDir()
+
 	type fields struct {
-		URLMap   map[string]string
 		filePath string
-		lastID   int
 	}
 	tests := []struct {
 		name    string
@@ -289,9 +283,7 @@ func TestSt_Initialize(t *testing.T) {
 		{
 			name: "Initialize inmemory test",
 			fields: fields{
-				filePath: "/tmp/short-url-repo.json",
-				URLMap:   make(map[string]string),
-				lastID:   0,
+				filePath: filepath.Join(dir, "short-url-repo.json"),
 			},
 			wantErr: false,
 		},
@@ -301,7 +293,7 @@ func TestSt_Initialize(t *testing.T) {
 			s := &St{
 				filePath: tt.fields.filePath,
 			}
-			if err := s.Initialize(); (err != nil) != tt.wantErr {
+			if err := s.Initialize(context.Background()); (err != nil) != tt.wantErr {
 				t.Errorf("Initialize() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -309,10 +301,10 @@ func TestSt_Initialize(t *testing.T) {
 }
 
 func TestSt_ListAll(t *testing.T) {
+	dir := t.TempDir()
+
 	type fields struct {
-		URLMap   map[string]string
 		filePath string
-		lastID   int
 	}
 	type args struct {
 		userID string
@@ -327,9 +319,7 @@ func TestSt_ListAll(t *testing.T) {
 		{
 			name: "ListAll inmemory test",
 			fields: fields{
-				filePath: "/tmp/short-url-repo.json",
-				URLMap:   make(map[string]string),
-				lastID:   0,
+				filePath: filepath.Join(dir, "short-url-repo.json"),
 			},
 			args: args{
 				"",
@@ -339,12 +329,12 @@ func TestSt_ListAll(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := &St{
-				URLMap:   tt.fields.URLMap,
-				filePath: tt.fields.filePath,
-				lastID:   tt.fields.lastID,
+			s, err := New(tt.fields.filePath)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
 			}
-			_, err := s.ListAll(tt.args.userID)
+
+			_, err = s.ListAll(context.Background(), tt.args.userID)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ListAll() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -354,10 +344,10 @@ func TestSt_ListAll(t *testing.T) {
 }
 
 func TestSt_SyncData(t *testing.T) {
+	dir := t.TempDir()
+
 	type fields struct {
-		URLMap   map[string]string
 		filePath string
-		lastID   int
 	}
 	tests := []struct {
 		name   string
@@ -366,20 +356,219 @@ func TestSt_SyncData(t *testing.T) {
 		{
 			name: "SyncData inmemory",
 			fields: fields{
-				filePath: "/tmp/short-url-repo.json",
-				URLMap:   make(map[string]string),
-				lastID:   0,
+				filePath: filepath.Join(dir, "short-url-repo.json"),
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := &St{
-				URLMap:   tt.fields.URLMap,
-				filePath: tt.fields.filePath,
-				lastID:   tt.fields.lastID,
+			s, err := New(tt.fields.filePath)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
 			}
+
 			s.SyncData()
 		})
 	}
 }
+
+func TestSt_AddDuplicate(t *testing.T) {
+	type args struct {
+		originalURL string
+		shortURL    string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr error
+	}{
+		{
+			name: "duplicate original url",
+			args: args{
+				originalURL: "test.com",
+				shortURL:    "other",
+			},
+			wantErr: entities.ErrOriginalURLExists,
+		},
+		{
+			name: "duplicate short url",
+			args: args{
+				originalURL: "other.com",
+				shortURL:    "tst",
+			},
+			wantErr: entities.ErrShortURLExists,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New("")
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if err := s.Add(context.Background(), "test.com", "tst", "1", nil); err != nil {
+				t.Fatalf("Add() error = %v", err)
+			}
+
+			if err := s.Add(context.Background(), tt.args.originalURL, tt.args.shortURL, "1", nil); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Add() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSt_CreateShortURLsAtomic(t *testing.T) {
+	s, err := New("")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := s.Add(context.Background(), "taken.com", "taken", "1", nil); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	urls := []*entities.ReqURL{
+		{OriginalURL: "first.com", ShortURL: "first"},
+		{OriginalURL: "second.com", ShortURL: "taken"},
+	}
+
+	if err := s.CreateShortURLs(context.Background(), urls, "1"); !errors.Is(err, entities.ErrShortURLExists) {
+		t.Errorf("CreateShortURLs() error = %v, wantErr %v", err, entities.ErrShortURLExists)
+	}
+
+	if _, exist := s.GetByShortURL(context.Background(), "first"); exist {
+		t.Errorf("CreateShortURLs() stored part of a failed batch")
+	}
+}
+
+func TestSt_ListAllByUser(t *testing.T) {
+	s, err := New("")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	urls := []*entities.ReqURL{
+		{OriginalURL: "first.com", ShortURL: "first"},
+		{OriginalURL: "second.com", ShortURL: "second"},
+	}
+	if err := s.CreateShortURLs(context.Background(), urls, "1"); err != nil {
+		t.Fatalf("CreateShortURLs() error = %v", err)
+	}
+	if err := s.Add(context.Background(), "third.com", "third", "2", nil); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	want := []*entities.ReqListAll{
+		{ShortURL: "first", OriginalURL: "first.com"},
+		{ShortURL: "second", OriginalURL: "second.com"},
+	}
+
+	got, err := s.ListAll(context.Background(), "1")
+	if err != nil {
+		t.Fatalf("ListAll() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListAll() got = %v, want %v", got, want)
+	}
+
+	got, err = s.ListAll(context.Background(), "3")
+	if err != nil {
+		t.Fatalf("ListAll() error = %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("ListAll() got = %v, want empty", got)
+	}
+}
+
+func TestSt_URLDeleted(t *testing.T) {
+	type args struct {
+		userID string
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{
+			name: "owner deletes url",
+			args: args{
+				userID: "1",
+			},
+			want: true,
+		},
+		{
+			name: "other user can not delete url",
+			args: args{
+				userID: "2",
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New("")
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if err := s.Add(context.Background(), "test.com", "tst", "1", nil); err != nil {
+				t.Fatalf("Add() error = %v", err)
+			}
+
+			if err := s.DeleteURLs(context.Background(), []string{"tst", "missing"}, tt.args.userID); err != nil {
+				t.Errorf("DeleteURLs() error = %v", err)
+			}
+
+			if got := s.URLDeleted(context.Background(), "tst"); got != tt.want {
+				t.Errorf("URLDeleted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSt_GetLink(t *testing.T) {
+	s, err := New("")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	expiresAt := time.Now().Add(time.Hour).UTC()
+	if err = s.Add(context.Background(), "test.com", "tst", "1", &expiresAt); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err = s.DeleteURLs(context.Background(), []string{"tst"}, "1"); err != nil {
+		t.Fatalf("DeleteURLs() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		shortURL string
+		want     *entities.Link
+		wantErr  error
+	}{
+		{
+			name:     "deleted link",
+			shortURL: "tst",
+			want: &entities.Link{
+				ShortURL:    "tst",
+				OriginalURL: "test.com",
+				UserID:      "1",
+				Deleted:     true,
+				ExpiresAt:   &expiresAt,
+			},
+		},
+		{
+			name:     "missing link",
+			shortURL: "missing",
+			wantErr:  entities.ErrURLNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GetLink(context.Background(), tt.shortURL)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetLink() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetLink() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
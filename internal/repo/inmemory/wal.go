@@ -0,0 +1,319 @@
+package inmemory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+)
+
+// Event actions stored in the log file.
+const (
+	actionAdd      = "add"      // actionAdd stores a new URL record.
+	actionUpdate   = "update"   // actionUpdate changes the short URL of an existing record.
+	actionDelete   = "delete"   // actionDelete marks a record as deleted.
+	actionPurge    = "purge"    // actionPurge removes an expired record.
+	actionTransfer = "transfer" // actionTransfer makes the user it holds the owner of a record.
+	actionDisable  = "disable"  // actionDisable marks a record as disabled by an administrator.
+	actionEnable   = "enable"   // actionEnable clears the disabled mark of a record.
+	actionSequence = "sequence" // actionSequence reserves short code sequence values up to the number it holds.
+)
+
+// archiveSuffix is appended to the log file path to get the path of the archive of expired records.
+const archiveSuffix = ".archive"
+
+// sequenceBlock is the number of sequence values reserved by a single log event.
+const sequenceBlock = 100
+
+// compactThreshold is the minimum number of events appended since the last compaction
+// before the log file is rewritten as a snapshot of the current state.
+const compactThreshold = 1000
+
+// writeLog writes the data to the log file, replaced in tests to simulate failing writes.
+var writeLog = (*os.File).Write
+
+// replay restores the storage state by applying every event from the log file.
+// A torn final line left by a crash in the middle of a write is truncated,
+// any other malformed line is reported as an error.
+func (s *St) replay() error {
+	file, err := os.OpenFile(s.filePath, os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+
+	var offset int64
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) == 0 {
+				return nil
+			}
+
+			log.Printf("truncating torn record at the end of %s", s.filePath)
+			return file.Truncate(offset)
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(bytes.TrimSpace(line)) != 0 {
+			var event Event
+			if err = json.Unmarshal(line, &event); err != nil {
+				return fmt.Errorf("decode record at offset %d: %w", offset, err)
+			}
+
+			s.apply(&event)
+			s.pending++
+		}
+
+		offset += int64(len(line))
+	}
+}
+
+// apply changes the in-memory state according to the event.
+// It must be called with writeMu held.
+func (s *St) apply(event *Event) {
+	switch event.Action {
+	case actionUpdate:
+		if shortURL, exist := s.shards.shortURL(event.OriginalURL); exist {
+			s.shards.rename(shortURL, event.ShortURL)
+		}
+	case actionDelete:
+		s.shards.markDeleted(event.ShortURL)
+	case actionTransfer:
+		s.shards.setOwner(event.ShortURL, event.UserID)
+	case actionDisable:
+		s.shards.setDisabled(event.ShortURL, true)
+	case actionEnable:
+		s.shards.setDisabled(event.ShortURL, false)
+	case actionPurge:
+		if _, exist := s.shards.get(event.ShortURL); exist {
+			s.shards.remove(event.ShortURL)
+			s.size--
+		}
+	case actionSequence:
+		if reserved, err := strconv.ParseInt(event.NumberUUID, 10, 64); err == nil && reserved > s.reserved {
+			s.reserved = reserved
+		}
+	default:
+		id, err := strconv.Atoi(event.NumberUUID)
+		if err != nil || id <= s.lastID {
+			id = s.lastID + 1
+		}
+		s.lastID = id
+		s.size++
+
+		s.shards.put(&entities.Storage{
+			UUID:         strconv.Itoa(id),
+			ShortURL:     event.ShortURL,
+			OriginalURL:  event.OriginalURL,
+			UserID:       event.UserID,
+			DeletedFlag:  event.DeletedFlag,
+			DisabledFlag: event.DisabledFlag,
+			ExpiresAt:    event.ExpiresAt,
+		})
+	}
+}
+
+// appendEvents durably writes the events to the end of the log file.
+// The events are written with a single write call and synced to disk before returning.
+// If the write or the sync fails, the log file is truncated back to its previous end and closed,
+// so that the next append does not follow a torn record, which replay would reject.
+// It must be called with writeMu held.
+func (s *St) appendEvents(events ...*Event) error {
+	if s.filePath == "" || len(events) == 0 {
+		return nil
+	}
+
+	if s.file == nil {
+		file, err := os.OpenFile(s.filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+		if err != nil {
+			return err
+		}
+		s.file = file
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+
+	info, err := s.file.Stat()
+	if err != nil {
+		return err
+	}
+	offset := info.Size()
+
+	if _, err = writeLog(s.file, buf.Bytes()); err == nil {
+		err = s.file.Sync()
+	}
+	if err != nil {
+		return errors.Join(err, s.discardTail(offset))
+	}
+
+	s.pending += len(events)
+
+	return nil
+}
+
+// discardTail truncates the log file back to the offset after a failed append and closes it,
+// so that it is opened again by the next append. It must be called with writeMu held.
+func (s *St) discardTail(offset int64) error {
+	err := s.file.Truncate(offset)
+	if err != nil {
+		err = fmt.Errorf("truncate torn record at offset %d: %w", offset, err)
+	}
+
+	err = errors.Join(err, s.file.Close())
+	s.file = nil
+
+	return err
+}
+
+// compactIfNeeded rewrites the log file once enough events have been appended
+// since the last compaction. It must be called with writeMu held.
+func (s *St) compactIfNeeded() {
+	if s.pending < compactThreshold || s.pending < 2*s.size {
+		return
+	}
+
+	if err := s.compact(); err != nil {
+		log.Println("error compact log file:", err)
+	}
+}
+
+// Compact replaces the log file with a snapshot holding one event per stored URL
+// and the sequence reservation.
+// The snapshot is written to a temporary file first and renamed over the log,
+// so a crash during compaction leaves the previous log intact.
+func (s *St) Compact() error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	return s.compact()
+}
+
+// compact writes the snapshot. It must be called with writeMu held.
+func (s *St) compact() error {
+	if s.filePath == "" {
+		return nil
+	}
+
+	tmpPath := s.filePath + ".tmp"
+
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+
+	if s.reserved > 0 {
+		event := &Event{Action: actionSequence, NumberUUID: strconv.FormatInt(s.reserved, 10)}
+		if err = encoder.Encode(event); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	for _, record := range s.sortedRecords(func(*entities.Storage) bool { return true }) {
+		event := newAddEvent(&record)
+		if err = encoder.Encode(event); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	if err = writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err = file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err = file.Close(); err != nil {
+		return err
+	}
+
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+
+	if err = os.Rename(tmpPath, s.filePath); err != nil {
+		return err
+	}
+
+	s.pending = s.size
+
+	return nil
+}
+
+// newAddEvent creates an event that stores the record as is.
+func newAddEvent(record *entities.Storage) *Event {
+	return &Event{
+		Action:       actionAdd,
+		NumberUUID:   record.UUID,
+		ShortURL:     record.ShortURL,
+		OriginalURL:  record.OriginalURL,
+		UserID:       record.UserID,
+		DeletedFlag:  record.DeletedFlag,
+		DisabledFlag: record.DisabledFlag,
+		ExpiresAt:    record.ExpiresAt,
+	}
+}
+
+// appendArchive durably appends the records to the archive file next to the log file.
+// It must be called with writeMu held.
+func (s *St) appendArchive(records []entities.Storage) error {
+	if s.filePath == "" || len(records) == 0 {
+		return nil
+	}
+
+	file, err := os.OpenFile(s.filePath+archiveSuffix, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+
+	for _, record := range records {
+		if err = encoder.Encode(newAddEvent(&record)); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	if _, err = file.Write(buf.Bytes()); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err = file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
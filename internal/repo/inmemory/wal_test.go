@@ -0,0 +1,335 @@
+package inmemory
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+)
+
+func TestSt_Replay(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "short-url-repo.json")
+
+	s, err := New(filePath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if err = s.Add(context.Background(), "first.com", "first", "1", nil); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	urls := []*entities.ReqURL{
+		{OriginalURL: "second.com", ShortURL: "second"},
+		{OriginalURL: "third.com", ShortURL: "third"},
+	}
+	if err = s.CreateShortURLs(context.Background(), urls, "1"); err != nil {
+		t.Fatalf("CreateShortURLs() error = %v", err)
+	}
+
+	if err = s.Update(context.Background(), "third.com", "updated"); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	if err = s.DeleteURLs(context.Background(), []string{"second"}, "1"); err != nil {
+		t.Fatalf("DeleteURLs() error = %v", err)
+	}
+
+	restored, err := New(filePath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	want, _ := s.ListAll(context.Background(), "1")
+	got, err := restored.ListAll(context.Background(), "1")
+	if err != nil {
+		t.Fatalf("ListAll() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListAll() got = %v, want %v", got, want)
+	}
+
+	if !restored.URLDeleted(context.Background(), "second") {
+		t.Errorf("URLDeleted() = false, want true")
+	}
+
+	if shortURL, _ := restored.GetByOriginalURL(context.Background(), "third.com"); shortURL != "updated" {
+		t.Errorf("GetByOriginalURL() got = %v, want %v", shortURL, "updated")
+	}
+
+	if err = restored.Add(context.Background(), "fourth.com", "fourth", "1", nil); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	got, _ = restored.ListAll(context.Background(), "1")
+	if last := got[len(got)-1]; last.ShortURL != "fourth" {
+		t.Errorf("ListAll() last got = %v, want %v", last.ShortURL, "fourth")
+	}
+}
+
+func TestSt_ReplayTornRecord(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "short-url-repo.json")
+
+	s, err := New(filePath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if err = s.Add(context.Background(), "first.com", "first", "1", nil); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	if _, err = file.WriteString(`{"action":"add","uuid":"2","short_url":"sec`); err != nil {
+		t.Fatalf("WriteString() error = %v", err)
+	}
+	file.Close()
+
+	restored, err := New(filePath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if _, exist := restored.GetByShortURL(context.Background(), "first"); !exist {
+		t.Errorf("GetByShortURL() lost a complete record")
+	}
+
+	if err = restored.Add(context.Background(), "second.com", "second", "1", nil); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	restored, err = New(filePath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if _, exist := restored.GetByShortURL(context.Background(), "second"); !exist {
+		t.Errorf("GetByShortURL() lost a record written after the torn one")
+	}
+}
+
+func TestSt_ReplayCorruptedRecord(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "short-url-repo.json")
+
+	data := "not a json\n" + `{"action":"add","uuid":"1","short_url":"first","original_url":"first.com"}` + "\n"
+	if err := os.WriteFile(filePath, []byte(data), 0666); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	if _, err := New(filePath); err == nil {
+		t.Errorf("New() error = nil, want error")
+	}
+}
+
+func TestSt_AppendShortWrite(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "short-url-repo.json")
+
+	s, err := New(filePath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if err = s.Add(context.Background(), "first.com", "first", "1", nil); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	writeLog = func(file *os.File, data []byte) (int, error) {
+		n, _ := file.Write(data[:len(data)/2])
+		return n, syscall.ENOSPC
+	}
+	t.Cleanup(func() { writeLog = (*os.File).Write })
+
+	if err = s.Add(context.Background(), "second.com", "second", "1", nil); !errors.Is(err, syscall.ENOSPC) {
+		t.Fatalf("Add() error = %v, want %v", err, syscall.ENOSPC)
+	}
+
+	writeLog = (*os.File).Write
+
+	if err = s.Add(context.Background(), "third.com", "third", "1", nil); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	restored, err := New(filePath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for _, shortURL := range []string{"first", "third"} {
+		if _, exist := restored.GetByShortURL(context.Background(), shortURL); !exist {
+			t.Errorf("GetByShortURL(%q) lost a record", shortURL)
+		}
+	}
+	if _, exist := restored.GetByShortURL(context.Background(), "second"); exist {
+		t.Errorf("GetByShortURL() restored a record whose write failed")
+	}
+}
+
+func TestSt_Compact(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "short-url-repo.json")
+
+	s, err := New(filePath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if err = s.Add(context.Background(), "first.com", "first", "1", nil); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err = s.Add(context.Background(), "second.com", "second", "1", nil); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err = s.DeleteURLs(context.Background(), []string{"first"}, "1"); err != nil {
+		t.Fatalf("DeleteURLs() error = %v", err)
+	}
+
+	if got := countLines(t, filePath); got != 3 {
+		t.Errorf("log lines before Compact() = %v, want %v", got, 3)
+	}
+
+	if err = s.Compact(); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+
+	if got := countLines(t, filePath); got != 2 {
+		t.Errorf("log lines after Compact() = %v, want %v", got, 2)
+	}
+
+	if err = s.Add(context.Background(), "third.com", "third", "1", nil); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	restored, err := New(filePath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if !restored.URLDeleted(context.Background(), "first") {
+		t.Errorf("URLDeleted() = false, want true")
+	}
+	if _, exist := restored.GetByShortURL(context.Background(), "third"); !exist {
+		t.Errorf("GetByShortURL() lost a record written after compaction")
+	}
+}
+
+func TestSt_NextSequence(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "short-url-repo.json")
+
+	s, err := New(filePath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	var last int64
+	for i := 0; i < sequenceBlock+1; i++ {
+		value, err := s.NextSequence(context.Background())
+		if err != nil {
+			t.Fatalf("NextSequence() error = %v", err)
+		}
+		if value <= last {
+			t.Fatalf("NextSequence() = %v, want greater than %v", value, last)
+		}
+		last = value
+	}
+
+	if err = s.Compact(); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+
+	restored, err := New(filePath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	value, err := restored.NextSequence(context.Background())
+	if err != nil {
+		t.Fatalf("NextSequence() error = %v", err)
+	}
+	if value <= last {
+		t.Errorf("NextSequence() after restart = %v, want greater than %v", value, last)
+	}
+}
+
+func TestSt_DeleteExpired(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "short-url-repo.json")
+
+	s, err := New(filePath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	expired := time.Now().Add(-time.Hour)
+	later := time.Now().Add(time.Hour)
+
+	if err = s.Add(context.Background(), "expired.com", "expired", "1", &expired); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err = s.Add(context.Background(), "later.com", "later", "1", &later); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	if !s.URLExpired(context.Background(), "expired") {
+		t.Errorf("URLExpired() = false, want true")
+	}
+	if s.URLExpired(context.Background(), "later") {
+		t.Errorf("URLExpired() = true, want false")
+	}
+
+	count, err := s.DeleteExpired(context.Background(), time.Now(), true)
+	if err != nil {
+		t.Fatalf("DeleteExpired() error = %v", err)
+	}
+	if count != 1 {
+		t.Errorf("DeleteExpired() = %v, want %v", count, 1)
+	}
+
+	if got := countLines(t, filePath+archiveSuffix); got != 1 {
+		t.Errorf("archive lines = %v, want %v", got, 1)
+	}
+
+	restored, err := New(filePath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if _, exist := restored.GetByShortURL(context.Background(), "expired"); exist {
+		t.Errorf("GetByShortURL() found a purged record")
+	}
+	if _, exist := restored.GetByOriginalURL(context.Background(), "expired.com"); exist {
+		t.Errorf("GetByOriginalURL() found a purged record")
+	}
+	if _, exist := restored.GetByShortURL(context.Background(), "later"); !exist {
+		t.Errorf("GetByShortURL() lost a record which has not expired")
+	}
+
+	if err = restored.Add(context.Background(), "expired.com", "expired", "1", nil); err != nil {
+		t.Errorf("Add() of a purged link error = %v", err)
+	}
+}
+
+func countLines(t *testing.T, filePath string) int {
+	t.Helper()
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer file.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines++
+	}
+
+	return lines
+}
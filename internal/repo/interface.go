@@ -10,6 +10,9 @@
 package repo
 
 import (
+	"context"
+	"time"
+
 	_ "github.com/jackc/pgx/v5"
 
 	"github.com/Azzonya/go-shortener/internal/entities"
@@ -17,38 +20,129 @@ import (
 )
 
 // Repo represents the repository interface for managing URLs and events.
+// Methods taking a context stop waiting for the storage once the context is done.
 type Repo interface {
 	// Initialize initializes the repository.
-	Initialize() error
+	Initialize(ctx context.Context) error
 
 	// TableExist checks if the necessary tables exist in the database.
-	TableExist() bool
+	TableExist(ctx context.Context) bool
 
 	// Add adds a new URL entry to the repository.
-	Add(originalURL, shortURL, userID string) error
+	// The link expires at expiresAt, a nil expiresAt means it never expires.
+	Add(ctx context.Context, originalURL, shortURL, userID string, expiresAt *time.Time) error
 
 	// CreateShortURLs creates multiple short URLs in the repository for the given user.
-	CreateShortURLs(urls []*entities.ReqURL, userID string) error
+	CreateShortURLs(ctx context.Context, urls []*entities.ReqURL, userID string) error
 
 	// Update updates the short URL for the given original URL.
-	Update(originalURL, shortURL string) error
+	Update(ctx context.Context, originalURL, shortURL string) error
 
 	// GetByShortURL retrieves the original URL associated with the given short URL.
 	// It returns the original URL and a boolean indicating whether the URL exists.
-	GetByShortURL(shortURL string) (string, bool)
+	GetByShortURL(ctx context.Context, shortURL string) (string, bool)
+
+	// GetLink returns the whole stored state of the short URL in a single lookup,
+	// including deleted and expired links.
+	// It returns entities.ErrURLNotFound if the short URL does not exist.
+	GetLink(ctx context.Context, shortURL string) (*entities.Link, error)
 
 	// GetByOriginalURL retrieves the short URL associated with the given original URL.
 	// It returns the short URL and a boolean indicating whether the URL exists.
-	GetByOriginalURL(originalURL string) (string, bool)
+	GetByOriginalURL(ctx context.Context, originalURL string) (string, bool)
 
 	// ListAll retrieves all short URLs associated with the given user.
-	ListAll(userID string) ([]*entities.ReqListAll, error)
+	ListAll(ctx context.Context, userID string) ([]*entities.ReqListAll, error)
 
 	// DeleteURLs deletes multiple URLs associated with the given user.
-	DeleteURLs(urls []string, userID string) error
+	DeleteURLs(ctx context.Context, urls []string, userID string) error
+
+	// DeleteURLsBatch deletes the URLs of several deletion requests at once.
+	// URLs not owned by the user of their request are left untouched.
+	DeleteURLsBatch(ctx context.Context, requests []*entities.DeleteRequest) error
 
 	// URLDeleted checks if the URL with the given short URL is deleted.
-	URLDeleted(shortURL string) bool
+	URLDeleted(ctx context.Context, shortURL string) bool
+
+	// Stats returns the total number of stored URLs and of distinct users owning them.
+	Stats(ctx context.Context) (*entities.ServiceStats, error)
+
+	// AddClicks stores the click events.
+	AddClicks(ctx context.Context, clicks []*entities.Click) error
+
+	// ClickStats returns the click statistics of the short URL owned by the user,
+	// with the histogram split into buckets of the given duration.
+	// It returns entities.ErrURLNotFound if the user has no such URL.
+	ClickStats(ctx context.Context, shortURL, userID string, bucket time.Duration) (*entities.LinkStats, error)
+
+	// AddAPIKey stores a new API key.
+	AddAPIKey(ctx context.Context, key *entities.APIKey) error
+
+	// GetAPIKey returns the API key with the given ID, including revoked keys.
+	// It returns entities.ErrAPIKeyNotFound if the key does not exist.
+	GetAPIKey(ctx context.Context, id string) (*entities.APIKey, error)
+
+	// ListAPIKeys returns the API keys of the user ordered by creation time, including revoked keys.
+	ListAPIKeys(ctx context.Context, userID string) ([]*entities.APIKey, error)
+
+	// RevokeAPIKey revokes the user's API key at the given time. Revoking a revoked key keeps its revocation time.
+	// It returns entities.ErrAPIKeyNotFound if the user has no such key.
+	RevokeAPIKey(ctx context.Context, id, userID string, revokedAt time.Time) error
+
+	// AddAccount stores a new user account.
+	// It returns entities.ErrLoginExists if the login is already taken.
+	AddAccount(ctx context.Context, account *entities.Account) error
+
+	// GetAccount returns the user account with the given ID.
+	// It returns entities.ErrAccountNotFound if the account does not exist.
+	GetAccount(ctx context.Context, id string) (*entities.Account, error)
+
+	// GetAccountByLogin returns the user account with the given login.
+	// It returns entities.ErrAccountNotFound if the account does not exist.
+	GetAccountByLogin(ctx context.Context, login string) (*entities.Account, error)
+
+	// TransferURLs makes the user toUserID the owner of every URL of the user fromUserID
+	// and returns the number of transferred URLs.
+	TransferURLs(ctx context.Context, fromUserID, toUserID string) (int64, error)
+
+	// SearchLinks returns the links of every user matching the filter in the order they were added,
+	// including deleted, disabled and expired links.
+	SearchLinks(ctx context.Context, filter *entities.LinkFilter) ([]*entities.Link, error)
+
+	// SetURLDisabled disables or enables again the short URL, whoever owns it.
+	// It returns entities.ErrURLNotFound if the short URL does not exist.
+	SetURLDisabled(ctx context.Context, shortURL string, disabled bool) error
+
+	// ReassignURL makes the user the owner of the short URL.
+	// It returns entities.ErrURLNotFound if the short URL does not exist.
+	ReassignURL(ctx context.Context, shortURL, userID string) error
+
+	// BanUser bans the user, replacing the previous ban of the user.
+	BanUser(ctx context.Context, ban *entities.Ban) error
+
+	// UnbanUser lifts the ban of the user.
+	// It returns entities.ErrBanNotFound if the user is not banned.
+	UnbanUser(ctx context.Context, userID string) error
+
+	// GetBan returns the ban of the user.
+	// It returns entities.ErrBanNotFound if the user is not banned.
+	GetBan(ctx context.Context, userID string) (*entities.Ban, error)
+
+	// AddAuditEntry appends the entry to the audit trail, setting its ID.
+	AddAuditEntry(ctx context.Context, entry *entities.AuditEntry) error
+
+	// ListAuditEntries returns up to limit entries of the audit trail, the most recent first.
+	ListAuditEntries(ctx context.Context, limit int) ([]*entities.AuditEntry, error)
+
+	// NextSequence returns the next value of the sequence backing the sequential short code generators.
+	NextSequence(ctx context.Context) (int64, error)
+
+	// URLExpired checks if the URL with the given short URL has expired.
+	URLExpired(ctx context.Context, shortURL string) bool
+
+	// DeleteExpired removes the URLs which expired before the given time and returns their number.
+	// With archive set, the removed URLs are kept in the archive of the storage.
+	DeleteExpired(ctx context.Context, before time.Time, archive bool) (int64, error)
 
 	// WriteEvent writes an event to the storage.
 	WriteEvent(event *inmemory.Event) error
@@ -57,5 +151,5 @@ type Repo interface {
 	SyncData()
 
 	// Ping pings the database to check its availability.
-	Ping() error
+	Ping(ctx context.Context) error
 }
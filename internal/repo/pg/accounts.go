@@ -0,0 +1,63 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+)
+
+// accountColumns lists the columns of the users table in the order scanned by scanAccount.
+const accountColumns = `id, login, password_hash, created_at`
+
+// AddAccount stores a new user account.
+// It returns entities.ErrLoginExists if the login is already taken.
+func (s *St) AddAccount(ctx context.Context, account *entities.Account) error {
+	_, err := s.db.Exec(ctx,
+		`INSERT INTO users (`+accountColumns+`) VALUES ($1, $2, $3, $4)`,
+		account.ID, account.Login, account.PasswordHash, account.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("insert account error: %w", convertUniqueErr(err))
+	}
+
+	return nil
+}
+
+// GetAccount returns the user account with the given ID.
+func (s *St) GetAccount(ctx context.Context, id string) (*entities.Account, error) {
+	return scanAccount(s.db.QueryRow(ctx, `SELECT `+accountColumns+` FROM users WHERE id = $1`, id))
+}
+
+// GetAccountByLogin returns the user account with the given login.
+func (s *St) GetAccountByLogin(ctx context.Context, login string) (*entities.Account, error) {
+	return scanAccount(s.db.QueryRow(ctx, `SELECT `+accountColumns+` FROM users WHERE login = $1`, login))
+}
+
+// TransferURLs makes the user toUserID the owner of every URL of the user fromUserID.
+func (s *St) TransferURLs(ctx context.Context, fromUserID, toUserID string) (int64, error) {
+	tag, err := s.db.Exec(ctx, `UPDATE urls SET userID = $2 WHERE userID = $1`, fromUserID, toUserID)
+	if err != nil {
+		return 0, fmt.Errorf("transfer URLs error: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// scanAccount scans a row of accountColumns into a user account.
+// It returns entities.ErrAccountNotFound if there is no row.
+func scanAccount(row pgx.Row) (*entities.Account, error) {
+	var account entities.Account
+
+	err := row.Scan(&account.ID, &account.Login, &account.PasswordHash, &account.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, entities.ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &account, nil
+}
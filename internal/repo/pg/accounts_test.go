@@ -0,0 +1,53 @@
+package pg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+	"github.com/Azzonya/go-shortener/pkg"
+)
+
+func TestSt_Accounts(t *testing.T) {
+	dsn := getPgDsnTestContainer()
+
+	db, err := pkg.InitDatabasePg(dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	s := &St{
+		db: db,
+	}
+
+	if err = s.Initialize(context.Background()); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+
+	ctx := context.Background()
+	account := &entities.Account{ID: "pgacc", Login: "pgalice", PasswordHash: "hash", CreatedAt: time.Now().UTC().Truncate(time.Microsecond)}
+	require.NoError(t, s.AddAccount(ctx, account))
+	assert.ErrorIs(t, s.AddAccount(ctx, &entities.Account{ID: "pgother", Login: "pgalice", CreatedAt: time.Now()}), entities.ErrLoginExists)
+
+	got, err := s.GetAccountByLogin(ctx, "pgalice")
+	require.NoError(t, err)
+	assert.Equal(t, account.ID, got.ID)
+	assert.Equal(t, "hash", got.PasswordHash)
+
+	_, err = s.GetAccount(ctx, "pgacc-missing")
+	assert.ErrorIs(t, err, entities.ErrAccountNotFound)
+
+	require.NoError(t, s.Add(ctx, "https://transfer.com", "transfer", "pganonymous", nil))
+
+	n, err := s.TransferURLs(ctx, "pganonymous", "pgacc")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	link, err := s.GetLink(ctx, "transfer")
+	require.NoError(t, err)
+	assert.Equal(t, "pgacc", link.UserID)
+}
@@ -0,0 +1,149 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+)
+
+// SearchLinks returns the links of every user matching the filter in the order they were added.
+// The query is matched literally, LIKE wildcards in it are escaped.
+func (s *St) SearchLinks(ctx context.Context, filter *entities.LinkFilter) ([]*entities.Link, error) {
+	query := `SELECT shortURL, originalURL, COALESCE(userID, ''), COALESCE(deleted, false), disabled, expires_at
+				FROM urls
+				WHERE ($1 = '' OR userID = $1)
+				  AND ($2 = '' OR shortURL LIKE '%' || $2 || '%' OR originalURL LIKE '%' || $2 || '%')
+				ORDER BY id
+				LIMIT $3 OFFSET $4`
+
+	rows, err := s.db.Query(ctx, query, filter.UserID, escapeLike(filter.Query), filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("search links error: %w", err)
+	}
+	defer rows.Close()
+
+	links := make([]*entities.Link, 0)
+	for rows.Next() {
+		var link entities.Link
+		if err = rows.Scan(&link.ShortURL, &link.OriginalURL, &link.UserID, &link.Deleted, &link.Disabled, &link.ExpiresAt); err != nil {
+			return nil, err
+		}
+		links = append(links, &link)
+	}
+
+	return links, rows.Err()
+}
+
+// SetURLDisabled disables or enables again the short URL, whoever owns it.
+func (s *St) SetURLDisabled(ctx context.Context, shortURL string, disabled bool) error {
+	tag, err := s.db.Exec(ctx, `UPDATE urls SET disabled = $2, updated_at = now() WHERE shortURL = $1`, shortURL, disabled)
+	if err != nil {
+		return fmt.Errorf("set url disabled error: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return entities.ErrURLNotFound
+	}
+
+	return nil
+}
+
+// ReassignURL makes the user the owner of the short URL.
+func (s *St) ReassignURL(ctx context.Context, shortURL, userID string) error {
+	tag, err := s.db.Exec(ctx, `UPDATE urls SET userID = $2, updated_at = now() WHERE shortURL = $1`, shortURL, userID)
+	if err != nil {
+		return fmt.Errorf("reassign url error: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return entities.ErrURLNotFound
+	}
+
+	return nil
+}
+
+// BanUser bans the user, replacing the previous ban of the user.
+func (s *St) BanUser(ctx context.Context, ban *entities.Ban) error {
+	_, err := s.db.Exec(ctx,
+		`INSERT INTO bans (user_id, reason, banned_by, created_at) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id) DO UPDATE SET reason = EXCLUDED.reason, banned_by = EXCLUDED.banned_by, created_at = EXCLUDED.created_at`,
+		ban.UserID, ban.Reason, ban.BannedBy, ban.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("insert ban error: %w", err)
+	}
+
+	return nil
+}
+
+// UnbanUser lifts the ban of the user.
+func (s *St) UnbanUser(ctx context.Context, userID string) error {
+	tag, err := s.db.Exec(ctx, `DELETE FROM bans WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("delete ban error: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return entities.ErrBanNotFound
+	}
+
+	return nil
+}
+
+// GetBan returns the ban of the user.
+func (s *St) GetBan(ctx context.Context, userID string) (*entities.Ban, error) {
+	var ban entities.Ban
+
+	err := s.db.QueryRow(ctx, `SELECT user_id, reason, banned_by, created_at FROM bans WHERE user_id = $1`, userID).
+		Scan(&ban.UserID, &ban.Reason, &ban.BannedBy, &ban.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, entities.ErrBanNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &ban, nil
+}
+
+// AddAuditEntry appends the entry to the audit trail, setting its ID.
+func (s *St) AddAuditEntry(ctx context.Context, entry *entities.AuditEntry) error {
+	err := s.db.QueryRow(ctx,
+		`INSERT INTO audit_log (actor, action, target, details, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		entry.Actor, entry.Action, entry.Target, entry.Details, entry.CreatedAt).Scan(&entry.ID)
+	if err != nil {
+		return fmt.Errorf("insert audit entry error: %w", err)
+	}
+
+	return nil
+}
+
+// ListAuditEntries returns up to limit entries of the audit trail, the most recent first.
+func (s *St) ListAuditEntries(ctx context.Context, limit int) ([]*entities.AuditEntry, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT id, actor, action, target, details, created_at FROM audit_log ORDER BY id DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list audit entries error: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*entities.AuditEntry, 0)
+	for rows.Next() {
+		var entry entities.AuditEntry
+		if err = rows.Scan(&entry.ID, &entry.Actor, &entry.Action, &entry.Target, &entry.Details, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// likeEscaper escapes the LIKE wildcards and the default escape character.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike returns the string matched literally by a LIKE pattern.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
@@ -0,0 +1,72 @@
+package pg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+	"github.com/Azzonya/go-shortener/pkg"
+)
+
+func TestSt_Moderation(t *testing.T) {
+	dsn := getPgDsnTestContainer()
+
+	db, err := pkg.InitDatabasePg(dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	s := &St{
+		db: db,
+	}
+
+	if err = s.Initialize(context.Background()); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+
+	ctx := context.Background()
+	require.NoError(t, s.Add(ctx, "https://moderated.example/100%_spam", "pgspam", "pgspammer", nil))
+
+	require.NoError(t, s.SetURLDisabled(ctx, "pgspam", true))
+	require.NoError(t, s.ReassignURL(ctx, "pgspam", "pgmoderators"))
+	assert.ErrorIs(t, s.SetURLDisabled(ctx, "pgspam-missing", true), entities.ErrURLNotFound)
+
+	link, err := s.GetLink(ctx, "pgspam")
+	require.NoError(t, err)
+	assert.True(t, link.Disabled)
+	assert.Equal(t, "pgmoderators", link.UserID)
+
+	links, err := s.SearchLinks(ctx, &entities.LinkFilter{Query: "100%_spam", Limit: 10})
+	require.NoError(t, err)
+	if assert.Len(t, links, 1) {
+		assert.Equal(t, "pgspam", links[0].ShortURL)
+	}
+	links, err = s.SearchLinks(ctx, &entities.LinkFilter{Query: "100%_spaX", Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, links, "wildcards in the query must be matched literally")
+
+	ban := &entities.Ban{UserID: "pgspammer", Reason: "spam", BannedBy: "pgadmin", CreatedAt: time.Now().UTC().Truncate(time.Microsecond)}
+	require.NoError(t, s.BanUser(ctx, ban))
+	require.NoError(t, s.BanUser(ctx, ban), "banning again replaces the ban")
+
+	got, err := s.GetBan(ctx, "pgspammer")
+	require.NoError(t, err)
+	assert.Equal(t, ban, got)
+
+	require.NoError(t, s.UnbanUser(ctx, "pgspammer"))
+	assert.ErrorIs(t, s.UnbanUser(ctx, "pgspammer"), entities.ErrBanNotFound)
+
+	entry := &entities.AuditEntry{Actor: "pgadmin", Action: entities.AuditDisableURL, Target: "pgspam", CreatedAt: time.Now().UTC()}
+	require.NoError(t, s.AddAuditEntry(ctx, entry))
+	assert.NotZero(t, entry.ID)
+
+	entries, err := s.ListAuditEntries(ctx, 1)
+	require.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, entry.ID, entries[0].ID)
+	}
+}
@@ -0,0 +1,87 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+)
+
+// apiKeyColumns lists the columns of the api_keys table in the order scanned by scanAPIKey.
+const apiKeyColumns = `id, user_id, name, hash, scopes, created_at, revoked_at`
+
+// AddAPIKey stores a new API key.
+func (s *St) AddAPIKey(ctx context.Context, key *entities.APIKey) error {
+	_, err := s.db.Exec(ctx,
+		`INSERT INTO api_keys (`+apiKeyColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		key.ID, key.UserID, key.Name, key.Hash, key.Scopes, key.CreatedAt, key.RevokedAt)
+	if err != nil {
+		return fmt.Errorf("insert API key error: %w", err)
+	}
+
+	return nil
+}
+
+// GetAPIKey returns the API key with the given ID.
+func (s *St) GetAPIKey(ctx context.Context, id string) (*entities.APIKey, error) {
+	key, err := scanAPIKey(s.db.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, entities.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// ListAPIKeys returns the API keys of the user ordered by creation time.
+func (s *St) ListAPIKeys(ctx context.Context, userID string) ([]*entities.APIKey, error) {
+	rows, err := s.db.Query(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE user_id = $1 ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*entities.APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, key)
+	}
+
+	return result, rows.Err()
+}
+
+// RevokeAPIKey revokes the user's API key at the given time.
+func (s *St) RevokeAPIKey(ctx context.Context, id, userID string, revokedAt time.Time) error {
+	tag, err := s.db.Exec(ctx,
+		`UPDATE api_keys SET revoked_at = COALESCE(revoked_at, $3) WHERE id = $1 AND user_id = $2`,
+		id, userID, revokedAt)
+	if err != nil {
+		return fmt.Errorf("revoke API key error: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return entities.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// scanAPIKey scans a row of apiKeyColumns into an API key.
+func scanAPIKey(row pgx.Row) (*entities.APIKey, error) {
+	var key entities.APIKey
+
+	err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Hash, &key.Scopes, &key.CreatedAt, &key.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
@@ -0,0 +1,62 @@
+package pg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+	"github.com/Azzonya/go-shortener/pkg"
+)
+
+func TestSt_APIKeys(t *testing.T) {
+	dsn := getPgDsnTestContainer()
+
+	db, err := pkg.InitDatabasePg(dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	s := &St{
+		db: db,
+	}
+
+	if err = s.Initialize(context.Background()); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+
+	ctx := context.Background()
+	createdAt := time.Now().UTC().Truncate(time.Microsecond)
+	key := &entities.APIKey{
+		ID:        "pgkey",
+		UserID:    "1",
+		Name:      "ci",
+		Hash:      "hash",
+		Scopes:    []string{entities.ScopeShorten, entities.ScopeRead},
+		CreatedAt: createdAt,
+	}
+	require.NoError(t, s.AddAPIKey(ctx, key))
+
+	got, err := s.GetAPIKey(ctx, "pgkey")
+	require.NoError(t, err)
+	assert.Equal(t, key.Scopes, got.Scopes)
+	assert.Equal(t, "hash", got.Hash)
+	assert.True(t, createdAt.Equal(got.CreatedAt))
+	assert.False(t, got.Revoked())
+
+	_, err = s.GetAPIKey(ctx, "pgkey-missing")
+	assert.ErrorIs(t, err, entities.ErrAPIKeyNotFound)
+
+	assert.ErrorIs(t, s.RevokeAPIKey(ctx, "pgkey", "2", createdAt), entities.ErrAPIKeyNotFound)
+	require.NoError(t, s.RevokeAPIKey(ctx, "pgkey", "1", createdAt.Add(time.Hour)))
+	require.NoError(t, s.RevokeAPIKey(ctx, "pgkey", "1", createdAt.Add(2*time.Hour)))
+
+	keys, err := s.ListAPIKeys(ctx, "1")
+	require.NoError(t, err)
+	if assert.Len(t, keys, 1) && assert.NotNil(t, keys[0].RevokedAt) {
+		assert.True(t, createdAt.Add(time.Hour).Equal(*keys[0].RevokedAt))
+	}
+}
@@ -0,0 +1,269 @@
+package pg
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"github.com/Azzonya/go-shortener/internal/logger"
+)
+
+// migrationsLockID is the key of the advisory lock taken while migrating,
+// so that instances starting at the same time do not apply migrations concurrently.
+const migrationsLockID = 7218390451
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// migrationFileRe matches migration file names like 0001_create_urls.up.sql.
+var migrationFileRe = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration represents a single versioned schema change.
+type Migration struct {
+	Version int64  // Version orders the migrations, it is the numeric prefix of the file name.
+	Name    string // Name describes the migration.
+	Up      string // Up is the SQL applying the migration.
+	Down    string // Down is the SQL reverting the migration.
+}
+
+// MigrationStatus represents the state of a migration in the database.
+type MigrationStatus struct {
+	Version   int64      // Version of the migration.
+	Name      string     // Name of the migration.
+	AppliedAt *time.Time // AppliedAt is the time the migration was applied, nil if it is pending.
+}
+
+// Migrator applies the embedded schema migrations to the PostgreSQL database.
+type Migrator struct {
+	db         *pgxpool.Pool // PostgreSQL connection pool.
+	migrations []*Migration  // Migrations ordered by version.
+}
+
+// NewMigrator creates a new migrator for the embedded migrations.
+func NewMigrator(db *pgxpool.Pool) (*Migrator, error) {
+	migrations, err := loadMigrations(migrationsFS)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// Up applies all pending migrations in version order.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			err = runInTx(ctx, conn, migration.Up,
+				`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, migration.Version, migration.Name)
+			if err != nil {
+				return fmt.Errorf("apply migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			logger.Log.Info("Applied migration", zap.Int64("version", migration.Version), zap.String("name", migration.Name))
+		}
+
+		return nil
+	})
+}
+
+// Down reverts the given number of the most recently applied migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+
+			err = runInTx(ctx, conn, migration.Down,
+				`DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+			if err != nil {
+				return fmt.Errorf("revert migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			logger.Log.Info("Reverted migration", zap.Int64("version", migration.Version), zap.String("name", migration.Name))
+			steps--
+		}
+
+		return nil
+	})
+}
+
+// Status returns the state of every known migration.
+func (m *Migrator) Status(ctx context.Context) ([]*MigrationStatus, error) {
+	var result []*MigrationStatus
+
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			status := &MigrationStatus{
+				Version: migration.Version,
+				Name:    migration.Name,
+			}
+			if appliedAt, ok := applied[migration.Version]; ok {
+				status.AppliedAt = &appliedAt
+			}
+			result = append(result, status)
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// withLock runs fn on a dedicated connection holding the migrations advisory lock.
+// The schema_migrations table is created beforehand if it does not exist.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err = conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationsLockID); err != nil {
+		return fmt.Errorf("acquire migrations lock: %w", err)
+	}
+
+	defer func() {
+		if _, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationsLockID); err != nil {
+			logger.Log.Error("failed to release migrations lock", zap.Error(err))
+		}
+	}()
+
+	query := `CREATE TABLE IF NOT EXISTS schema_migrations (
+				version BIGINT PRIMARY KEY,
+				name TEXT NOT NULL,
+				applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+				)`
+
+	if _, err = conn.Exec(ctx, query); err != nil {
+		return fmt.Errorf("schema_migrations table creating error: %w", err)
+	}
+
+	return fn(conn)
+}
+
+// appliedVersions returns the application time of every applied migration keyed by version.
+func appliedVersions(ctx context.Context, conn *pgxpool.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+
+		if err = rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// runInTx executes the migration SQL and the bookkeeping query in a single transaction.
+func runInTx(ctx context.Context, conn *pgxpool.Conn, migrationSQL, query string, args ...any) error {
+	return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, migrationSQL); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(ctx, query, args...)
+		return err
+	})
+}
+
+// loadMigrations reads the migrations from the file system and orders them by version.
+// Every version must have both an up and a down file.
+func loadMigrations(fsys fs.FS) ([]*Migration, error) {
+	files, err := fs.Glob(fsys, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+
+	for _, file := range files {
+		name := file[len("migrations/"):]
+
+		match := migrationFileRe.FindStringSubmatch(name)
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", name)
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %q: %w", name, err)
+		}
+
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has different names %q and %q", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down files", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
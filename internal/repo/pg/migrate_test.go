@@ -0,0 +1,79 @@
+package pg
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations_Embedded(t *testing.T) {
+	migrations, err := loadMigrations(migrationsFS)
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, migration := range migrations {
+		assert.Equal(t, int64(i+1), migration.Version, "migrations must be numbered without gaps")
+		assert.NotEmpty(t, migration.Up)
+		assert.NotEmpty(t, migration.Down)
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	tests := []struct {
+		name      string
+		fsys      fstest.MapFS
+		wantNames []string
+		wantErr   bool
+	}{
+		{
+			name: "ordered by version",
+			fsys: fstest.MapFS{
+				"migrations/0010_second.up.sql":   {Data: []byte("SELECT 2")},
+				"migrations/0010_second.down.sql": {Data: []byte("SELECT 2")},
+				"migrations/0002_first.up.sql":    {Data: []byte("SELECT 1")},
+				"migrations/0002_first.down.sql":  {Data: []byte("SELECT 1")},
+			},
+			wantNames: []string{"first", "second"},
+		},
+		{
+			name: "missing down file",
+			fsys: fstest.MapFS{
+				"migrations/0001_first.up.sql": {Data: []byte("SELECT 1")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid file name",
+			fsys: fstest.MapFS{
+				"migrations/first.sql": {Data: []byte("SELECT 1")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "different names for one version",
+			fsys: fstest.MapFS{
+				"migrations/0001_first.up.sql":   {Data: []byte("SELECT 1")},
+				"migrations/0001_other.down.sql": {Data: []byte("SELECT 1")},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := loadMigrations(tt.fsys)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			names := make([]string, 0, len(migrations))
+			for _, migration := range migrations {
+				names = append(names, migration.Name)
+			}
+			assert.Equal(t, tt.wantNames, names)
+		})
+	}
+}
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS urls (
+    id SERIAL PRIMARY KEY,
+    originalURL VARCHAR(255) NOT NULL,
+    shortURL VARCHAR(255) UNIQUE NOT NULL,
+    userID VARCHAR(255) NOT NULL,
+    deleted BOOLEAN DEFAULT false
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_original_url ON urls (originalURL);
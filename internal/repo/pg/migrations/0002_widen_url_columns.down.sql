@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_original_url;
+
+ALTER TABLE urls
+    ALTER COLUMN originalURL TYPE VARCHAR(255),
+    ALTER COLUMN shortURL TYPE VARCHAR(255),
+    ALTER COLUMN userID TYPE VARCHAR(255);
+
+CREATE UNIQUE INDEX idx_original_url ON urls (originalURL);
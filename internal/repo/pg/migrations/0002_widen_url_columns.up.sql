@@ -0,0 +1,9 @@
+ALTER TABLE urls
+    ALTER COLUMN originalURL TYPE TEXT,
+    ALTER COLUMN shortURL TYPE TEXT,
+    ALTER COLUMN userID TYPE TEXT;
+
+-- A btree index can not hold arbitrarily long values, so uniqueness of
+-- original URLs is enforced on their hash instead.
+DROP INDEX IF EXISTS idx_original_url;
+CREATE UNIQUE INDEX idx_original_url ON urls (md5(originalURL));
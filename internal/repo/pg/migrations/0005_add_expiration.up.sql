@@ -0,0 +1,15 @@
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_urls_expires_at ON urls (expires_at) WHERE expires_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS urls_archive (
+    id INTEGER PRIMARY KEY,
+    originalURL TEXT NOT NULL,
+    shortURL TEXT NOT NULL,
+    userID TEXT NOT NULL,
+    deleted BOOLEAN NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    archived_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
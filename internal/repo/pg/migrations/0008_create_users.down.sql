@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_urls_userid;
+DROP TABLE IF EXISTS users;
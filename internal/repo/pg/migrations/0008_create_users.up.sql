@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS users (
+    id TEXT PRIMARY KEY,
+    login TEXT NOT NULL,
+    password_hash TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT users_login_key UNIQUE (login)
+);
+
+CREATE INDEX IF NOT EXISTS idx_urls_userid ON urls (userID);
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS bans;
+
+ALTER TABLE urls_archive
+    DROP COLUMN IF EXISTS disabled;
+
+ALTER TABLE urls
+    DROP COLUMN IF EXISTS disabled;
@@ -4,10 +4,13 @@ package pg
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 
@@ -16,94 +19,85 @@ import (
 	"github.com/Azzonya/go-shortener/internal/repo/inmemory"
 )
 
+const (
+	uniqueViolationCode   = "23505"             // uniqueViolationCode is the PostgreSQL error code for unique constraint violations.
+	originalURLConstraint = "idx_original_url"  // originalURLConstraint is the unique index on the original URL column.
+	shortURLConstraint    = "urls_shorturl_key" // shortURLConstraint is the unique constraint on the short URL column.
+	loginConstraint       = "users_login_key"   // loginConstraint is the unique constraint on the account login column.
+)
+
 // St represents the PostgreSQL storage structure for shortened URLs.
 type St struct {
 	db *pgxpool.Pool // PostgreSQL connection pool.
 }
 
 // New creates and initializes a new PostgreSQL storage instance with the provided database connection pool.
-func New(db *pgxpool.Pool) *St {
+// Pending schema migrations are applied before the storage is returned, a failing migration is returned as an error.
+func New(db *pgxpool.Pool) (*St, error) {
 	s := &St{
 		db: db,
 	}
 
-	var err error
-
-	if !s.TableExist() {
-		err = s.Initialize()
-		if err != nil {
-			return nil
-		}
+	if err := s.Initialize(context.Background()); err != nil {
+		return nil, err
 	}
 
-	return &St{
-		db: db,
-	}
+	return s, nil
 }
 
-// Initialize initializes the PostgreSQL storage by creating the necessary table if it doesn't exist.
-func (s *St) Initialize() error {
-	query := `CREATE TABLE urls (
-				id SERIAL PRIMARY KEY,
-				originalURL VARCHAR(255) NOT NULL,
-				shortURL VARCHAR(255) UNIQUE NOT NULL,
-                userID VARCHAR(255) NOT NULL,
-                deleted BOOLEAN default false 
-				);
-				DO $$ 
-				BEGIN 
-   	 			IF NOT EXISTS (SELECT 1 FROM pg_indexes WHERE tablename = 'urls' AND indexname = 'idx_original_url') THEN
-        			CREATE UNIQUE INDEX idx_original_url ON urls(originalURL);
-    			END IF;
-				END $$;`
-
-	_, err := s.db.Exec(context.Background(), query)
+// Initialize initializes the PostgreSQL storage by applying all pending schema migrations.
+func (s *St) Initialize(ctx context.Context) error {
+	migrator, err := NewMigrator(s.db)
 	if err != nil {
-		return fmt.Errorf("URL table creating error: %w", err)
+		return fmt.Errorf("load migrations error: %w", err)
+	}
+
+	if err = migrator.Up(ctx); err != nil {
+		return fmt.Errorf("migrations error: %w", err)
 	}
 
 	return nil
 }
 
 // TableExist checks if the table exists in the PostgreSQL storage.
-func (s *St) TableExist() bool {
+func (s *St) TableExist(ctx context.Context) bool {
 	var count int
-	err := s.db.QueryRow(context.Background(), "SELECT COUNT(*) from urls").Scan(&count)
+	err := s.db.QueryRow(ctx, "SELECT COUNT(*) from urls").Scan(&count)
 
 	return err == nil
 }
 
 // Add adds a new URL mapping to the PostgreSQL storage.
-func (s *St) Add(originalURL, shortURL, userID string) error {
-	query := `INSERT INTO urls (originalURL, shortURL, userID) VALUES ($1, $2, $3)`
+func (s *St) Add(ctx context.Context, originalURL, shortURL, userID string, expiresAt *time.Time) error {
+	query := `INSERT INTO urls (originalURL, shortURL, userID, expires_at) VALUES ($1, $2, $3, $4)`
 
-	_, err := s.db.Exec(context.Background(), query, originalURL, shortURL, userID)
+	_, err := s.db.Exec(ctx, query, originalURL, shortURL, userID, expiresAt)
 
 	if err != nil {
-		return err
+		return convertUniqueErr(err)
 	}
 
 	return nil
 }
 
 // Update updates the short URL associated with the given original URL in the PostgreSQL storage.
-func (s *St) Update(originalURL, shortURL string) error {
-	query := `UPDATE urls SET shortURL = $1 where originalURL = $2`
+func (s *St) Update(ctx context.Context, originalURL, shortURL string) error {
+	query := `UPDATE urls SET shortURL = $1, updated_at = now() WHERE md5(originalURL) = md5($2) AND originalURL = $2`
 
-	_, err := s.db.Exec(context.Background(), query, shortURL, originalURL)
+	_, err := s.db.Exec(ctx, query, shortURL, originalURL)
 
-	return err
+	return convertUniqueErr(err)
 }
 
 // GetByShortURL retrieves the original URL associated with the given short URL from the PostgreSQL storage.
-func (s *St) GetByShortURL(shortURL string) (string, bool) {
+func (s *St) GetByShortURL(ctx context.Context, shortURL string) (string, bool) {
 	var url string
 
 	exist := false
 
 	query := `SELECT originalURL from urls WHERE shortURL = $1`
 
-	row := s.db.QueryRow(context.Background(), query, shortURL)
+	row := s.db.QueryRow(ctx, query, shortURL)
 
 	err := row.Scan(&url)
 	if err != nil {
@@ -117,15 +111,32 @@ func (s *St) GetByShortURL(shortURL string) (string, bool) {
 	return url, exist
 }
 
+// GetLink returns the stored state of the short URL with a single query.
+func (s *St) GetLink(ctx context.Context, shortURL string) (*entities.Link, error) {
+	link := &entities.Link{ShortURL: shortURL}
+
+	query := `SELECT originalURL, COALESCE(userID, ''), COALESCE(deleted, false), disabled, expires_at FROM urls WHERE shortURL = $1`
+
+	err := s.db.QueryRow(ctx, query, shortURL).Scan(&link.OriginalURL, &link.UserID, &link.Deleted, &link.Disabled, &link.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, entities.ErrURLNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get link error: %w", err)
+	}
+
+	return link, nil
+}
+
 // GetByOriginalURL retrieves the short URL associated with the given original URL from the PostgreSQL storage.
-func (s *St) GetByOriginalURL(originalURL string) (string, bool) {
+func (s *St) GetByOriginalURL(ctx context.Context, originalURL string) (string, bool) {
 	var url string
 
 	exist := false
 
-	query := `SELECT shortURL from urls WHERE originalURL = $1`
+	query := `SELECT shortURL from urls WHERE md5(originalURL) = md5($1) AND originalURL = $1`
 
-	row := s.db.QueryRow(context.Background(), query, originalURL)
+	row := s.db.QueryRow(ctx, query, originalURL)
 
 	err := row.Scan(&url)
 	if err != nil {
@@ -140,11 +151,11 @@ func (s *St) GetByOriginalURL(originalURL string) (string, bool) {
 }
 
 // ListAll retrieves all shortened URLs associated with a user from the PostgreSQL storage.
-func (s *St) ListAll(userID string) ([]*entities.ReqListAll, error) {
+func (s *St) ListAll(ctx context.Context, userID string) ([]*entities.ReqListAll, error) {
 	result := []*entities.ReqListAll{}
-	query := `SELECT originalurl, shortURL from urls WHERE userID = $1`
+	query := `SELECT originalurl, shortURL from urls WHERE userID = $1 ORDER BY id`
 
-	rows, err := s.db.Query(context.Background(), query, userID)
+	rows, err := s.db.Query(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -163,10 +174,20 @@ func (s *St) ListAll(userID string) ([]*entities.ReqListAll, error) {
 	return result, nil
 }
 
-// CreateShortURLs creates multiple shortened URLs in a single transaction in the PostgreSQL storage.
-func (s *St) CreateShortURLs(urls []*entities.ReqURL, userID string) error {
-	ctx := context.Background()
+// Stats returns the total number of stored URLs and of distinct users owning them.
+func (s *St) Stats(ctx context.Context) (*entities.ServiceStats, error) {
+	stats := &entities.ServiceStats{}
+	query := `SELECT count(*), count(DISTINCT userid) FILTER (WHERE userid <> '') FROM urls`
+
+	if err := s.db.QueryRow(ctx, query).Scan(&stats.URLs, &stats.Users); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
 
+// CreateShortURLs creates multiple shortened URLs in a single transaction in the PostgreSQL storage.
+func (s *St) CreateShortURLs(ctx context.Context, urls []*entities.ReqURL, userID string) error {
 	tx, err := s.db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("transaction error: %w", err)
@@ -178,19 +199,19 @@ func (s *St) CreateShortURLs(urls []*entities.ReqURL, userID string) error {
 		}
 	}()
 
-	stmt, err := tx.Prepare(ctx, "insertURLs", "INSERT INTO urls (originalURL, shortURL, userID) VALUES($1, $2, $3)")
+	stmt, err := tx.Prepare(ctx, "insertURLs", "INSERT INTO urls (originalURL, shortURL, userID, expires_at) VALUES($1, $2, $3, $4)")
 	if err != nil {
 		return fmt.Errorf("tx query error: %w", err)
 	}
 
 	for _, v := range urls {
-		_, err := tx.Exec(ctx, stmt.Name, v.OriginalURL, v.ShortURL, userID)
+		_, err := tx.Exec(ctx, stmt.Name, v.OriginalURL, v.ShortURL, userID, v.ExpiresAt)
 		if err != nil {
-			return fmt.Errorf("statement exec error: %w", err)
+			return fmt.Errorf("statement exec error: %w", convertUniqueErr(err))
 		}
 	}
 
-	err = tx.Commit(context.Background())
+	err = tx.Commit(ctx)
 	if err != nil {
 		return fmt.Errorf("commit error: %w", err)
 	}
@@ -201,14 +222,27 @@ func (s *St) CreateShortURLs(urls []*entities.ReqURL, userID string) error {
 // DeleteURLs deletes URLs associated with the given userID.
 // It takes a slice of URLs to delete and the userID of the user.
 // It returns an error if any occurs during the deletion process.
-func (s *St) DeleteURLs(urls []string, userID string) error {
+func (s *St) DeleteURLs(ctx context.Context, urls []string, userID string) error {
+	return s.DeleteURLsBatch(ctx, []*entities.DeleteRequest{{UserID: userID, ShortURLs: urls}})
+}
+
+// DeleteURLsBatch deletes the URLs of several deletion requests in a single round trip,
+// with one update per request matching all of its URLs at once.
+func (s *St) DeleteURLsBatch(ctx context.Context, requests []*entities.DeleteRequest) error {
 	batch := &pgx.Batch{}
 
-	for _, data := range urls {
-		batch.Queue("UPDATE urls SET deleted = true WHERE shorturl = $1 AND userid = $2", data, userID)
+	for _, req := range requests {
+		if len(req.ShortURLs) == 0 {
+			continue
+		}
+		batch.Queue("UPDATE urls SET deleted = true, updated_at = now() WHERE shorturl = ANY($1) AND userid = $2 AND NOT deleted", req.ShortURLs, req.UserID)
+	}
+
+	if batch.Len() == 0 {
+		return nil
 	}
 
-	bRes := s.db.SendBatch(context.Background(), batch)
+	bRes := s.db.SendBatch(ctx, batch)
 	err := bRes.Close()
 	if err != nil {
 		logger.Log.Error(err.Error())
@@ -218,11 +252,11 @@ func (s *St) DeleteURLs(urls []string, userID string) error {
 }
 
 // URLDeleted checks if the URL with the given shortURL is deleted.
-func (s *St) URLDeleted(shortURL string) bool {
+func (s *St) URLDeleted(ctx context.Context, shortURL string) bool {
 	deleted := false
 	query := `SELECT deleted from urls WHERE shortURL = $1`
 
-	row := s.db.QueryRow(context.Background(), query, shortURL)
+	row := s.db.QueryRow(ctx, query, shortURL)
 
 	err := row.Scan(&deleted)
 	if err != nil {
@@ -232,6 +266,113 @@ func (s *St) URLDeleted(shortURL string) bool {
 	return deleted
 }
 
+// URLExpired checks if the URL with the given shortURL has expired.
+func (s *St) URLExpired(ctx context.Context, shortURL string) bool {
+	expired := false
+	query := `SELECT COALESCE(expires_at <= now(), false) from urls WHERE shortURL = $1`
+
+	err := s.db.QueryRow(ctx, query, shortURL).Scan(&expired)
+	if err != nil {
+		return false
+	}
+
+	return expired
+}
+
+// DeleteExpired removes the URLs which expired before the given time.
+// With archive set, the removed rows are moved to the urls_archive table in the same statement.
+func (s *St) DeleteExpired(ctx context.Context, before time.Time, archive bool) (int64, error) {
+	query := `DELETE FROM urls WHERE expires_at < $1`
+	if archive {
+		query = `WITH expired AS (
+					DELETE FROM urls WHERE expires_at < $1
+					RETURNING id, originalURL, shortURL, userID, COALESCE(deleted, false) AS deleted, disabled, created_at, expires_at
+				)
+				INSERT INTO urls_archive (id, originalURL, shortURL, userID, deleted, disabled, created_at, expires_at)
+				SELECT id, originalURL, shortURL, userID, deleted, disabled, created_at, expires_at FROM expired`
+	}
+
+	tag, err := s.db.Exec(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("delete expired urls error: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// AddClicks stores the clicks with a single COPY.
+func (s *St) AddClicks(ctx context.Context, clicks []*entities.Click) error {
+	rows := make([][]any, 0, len(clicks))
+	for _, click := range clicks {
+		rows = append(rows, []any{click.ShortURL, click.ClickedAt, click.Referrer, click.UserAgent, click.IPHash})
+	}
+
+	_, err := s.db.CopyFrom(ctx,
+		pgx.Identifier{"clicks"},
+		[]string{"shorturl", "clicked_at", "referrer", "user_agent", "ip_hash"},
+		pgx.CopyFromRows(rows),
+	)
+	if err != nil {
+		return fmt.Errorf("copy clicks error: %w", err)
+	}
+
+	return nil
+}
+
+// ClickStats returns the click statistics of the short URL owned by the user.
+// Histogram buckets are aligned to the Unix epoch.
+func (s *St) ClickStats(ctx context.Context, shortURL, userID string, bucket time.Duration) (*entities.LinkStats, error) {
+	var owned bool
+
+	err := s.db.QueryRow(ctx, `SELECT true FROM urls WHERE shortURL = $1 AND userID = $2`, shortURL, userID).Scan(&owned)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, entities.ErrURLNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &entities.LinkStats{Histogram: make([]*entities.StatsBucket, 0)}
+
+	query := `SELECT COUNT(*), COUNT(DISTINCT NULLIF(ip_hash, '')) FROM clicks WHERE shorturl = $1`
+	if err = s.db.QueryRow(ctx, query, shortURL).Scan(&stats.TotalClicks, &stats.UniqueVisitors); err != nil {
+		return nil, err
+	}
+
+	query = `SELECT to_timestamp(floor(extract(epoch FROM clicked_at) / $2) * $2) AS bucket, COUNT(*)
+				FROM clicks WHERE shorturl = $1
+				GROUP BY bucket ORDER BY bucket`
+
+	rows, err := s.db.Query(ctx, query, shortURL, int64(bucket/time.Second))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		b := &entities.StatsBucket{}
+		if err = rows.Scan(&b.Start, &b.Clicks); err != nil {
+			return nil, err
+		}
+		b.Start = b.Start.UTC()
+		stats.Histogram = append(stats.Histogram, b)
+	}
+
+	return stats, rows.Err()
+}
+
+// NextSequence returns the next value of the short code sequence.
+func (s *St) NextSequence(ctx context.Context) (int64, error) {
+	var value int64
+
+	err := s.db.QueryRow(ctx, `SELECT nextval('short_code_seq')`).Scan(&value)
+	if err != nil {
+		return 0, fmt.Errorf("short code sequence error: %w", err)
+	}
+
+	return value, nil
+}
+
 // WriteEvent writes an event to the storage.
 func (s *St) WriteEvent(_ *inmemory.Event) error {
 	return nil
@@ -244,8 +385,28 @@ func (s *St) SyncData() {
 }
 
 // Ping pings the database.
-func (s *St) Ping() error {
-	err := s.db.Ping(context.Background())
+func (s *St) Ping(ctx context.Context) error {
+	err := s.db.Ping(ctx)
 
 	return err
 }
+
+// convertUniqueErr wraps unique constraint violations into the matching entities error,
+// so callers get the same error values as from the other repository backends.
+func convertUniqueErr(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != uniqueViolationCode {
+		return err
+	}
+
+	switch pgErr.ConstraintName {
+	case originalURLConstraint:
+		return fmt.Errorf("%w: %w", entities.ErrOriginalURLExists, err)
+	case shortURLConstraint:
+		return fmt.Errorf("%w: %w", entities.ErrShortURLExists, err)
+	case loginConstraint:
+		return fmt.Errorf("%w: %w", entities.ErrLoginExists, err)
+	default:
+		return err
+	}
+}
@@ -79,7 +79,8 @@ func TestNew(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := New(tt.args.db)
+			got, err := New(tt.args.db)
+			assert.NoError(t, err)
 			assert.NotNil(t, got)
 		})
 	}
@@ -126,7 +127,7 @@ func TestSt_Add(t *testing.T) {
 				db: tt.fields.db,
 			}
 
-			if err := s.Initialize(); (err != nil) != tt.wantErr {
+			if err := s.Initialize(context.Background()); (err != nil) != tt.wantErr {
 				t.Errorf("Initialize() error = %v, wantErr %v", err, tt.wantErr)
 			}
 
@@ -134,7 +135,7 @@ func TestSt_Add(t *testing.T) {
 			randomGenerator := rand.New(source)
 			randomNumber := randomGenerator.Intn(10000)
 
-			if err := s.Add(tt.args.originalURL+fmt.Sprint(randomNumber), tt.args.shortURL+fmt.Sprint(randomNumber), tt.args.userID); (err != nil) != tt.wantErr {
+			if err := s.Add(context.Background(), tt.args.originalURL+fmt.Sprint(randomNumber), tt.args.shortURL+fmt.Sprint(randomNumber), tt.args.userID, nil); (err != nil) != tt.wantErr {
 				t.Errorf("Add() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -190,7 +191,7 @@ func TestSt_CreateShortURLs(t *testing.T) {
 				db: tt.fields.db,
 			}
 
-			if err := s.Initialize(); (err != nil) != tt.wantErr {
+			if err := s.Initialize(context.Background()); (err != nil) != tt.wantErr {
 				t.Errorf("Initialize() error = %v, wantErr %v", err, tt.wantErr)
 			}
 
@@ -203,7 +204,7 @@ func TestSt_CreateShortURLs(t *testing.T) {
 				v.ID += fmt.Sprint(randomNumber)
 			}
 
-			if err := s.CreateShortURLs(tt.args.urls, tt.args.userID); (err != nil) != tt.wantErr {
+			if err := s.CreateShortURLs(context.Background(), tt.args.urls, tt.args.userID); (err != nil) != tt.wantErr {
 				t.Errorf("CreateShortURLs() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -251,7 +252,7 @@ func TestSt_DeleteURLs(t *testing.T) {
 				db: tt.fields.db,
 			}
 
-			if err := s.Initialize(); (err != nil) != tt.wantErr {
+			if err := s.Initialize(context.Background()); (err != nil) != tt.wantErr {
 				t.Errorf("Initialize() error = %v, wantErr %v", err, tt.wantErr)
 			}
 
@@ -266,11 +267,11 @@ func TestSt_DeleteURLs(t *testing.T) {
 				},
 			}
 
-			if err := s.CreateShortURLs(urls, tt.args.userID); (err != nil) != tt.wantErr {
+			if err := s.CreateShortURLs(context.Background(), urls, tt.args.userID); (err != nil) != tt.wantErr {
 				t.Errorf("CreateShortURLs() error = %v, wantErr %v", err, tt.wantErr)
 			}
 
-			if err := s.DeleteURLs(tt.args.urls, tt.args.userID); (err != nil) != tt.wantErr {
+			if err := s.DeleteURLs(context.Background(), tt.args.urls, tt.args.userID); (err != nil) != tt.wantErr {
 				t.Errorf("DeleteURLs() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -316,13 +317,13 @@ func TestSt_GetByOriginalURL(t *testing.T) {
 				db: tt.fields.db,
 			}
 
-			if err := s.Initialize(); (err != nil) != false {
+			if err := s.Initialize(context.Background()); (err != nil) != false {
 				t.Errorf("Initialize() error = %v, wantErr %v", err, false)
 			}
 
-			s.Add(tt.args.originalURL, tt.want, "1")
+			s.Add(context.Background(), tt.args.originalURL, tt.want, "1", nil)
 
-			got, got1 := s.GetByOriginalURL(tt.args.originalURL)
+			got, got1 := s.GetByOriginalURL(context.Background(), tt.args.originalURL)
 			if got != tt.want {
 				t.Errorf("GetByOriginalURL() got = %v, want %v", got, tt.want)
 			}
@@ -372,13 +373,13 @@ func TestSt_GetByShortURL(t *testing.T) {
 				db: tt.fields.db,
 			}
 
-			if err := s.Initialize(); (err != nil) != false {
+			if err := s.Initialize(context.Background()); (err != nil) != false {
 				t.Errorf("Initialize() error = %v, wantErr %v", err, false)
 			}
 
-			s.Add(tt.want, tt.args.shortURL, "1")
+			s.Add(context.Background(), tt.want, tt.args.shortURL, "1", nil)
 
-			got, got1 := s.GetByShortURL(tt.args.shortURL)
+			got, got1 := s.GetByShortURL(context.Background(), tt.args.shortURL)
 			if got != tt.want {
 				t.Errorf("GetByShortURL() got = %v, want %v", got, tt.want)
 			}
@@ -418,7 +419,7 @@ func TestSt_Initialize(t *testing.T) {
 			s := &St{
 				db: tt.fields.db,
 			}
-			if err := s.Initialize(); (err != nil) != tt.wantErr {
+			if err := s.Initialize(context.Background()); (err != nil) != tt.wantErr {
 				t.Errorf("Initialize() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -463,11 +464,11 @@ func TestSt_ListAll(t *testing.T) {
 				db: tt.fields.db,
 			}
 
-			if err := s.Initialize(); (err != nil) != tt.wantErr {
+			if err := s.Initialize(context.Background()); (err != nil) != tt.wantErr {
 				t.Errorf("Initialize() error = %v, wantErr %v", err, tt.wantErr)
 			}
 
-			_, err := s.ListAll(tt.args.userID)
+			_, err := s.ListAll(context.Background(), tt.args.userID)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ListAll() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -505,7 +506,7 @@ func TestSt_Ping(t *testing.T) {
 			s := &St{
 				db: tt.fields.db,
 			}
-			if err := s.Ping(); (err != nil) != tt.wantErr {
+			if err := s.Ping(context.Background()); (err != nil) != tt.wantErr {
 				t.Errorf("Ping() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -574,11 +575,11 @@ func TestSt_TableExist(t *testing.T) {
 				db: tt.fields.db,
 			}
 
-			if err := s.Initialize(); (err != nil) != false {
+			if err := s.Initialize(context.Background()); (err != nil) != false {
 				t.Errorf("Initialize() error = %v, wantErr %v", err, false)
 			}
 
-			if got := s.TableExist(); got != tt.want {
+			if got := s.TableExist(context.Background()); got != tt.want {
 				t.Errorf("TableExist() = %v, want %v", got, tt.want)
 			}
 		})
@@ -622,13 +623,13 @@ func TestSt_URLDeleted(t *testing.T) {
 				db: tt.fields.db,
 			}
 
-			if err := s.Initialize(); (err != nil) != false {
+			if err := s.Initialize(context.Background()); (err != nil) != false {
 				t.Errorf("Initialize() error = %v, wantErr %v", err, false)
 			}
 
-			s.Add("urldeleted.com", tt.args.shortURL, "1")
+			s.Add(context.Background(), "urldeleted.com", tt.args.shortURL, "1", nil)
 
-			if got := s.URLDeleted(tt.args.shortURL); got != tt.want {
+			if got := s.URLDeleted(context.Background(), tt.args.shortURL); got != tt.want {
 				t.Errorf("URLDeleted() = %v, want %v", got, tt.want)
 			}
 		})
@@ -674,7 +675,7 @@ func TestSt_Update(t *testing.T) {
 				db: tt.fields.db,
 			}
 
-			if err := s.Initialize(); (err != nil) != tt.wantErr {
+			if err := s.Initialize(context.Background()); (err != nil) != tt.wantErr {
 				t.Errorf("Initialize() error = %v, wantErr %v", err, tt.wantErr)
 			}
 
@@ -682,11 +683,50 @@ func TestSt_Update(t *testing.T) {
 			randomGenerator := rand.New(source)
 			randomNumber := randomGenerator.Intn(10000)
 
-			s.Add(tt.args.originalURL, tt.args.shortURL, "1")
+			s.Add(context.Background(), tt.args.originalURL, tt.args.shortURL, "1", nil)
 
-			if err := s.Update(tt.args.originalURL, tt.args.shortURL+fmt.Sprint(randomNumber)); (err != nil) != tt.wantErr {
+			if err := s.Update(context.Background(), tt.args.originalURL, tt.args.shortURL+fmt.Sprint(randomNumber)); (err != nil) != tt.wantErr {
 				t.Errorf("Update() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
+
+func TestSt_GetLink(t *testing.T) {
+	dsn := getPgDsnTestContainer()
+
+	db, err := pkg.InitDatabasePg(dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	s := &St{
+		db: db,
+	}
+
+	if err = s.Initialize(context.Background()); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Microsecond)
+	if err = s.Add(context.Background(), "getlink.com", "getlink", "1", &expiresAt); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err = s.DeleteURLs(context.Background(), []string{"getlink"}, "1"); err != nil {
+		t.Fatalf("DeleteURLs() error = %v", err)
+	}
+
+	link, err := s.GetLink(context.Background(), "getlink")
+	if err != nil {
+		t.Fatalf("GetLink() error = %v", err)
+	}
+	assert.Equal(t, "getlink.com", link.OriginalURL)
+	assert.Equal(t, "1", link.UserID)
+	assert.True(t, link.Deleted)
+	if assert.NotNil(t, link.ExpiresAt) {
+		assert.True(t, expiresAt.Equal(*link.ExpiresAt))
+	}
+
+	_, err = s.GetLink(context.Background(), "getlink-missing")
+	assert.ErrorIs(t, err, entities.ErrURLNotFound)
+}
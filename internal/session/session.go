@@ -4,6 +4,7 @@ package session
 import (
 	"context"
 	"errors"
+	"slices"
 
 	"github.com/Azzonya/go-shortener/internal/user"
 )
@@ -14,6 +15,12 @@ type ctxKey int
 const (
 	// ctxKeyUID is the context key for storing user information.
 	ctxKeyUID ctxKey = iota
+
+	// ctxKeyScopes is the context key for storing the scopes of the API key authenticating the user.
+	ctxKeyScopes
+
+	// ctxKeyExpired is the context key for flagging a request whose session token has expired.
+	ctxKeyExpired
 )
 
 // SetUserContext sets the user information in the context.
@@ -41,3 +48,35 @@ func GetUser(c context.Context) (*user.User, error) {
 
 	return u, nil
 }
+
+// SetScopesContext restricts the requests of the user to the given scopes,
+// used when the user is authenticated by an API key.
+func SetScopesContext(parent context.Context, scopes []string) context.Context {
+	return context.WithValue(parent, ctxKeyScopes, scopes)
+}
+
+// GetScopes returns the scopes the requests of the user are restricted to.
+// It returns false if the user is not restricted, as when authenticated by a session cookie.
+func GetScopes(ctx context.Context) ([]string, bool) {
+	scopes, ok := ctx.Value(ctxKeyScopes).([]string)
+	return scopes, ok
+}
+
+// HasScope checks if the user is allowed requests of the given scope.
+// Users not restricted to any scopes are allowed every request.
+func HasScope(ctx context.Context, scope string) bool {
+	scopes, ok := GetScopes(ctx)
+	return !ok || slices.Contains(scopes, scope)
+}
+
+// SetExpiredContext flags the request as made with an expired session token,
+// the user of the request then being a new one.
+func SetExpiredContext(parent context.Context) context.Context {
+	return context.WithValue(parent, ctxKeyExpired, true)
+}
+
+// IsExpired checks if the request was made with an expired session token.
+func IsExpired(ctx context.Context) bool {
+	expired, _ := ctx.Value(ctxKeyExpired).(bool)
+	return expired
+}
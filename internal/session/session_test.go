@@ -110,3 +110,25 @@ func TestSetUserContext(t *testing.T) {
 		})
 	}
 }
+
+func TestHasScope(t *testing.T) {
+	scoped := SetScopesContext(context.Background(), []string{"shorten", "read"})
+
+	tests := []struct {
+		name  string
+		ctx   context.Context
+		scope string
+		want  bool
+	}{
+		{name: "Unrestricted user", ctx: context.Background(), scope: "delete", want: true},
+		{name: "Granted scope", ctx: scoped, scope: "read", want: true},
+		{name: "Missing scope", ctx: scoped, scope: "delete", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasScope(tt.ctx, tt.scope); got != tt.want {
+				t.Errorf("HasScope() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
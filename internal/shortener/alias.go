@@ -0,0 +1,54 @@
+package shortener
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Alias length limits.
+const (
+	minAliasLength = 3  // minAliasLength is the minimum length of a custom alias.
+	maxAliasLength = 64 // maxAliasLength is the maximum length of a custom alias.
+)
+
+// aliasRe matches the allowed alias characters: letters, digits, dashes and underscores,
+// starting with a letter or a digit.
+var aliasRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// reservedAliases contains the words that can not be claimed as aliases,
+// because they are or may become paths of the service itself.
+var reservedAliases = map[string]struct{}{
+	"api":     {},
+	"ping":    {},
+	"admin":   {},
+	"debug":   {},
+	"health":  {},
+	"metrics": {},
+	"static":  {},
+}
+
+// Alias errors.
+var (
+	ErrInvalidAlias = errors.New("invalid alias")  // ErrInvalidAlias is returned for aliases failing validation.
+	ErrAliasTaken   = errors.New("alias is taken") // ErrAliasTaken is returned when the alias is already used by another link.
+)
+
+// ValidateAlias checks that the alias has an allowed length and charset and is not a reserved word.
+// The returned error wraps ErrInvalidAlias.
+func ValidateAlias(alias string) error {
+	if len(alias) < minAliasLength || len(alias) > maxAliasLength {
+		return fmt.Errorf("%w: length must be from %d to %d characters", ErrInvalidAlias, minAliasLength, maxAliasLength)
+	}
+
+	if !aliasRe.MatchString(alias) {
+		return fmt.Errorf("%w: only letters, digits, '-' and '_' are allowed, starting with a letter or a digit", ErrInvalidAlias)
+	}
+
+	if _, reserved := reservedAliases[strings.ToLower(alias)]; reserved {
+		return fmt.Errorf("%w: %q is reserved", ErrInvalidAlias, alias)
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package shortener
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateAlias(t *testing.T) {
+	tests := []struct {
+		name    string
+		alias   string
+		wantErr bool
+	}{
+		{name: "valid", alias: "spring-sale", wantErr: false},
+		{name: "valid with underscore and digits", alias: "sale_2024", wantErr: false},
+		{name: "too short", alias: "ab", wantErr: true},
+		{name: "too long", alias: strings.Repeat("a", maxAliasLength+1), wantErr: true},
+		{name: "invalid character", alias: "spring/sale", wantErr: true},
+		{name: "starts with dash", alias: "-sale", wantErr: true},
+		{name: "non ascii", alias: "распродажа", wantErr: true},
+		{name: "reserved", alias: "api", wantErr: true},
+		{name: "reserved in upper case", alias: "PING", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAlias(tt.alias)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateAlias() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidAlias) {
+				t.Errorf("ValidateAlias() error = %v, want %v", err, ErrInvalidAlias)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package shortener
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+	"github.com/Azzonya/go-shortener/internal/logger"
+	"github.com/Azzonya/go-shortener/internal/repo"
+)
+
+// clickBatchSize is the maximum number of clicks written to the repository at once.
+const clickBatchSize = 500
+
+// saltSize is the number of random bytes of a generated IP hash salt.
+const saltSize = 32
+
+// ClickTracker records redirects through short URLs without blocking them.
+// Clicks are queued in a bounded buffer and written to the repository in batches
+// by a background worker, clicks arriving while the buffer is full are dropped.
+type ClickTracker struct {
+	repo          repo.Repo            // Repository the clicks are written to
+	queue         chan *entities.Click // Buffer of clicks waiting to be written
+	flushInterval time.Duration        // Maximum time a click waits in the buffer
+	timeout       time.Duration        // Maximum duration of a single batch write, zero for no limit
+	salt          []byte               // Key of the HMAC hashing visitor IP addresses
+	dropped       atomic.Int64         // Number of clicks dropped because the buffer was full
+	stop          chan struct{}        // Closed to stop the worker
+	done          chan struct{}        // Closed once the worker has stopped
+	now           func() time.Time     // Clock used to timestamp clicks
+}
+
+// NewClickTracker creates a new click tracker buffering up to bufferSize clicks.
+// Visitor IP addresses are never stored, only their HMAC keyed by the salt, which must be secret, see ResolveSalt.
+func NewClickTracker(repo repo.Repo, bufferSize int, flushInterval time.Duration, salt string, timeout time.Duration) *ClickTracker {
+	return &ClickTracker{
+		repo:          repo,
+		queue:         make(chan *entities.Click, bufferSize),
+		flushInterval: flushInterval,
+		timeout:       timeout,
+		salt:          []byte(salt),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+		now:           time.Now,
+	}
+}
+
+// ResolveSalt returns the salt hashing visitor IP addresses. A configured salt is returned as is.
+// Without one, a random salt is generated in the file on first use and read from it afterwards,
+// so that the hashes of a visitor stay the same across restarts.
+func ResolveSalt(salt, file string) (string, error) {
+	if salt != "" {
+		return salt, nil
+	}
+	if file == "" {
+		return "", errors.New("neither IP hash salt nor salt file is set")
+	}
+
+	data, err := os.ReadFile(file)
+	if err == nil {
+		if salt = strings.TrimSpace(string(data)); salt == "" {
+			return "", fmt.Errorf("IP hash salt file %s is empty", file)
+		}
+		return salt, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("cannot read IP hash salt: %w", err)
+	}
+
+	random := make([]byte, saltSize)
+	if _, err = rand.Read(random); err != nil {
+		return "", fmt.Errorf("cannot generate IP hash salt: %w", err)
+	}
+	salt = hex.EncodeToString(random)
+
+	if err = os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return "", fmt.Errorf("cannot create IP hash salt directory: %w", err)
+	}
+	if err = os.WriteFile(file, []byte(salt+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("cannot write IP hash salt: %w", err)
+	}
+
+	return salt, nil
+}
+
+// Record queues a click on the short URL. It never blocks, the click is dropped if the buffer is full.
+func (t *ClickTracker) Record(shortURL, referrer, userAgent, ip string) {
+	click := &entities.Click{
+		ShortURL:  shortURL,
+		ClickedAt: t.now().UTC(),
+		Referrer:  referrer,
+		UserAgent: userAgent,
+		IPHash:    t.HashIP(ip),
+	}
+
+	select {
+	case t.queue <- click:
+	default:
+		if dropped := t.dropped.Add(1); dropped%1000 == 1 {
+			logger.Log.Warn("Click buffer is full, dropping clicks", zap.Int64("dropped", dropped))
+		}
+	}
+}
+
+// HashIP returns the hex encoded HMAC-SHA256 of the IP address, or an empty string for an empty address.
+func (t *ClickTracker) HashIP(ip string) string {
+	if ip == "" {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, t.salt)
+	mac.Write([]byte(ip))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dropped returns the number of clicks dropped because the buffer was full.
+func (t *ClickTracker) Dropped() int64 {
+	return t.dropped.Load()
+}
+
+// Start runs the worker writing the queued clicks in the background until Stop is called.
+func (t *ClickTracker) Start() {
+	go func() {
+		defer close(t.done)
+
+		ticker := time.NewTicker(t.flushInterval)
+		defer ticker.Stop()
+
+		batch := make([]*entities.Click, 0, clickBatchSize)
+
+		for {
+			select {
+			case click := <-t.queue:
+				batch = append(batch, click)
+				if len(batch) >= clickBatchSize {
+					batch = t.flush(batch)
+				}
+			case <-ticker.C:
+				batch = t.flush(batch)
+			case <-t.stop:
+				t.drain(batch)
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the worker after writing the clicks left in the buffer.
+func (t *ClickTracker) Stop() {
+	close(t.stop)
+	<-t.done
+}
+
+// drain writes the batch and every click left in the buffer.
+func (t *ClickTracker) drain(batch []*entities.Click) {
+	for {
+		select {
+		case click := <-t.queue:
+			batch = append(batch, click)
+			if len(batch) >= clickBatchSize {
+				batch = t.flush(batch)
+			}
+		default:
+			t.flush(batch)
+			return
+		}
+	}
+}
+
+// flush writes the batch to the repository and returns the emptied batch for reuse.
+// Write errors are logged, the clicks of a failed batch are lost.
+func (t *ClickTracker) flush(batch []*entities.Click) []*entities.Click {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	ctx, cancel := withTimeout(context.Background(), t.timeout)
+	defer cancel()
+
+	if err := t.repo.AddClicks(ctx, batch); err != nil {
+		logger.Log.Error("Failed to write clicks", zap.Int("count", len(batch)), zap.Error(err))
+	}
+
+	clear(batch)
+
+	return batch[:0]
+}
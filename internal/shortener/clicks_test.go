@@ -0,0 +1,106 @@
+package shortener
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azzonya/go-shortener/internal/repo/inmemory"
+)
+
+func TestClickTracker(t *testing.T) {
+	repoTest, err := inmemory.New("")
+	if err != nil {
+		t.Fatalf("inmemory.New() error = %v", err)
+	}
+
+	if err = repoTest.Add(context.Background(), "example.com", "tst", "1", nil); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	tracker := NewClickTracker(repoTest, 100, time.Hour, "salt", 0)
+	tracker.Start()
+
+	tracker.Record("tst", "https://referrer.com", "agent", "10.0.0.1")
+	tracker.Record("tst", "", "agent", "10.0.0.1")
+	tracker.Record("tst", "", "agent", "10.0.0.2")
+
+	tracker.Stop()
+
+	stats, err := repoTest.ClickStats(context.Background(), "tst", "1", time.Hour)
+	if err != nil {
+		t.Fatalf("ClickStats() error = %v", err)
+	}
+
+	if stats.TotalClicks != 3 {
+		t.Errorf("TotalClicks = %v, want %v", stats.TotalClicks, 3)
+	}
+	if stats.UniqueVisitors != 2 {
+		t.Errorf("UniqueVisitors = %v, want %v", stats.UniqueVisitors, 2)
+	}
+}
+
+func TestClickTracker_RecordFullBuffer(t *testing.T) {
+	tracker := NewClickTracker(nil, 1, time.Hour, "salt", 0)
+
+	tracker.Record("tst", "", "", "")
+	tracker.Record("tst", "", "", "")
+
+	if got := tracker.Dropped(); got != 1 {
+		t.Errorf("Dropped() = %v, want %v", got, 1)
+	}
+}
+
+func TestClickTracker_HashIP(t *testing.T) {
+	tracker := NewClickTracker(nil, 1, time.Hour, "salt", 0)
+	other := NewClickTracker(nil, 1, time.Hour, "other", 0)
+
+	hash := tracker.HashIP("10.0.0.1")
+
+	if hash == "" || strings.Contains(hash, "10.0.0.1") {
+		t.Errorf("HashIP() = %q, want a hash of the address", hash)
+	}
+	if got := tracker.HashIP("10.0.0.1"); got != hash {
+		t.Errorf("HashIP() = %q, want the same hash %q", got, hash)
+	}
+	if got := other.HashIP("10.0.0.1"); got == hash {
+		t.Errorf("HashIP() with another salt = %q, want a different hash", got)
+	}
+	if got := tracker.HashIP(""); got != "" {
+		t.Errorf("HashIP() of an empty address = %q, want empty", got)
+	}
+}
+
+func TestResolveSalt(t *testing.T) {
+	if got, err := ResolveSalt("configured", ""); err != nil || got != "configured" {
+		t.Errorf("ResolveSalt() = %q, %v, want the configured salt", got, err)
+	}
+	if _, err := ResolveSalt("", ""); err == nil {
+		t.Error("ResolveSalt() without salt nor file must fail")
+	}
+
+	file := filepath.Join(t.TempDir(), "salt", "click-salt")
+
+	generated, err := ResolveSalt("", file)
+	if err != nil {
+		t.Fatalf("ResolveSalt() error = %v", err)
+	}
+	if len(generated) != 2*saltSize {
+		t.Errorf("ResolveSalt() = %q, want %d random hex characters", generated, 2*saltSize)
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Fatalf("salt file not written: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("salt file permissions = %v, want 0600", perm)
+	}
+
+	if again, err := ResolveSalt("", file); err != nil || again != generated {
+		t.Errorf("ResolveSalt() after restart = %q, %v, want the persisted %q", again, err, generated)
+	}
+}
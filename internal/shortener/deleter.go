@@ -0,0 +1,203 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+	"github.com/Azzonya/go-shortener/internal/logger"
+	"github.com/Azzonya/go-shortener/internal/repo"
+)
+
+// deleteMaxAttempts is the number of times a failed deletion batch is written before it is given up.
+const deleteMaxAttempts = 3
+
+// deleteRetryDelay is the pause before writing a failed deletion batch again.
+const deleteRetryDelay = 100 * time.Millisecond
+
+// ErrDeletionQueueFull is returned when the deletion queue cannot accept more requests.
+var ErrDeletionQueueFull = errors.New("deletion queue is full")
+
+// Deleter deletes short URLs in the background with a fixed pool of workers.
+// Deletion requests are queued in a bounded buffer, every worker merges the requests
+// of many users into a single repository write, flushed when it holds batchSize URLs
+// or when the flush interval elapses. Requests arriving while the buffer is full are rejected.
+type Deleter struct {
+	repo          repo.Repo                    // Repository the deletions are written to
+	queue         chan *entities.DeleteRequest // Buffer of requests waiting to be written
+	workers       int                          // Number of workers writing the deletions
+	batchSize     int                          // Number of URLs flushing a batch
+	flushInterval time.Duration                // Maximum time a request waits in a batch
+	timeout       time.Duration                // Maximum duration of a single batch write, zero for no limit
+	failed        atomic.Int64                 // Number of URLs whose deletion failed
+	stop          chan struct{}                // Closed to stop the workers
+	wg            sync.WaitGroup               // Waits for the workers to stop
+}
+
+// NewDeleter creates a new deleter buffering up to queueSize deletion requests.
+func NewDeleter(repo repo.Repo, queueSize, workers, batchSize int, flushInterval, timeout time.Duration) *Deleter {
+	return &Deleter{
+		repo:          repo,
+		queue:         make(chan *entities.DeleteRequest, queueSize),
+		workers:       max(workers, 1),
+		batchSize:     max(batchSize, 1),
+		flushInterval: flushInterval,
+		timeout:       timeout,
+		stop:          make(chan struct{}),
+	}
+}
+
+// Enqueue queues the deletion of the user's short URLs. It never blocks,
+// ErrDeletionQueueFull is returned if the buffer is full.
+func (d *Deleter) Enqueue(urls []string, userID string) error {
+	if len(urls) == 0 {
+		return nil
+	}
+
+	select {
+	case d.queue <- &entities.DeleteRequest{UserID: userID, ShortURLs: urls}:
+		return nil
+	default:
+		return ErrDeletionQueueFull
+	}
+}
+
+// QueueDepth returns the number of deletion requests waiting in the buffer.
+func (d *Deleter) QueueDepth() int {
+	return len(d.queue)
+}
+
+// Failed returns the number of URLs whose deletion failed after every attempt.
+func (d *Deleter) Failed() int64 {
+	return d.failed.Load()
+}
+
+// Start runs the workers writing the queued deletions in the background until Stop is called.
+func (d *Deleter) Start() {
+	for i := 0; i < d.workers; i++ {
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			d.work()
+		}()
+	}
+}
+
+// Stop stops the workers after writing the deletions left in the buffer.
+func (d *Deleter) Stop() {
+	close(d.stop)
+	d.wg.Wait()
+}
+
+// work merges the queued requests into batches and writes them until the deleter is stopped.
+func (d *Deleter) work() {
+	ticker := time.NewTicker(d.flushInterval)
+	defer ticker.Stop()
+
+	b := &deleteBatch{}
+
+	for {
+		select {
+		case req := <-d.queue:
+			if b.add(req) >= d.batchSize {
+				d.flush(b)
+			}
+		case <-ticker.C:
+			d.flush(b)
+		case <-d.stop:
+			d.drain(b)
+			return
+		}
+	}
+}
+
+// drain writes the batch and every request left in the buffer.
+func (d *Deleter) drain(b *deleteBatch) {
+	for {
+		select {
+		case req := <-d.queue:
+			if b.add(req) >= d.batchSize {
+				d.flush(b)
+			}
+		default:
+			d.flush(b)
+			return
+		}
+	}
+}
+
+// flush writes the batch to the repository, retrying failed writes, and empties it.
+// URLs of a batch failing every attempt are logged and counted as failed.
+func (d *Deleter) flush(b *deleteBatch) {
+	if b.size == 0 {
+		return
+	}
+
+	requests := b.requests()
+
+	var err error
+	for attempt := 1; attempt <= deleteMaxAttempts; attempt++ {
+		if err = d.writeBatch(requests); err == nil {
+			break
+		}
+
+		logger.Log.Warn("Failed to delete URLs", zap.Int("attempt", attempt), zap.Int("count", b.size), zap.Error(err))
+		if attempt < deleteMaxAttempts {
+			time.Sleep(deleteRetryDelay)
+		}
+	}
+
+	if err != nil {
+		d.failed.Add(int64(b.size))
+		logger.Log.Error("Giving up deleting URLs", zap.Int("count", b.size), zap.Error(err))
+	}
+
+	b.reset()
+}
+
+// writeBatch writes the deletion requests to the repository.
+func (d *Deleter) writeBatch(requests []*entities.DeleteRequest) error {
+	ctx, cancel := withTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	return d.repo.DeleteURLsBatch(ctx, requests)
+}
+
+// deleteBatch merges deletion requests by user.
+type deleteBatch struct {
+	byUser map[string][]string // Short URLs to delete keyed by their owner
+	size   int                 // Number of short URLs in the batch
+}
+
+// add merges the request into the batch and returns the number of short URLs in the batch.
+func (b *deleteBatch) add(req *entities.DeleteRequest) int {
+	if b.byUser == nil {
+		b.byUser = make(map[string][]string)
+	}
+
+	b.byUser[req.UserID] = append(b.byUser[req.UserID], req.ShortURLs...)
+	b.size += len(req.ShortURLs)
+
+	return b.size
+}
+
+// requests returns one deletion request per user of the batch.
+func (b *deleteBatch) requests() []*entities.DeleteRequest {
+	requests := make([]*entities.DeleteRequest, 0, len(b.byUser))
+	for userID, urls := range b.byUser {
+		requests = append(requests, &entities.DeleteRequest{UserID: userID, ShortURLs: urls})
+	}
+
+	return requests
+}
+
+// reset empties the batch.
+func (b *deleteBatch) reset() {
+	clear(b.byUser)
+	b.size = 0
+}
@@ -0,0 +1,78 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Azzonya/go-shortener/internal/repo/inmemory"
+)
+
+func TestDeleter(t *testing.T) {
+	repoTest, err := inmemory.New("")
+	if err != nil {
+		t.Fatalf("inmemory.New() error = %v", err)
+	}
+
+	links := []struct {
+		shortURL string
+		userID   string
+	}{
+		{shortURL: "first", userID: "1"},
+		{shortURL: "second", userID: "1"},
+		{shortURL: "third", userID: "2"},
+		{shortURL: "kept", userID: "2"},
+	}
+	for _, l := range links {
+		if err = repoTest.Add(context.Background(), l.shortURL+".com", l.shortURL, l.userID, nil); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	deleter := NewDeleter(repoTest, 10, 2, 100, time.Hour, 0)
+	deleter.Start()
+
+	for _, req := range []struct {
+		urls   []string
+		userID string
+	}{
+		{urls: []string{"first"}, userID: "1"},
+		{urls: []string{"second", "kept"}, userID: "1"},
+		{urls: []string{"third"}, userID: "2"},
+	} {
+		if err = deleter.Enqueue(req.urls, req.userID); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+
+	deleter.Stop()
+
+	for _, shortURL := range []string{"first", "second", "third"} {
+		if !repoTest.URLDeleted(context.Background(), shortURL) {
+			t.Errorf("URLDeleted(%q) = false, want true", shortURL)
+		}
+	}
+	if repoTest.URLDeleted(context.Background(), "kept") {
+		t.Errorf("URLDeleted(%q) = true, want false for a URL of another user", "kept")
+	}
+	if got := deleter.Failed(); got != 0 {
+		t.Errorf("Failed() = %v, want %v", got, 0)
+	}
+}
+
+func TestDeleter_EnqueueFullQueue(t *testing.T) {
+	deleter := NewDeleter(nil, 1, 1, 100, time.Hour, 0)
+
+	if err := deleter.Enqueue([]string{"first"}, "1"); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	if err := deleter.Enqueue([]string{"second"}, "1"); !errors.Is(err, ErrDeletionQueueFull) {
+		t.Errorf("Enqueue() error = %v, want %v", err, ErrDeletionQueueFull)
+	}
+
+	if got := deleter.QueueDepth(); got != 1 {
+		t.Errorf("QueueDepth() = %v, want %v", got, 1)
+	}
+}
@@ -0,0 +1,110 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Azzonya/go-shortener/internal/logger"
+	"github.com/Azzonya/go-shortener/internal/repo"
+)
+
+// ErrInvalidExpiration is returned for expiration settings that can not be applied to a link.
+var ErrInvalidExpiration = errors.New("invalid expiration")
+
+// ResolveExpiration returns the expiration time of a link given either as an absolute time or
+// as a time-to-live in seconds. It returns nil if neither is set, meaning the link never expires.
+// The returned error wraps ErrInvalidExpiration.
+func ResolveExpiration(expiresAt *time.Time, ttl int64) (*time.Time, error) {
+	now := time.Now()
+
+	switch {
+	case expiresAt != nil && ttl != 0:
+		return nil, fmt.Errorf("%w: expires_at and ttl can not be used together", ErrInvalidExpiration)
+	case ttl < 0:
+		return nil, fmt.Errorf("%w: ttl must be positive", ErrInvalidExpiration)
+	case ttl > 0:
+		result := now.Add(time.Duration(ttl) * time.Second).UTC()
+		return &result, nil
+	case expiresAt != nil:
+		if !expiresAt.After(now) {
+			return nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidExpiration)
+		}
+		result := expiresAt.UTC()
+		return &result, nil
+	default:
+		return nil, nil
+	}
+}
+
+// Sweeper periodically removes the links which expired longer than the retention period ago.
+// Until then, expired links are kept so that redirects to them are answered with 410 Gone.
+type Sweeper struct {
+	repo      repo.Repo     // Repository the expired links are removed from
+	interval  time.Duration // Time between two sweeps
+	retention time.Duration // Time expired links are kept before being removed
+	archive   bool          // Whether removed links are archived instead of purged
+	timeout   time.Duration // Maximum duration of a single sweep, zero for no limit
+	stop      chan struct{} // Closed to stop the sweeper
+	done      chan struct{} // Closed once the sweeper has stopped
+}
+
+// NewSweeper creates a new sweeper of expired links.
+func NewSweeper(repo repo.Repo, interval, retention time.Duration, archive bool, timeout time.Duration) *Sweeper {
+	return &Sweeper{
+		repo:      repo,
+		interval:  interval,
+		retention: retention,
+		archive:   archive,
+		timeout:   timeout,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Start runs the sweeper in the background until Stop is called.
+func (w *Sweeper) Start() {
+	go func() {
+		defer close(w.done)
+
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+				if _, err := w.Sweep(context.Background()); err != nil {
+					logger.Log.Error("Failed to sweep expired URLs", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops the sweeper and waits for the running sweep to finish.
+func (w *Sweeper) Stop() {
+	close(w.stop)
+	<-w.done
+}
+
+// Sweep removes the links which expired longer than the retention period ago and returns their number.
+func (w *Sweeper) Sweep(ctx context.Context) (int64, error) {
+	ctx, cancel := withTimeout(ctx, w.timeout)
+	defer cancel()
+
+	count, err := w.repo.DeleteExpired(ctx, time.Now().Add(-w.retention), w.archive)
+	if err != nil {
+		return 0, err
+	}
+
+	if count > 0 {
+		logger.Log.Info("Swept expired URLs", zap.Int64("count", count), zap.Bool("archived", w.archive))
+	}
+
+	return count, nil
+}
@@ -0,0 +1,97 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Azzonya/go-shortener/internal/repo/inmemory"
+)
+
+func TestResolveExpiration(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name      string
+		expiresAt *time.Time
+		ttl       int64
+		want      *time.Time
+		wantErr   bool
+	}{
+		{name: "never expires"},
+		{name: "absolute time", expiresAt: &future, want: &future},
+		{name: "ttl", ttl: 60, want: func() *time.Time { t := time.Now().Add(time.Minute); return &t }()},
+		{name: "past time", expiresAt: &past, wantErr: true},
+		{name: "negative ttl", ttl: -1, wantErr: true},
+		{name: "both set", expiresAt: &future, ttl: 60, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveExpiration(tt.expiresAt, tt.ttl)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidExpiration) {
+					t.Errorf("ResolveExpiration() error = %v, want %v", err, ErrInvalidExpiration)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveExpiration() error = %v", err)
+			}
+
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("ResolveExpiration() = %v, want nil", got)
+				}
+				return
+			}
+			if got == nil || got.Sub(*tt.want).Abs() > time.Second {
+				t.Errorf("ResolveExpiration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSweeper_Sweep(t *testing.T) {
+	repoTest, err := inmemory.New("")
+	if err != nil {
+		t.Fatalf("inmemory.New() error = %v", err)
+	}
+
+	longAgo := time.Now().Add(-48 * time.Hour)
+	recently := time.Now().Add(-time.Hour)
+	later := time.Now().Add(time.Hour)
+
+	links := []struct {
+		shortURL  string
+		expiresAt *time.Time
+	}{
+		{shortURL: "long-ago", expiresAt: &longAgo},
+		{shortURL: "recently", expiresAt: &recently},
+		{shortURL: "later", expiresAt: &later},
+		{shortURL: "never"},
+	}
+	for _, link := range links {
+		if err = repoTest.Add(context.Background(), link.shortURL+".com", link.shortURL, "1", link.expiresAt); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	sweeper := NewSweeper(repoTest, time.Minute, 24*time.Hour, false, 0)
+
+	count, err := sweeper.Sweep(context.Background())
+	if err != nil {
+		t.Fatalf("Sweep() error = %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Sweep() = %v, want %v", count, 1)
+	}
+
+	for _, link := range links {
+		_, exist := repoTest.GetByShortURL(context.Background(), link.shortURL)
+		if want := link.shortURL != "long-ago"; exist != want {
+			t.Errorf("GetByShortURL(%q) exist = %v, want %v", link.shortURL, exist, want)
+		}
+	}
+}
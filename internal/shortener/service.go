@@ -9,38 +9,97 @@
 package shortener
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/url"
+	"time"
 
-	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 
+	"github.com/Azzonya/go-shortener/internal/codegen"
 	"github.com/Azzonya/go-shortener/internal/entities"
-	"github.com/Azzonya/go-shortener/internal/logger"
 	"github.com/Azzonya/go-shortener/internal/repo"
+	"github.com/Azzonya/go-shortener/internal/tracing"
 )
 
+// maxGenerateAttempts is the number of short codes tried before giving up on collisions.
+const maxGenerateAttempts = 5
+
+// defaultCodeLength is the length of random short codes generated when no generator is configured.
+const defaultCodeLength = 8
+
+// ErrShortURLCollision is returned when every generated short code is already taken.
+var ErrShortURLCollision = errors.New("cannot generate a unique short URL")
+
+// ErrURLGone is returned when the short URL was deleted, disabled or has expired.
+var ErrURLGone = errors.New("short URL is gone")
+
 // Shortener represents the URL shortener service.
 type Shortener struct {
-	repo    repo.Repo // Repository for storing and retrieving shortened URLs
-	baseURL string    // Base URL used for constructing shortened URLs
+	repo         repo.Repo             // Repository for storing and retrieving shortened URLs
+	baseURL      string                // Base URL used for constructing shortened URLs
+	codes        codegen.CodeGenerator // Generator of short codes, random codes are used if nil
+	readTimeout  time.Duration         // Maximum duration of a single repository read, zero for no limit
+	writeTimeout time.Duration         // Maximum duration of a single repository write, zero for no limit
+	deleter      *Deleter              // Background deleter of URLs, URLs are deleted synchronously if nil
 }
 
 // New creates a new instance of the Shortener struct.
-func New(baseURL string, repo repo.Repo) *Shortener {
+// Short codes are produced by the code generator, random codes are used if it is nil.
+// Every repository call is limited by the read or write timeout, zero disables the limit.
+func New(baseURL string, repo repo.Repo, codes codegen.CodeGenerator, readTimeout, writeTimeout time.Duration) *Shortener {
 	return &Shortener{
-		baseURL: baseURL,
-		repo:    repo,
+		baseURL:      baseURL,
+		repo:         repo,
+		codes:        codes,
+		readTimeout:  readTimeout,
+		writeTimeout: writeTimeout,
 	}
 }
 
 // GetOneByShortURL retrieves the original URL associated with a given short URL.
-func (s *Shortener) GetOneByShortURL(key string) (string, bool) {
-	return s.repo.GetByShortURL(key)
+func (s *Shortener) GetOneByShortURL(ctx context.Context, key string) (string, bool) {
+	ctx, span := tracing.Start(ctx, "Shortener.GetOneByShortURL")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, s.readTimeout)
+	defer cancel()
+
+	return s.repo.GetByShortURL(ctx, key)
+}
+
+// Resolve returns the link of the short URL with a single repository lookup.
+// It returns entities.ErrURLNotFound if the short URL does not exist, and the link
+// together with ErrURLGone if it was deleted, disabled or has expired.
+func (s *Shortener) Resolve(ctx context.Context, shortURL string) (_ *entities.Link, err error) {
+	ctx, span := tracing.Start(ctx, "Shortener.Resolve")
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := withTimeout(ctx, s.readTimeout)
+	defer cancel()
+
+	link, err := s.repo.GetLink(ctx, shortURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if !link.Active(time.Now()) {
+		return link, ErrURLGone
+	}
+
+	return link, nil
 }
 
 // GetOneByOriginalURL retrieves the short URL associated with a given original URL.
-func (s *Shortener) GetOneByOriginalURL(url string) (string, bool) {
-	URL, exist := s.repo.GetByOriginalURL(url)
+func (s *Shortener) GetOneByOriginalURL(ctx context.Context, url string) (string, bool) {
+	ctx, span := tracing.Start(ctx, "Shortener.GetOneByOriginalURL")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, s.readTimeout)
+	defer cancel()
+
+	URL, exist := s.repo.GetByOriginalURL(ctx, url)
 	if !exist {
 		return "", false
 	}
@@ -51,8 +110,14 @@ func (s *Shortener) GetOneByOriginalURL(url string) (string, bool) {
 }
 
 // ListAll retrieves a list of all shortened URLs associated with a given user ID.
-func (s *Shortener) ListAll(userID string) ([]*entities.ReqListAll, error) {
-	list, err := s.repo.ListAll(userID)
+func (s *Shortener) ListAll(ctx context.Context, userID string) (_ []*entities.ReqListAll, err error) {
+	ctx, span := tracing.Start(ctx, "Shortener.ListAll")
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := withTimeout(ctx, s.readTimeout)
+	defer cancel()
+
+	list, err := s.repo.ListAll(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -64,36 +129,127 @@ func (s *Shortener) ListAll(userID string) ([]*entities.ReqListAll, error) {
 }
 
 // ShortenAndSaveLink generates a short URL for a given original URL and saves it in the repository.
-func (s *Shortener) ShortenAndSaveLink(originalURL, userID string) (string, error) {
-	shortURL := s.GenerateShortURL()
-	if err := s.repo.Add(originalURL, shortURL, userID); err != nil {
+// A new short code is generated if the previous one is already taken, up to maxGenerateAttempts times.
+// The link expires at expiresAt, a nil expiresAt means it never expires.
+func (s *Shortener) ShortenAndSaveLink(ctx context.Context, originalURL, userID string, expiresAt *time.Time) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "Shortener.ShortenAndSaveLink")
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := withTimeout(ctx, s.writeTimeout)
+	defer cancel()
+
+	for attempt := 0; attempt < maxGenerateAttempts; attempt++ {
+		shortURL, err := s.GenerateShortURL(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		err = s.repo.Add(ctx, originalURL, shortURL, userID, expiresAt)
+		if errors.Is(err, entities.ErrShortURLExists) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		outputURL := fmt.Sprintf("%s/%s", s.baseURL, shortURL)
+
+		return outputURL, nil
+	}
+
+	return "", ErrShortURLCollision
+}
+
+// ShortenAndSaveAlias saves the original URL under the custom alias instead of a generated short code.
+// It returns an error wrapping ErrInvalidAlias if the alias fails validation
+// and ErrAliasTaken if another link already uses it.
+func (s *Shortener) ShortenAndSaveAlias(ctx context.Context, originalURL, alias, userID string, expiresAt *time.Time) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "Shortener.ShortenAndSaveAlias")
+	defer func() { tracing.End(span, err) }()
+
+	if err = ValidateAlias(alias); err != nil {
 		return "", err
 	}
 
-	outputURL := fmt.Sprintf("%s/%s", s.baseURL, shortURL)
+	ctx, cancel := withTimeout(ctx, s.writeTimeout)
+	defer cancel()
+
+	err = s.repo.Add(ctx, originalURL, alias, userID, expiresAt)
+	if errors.Is(err, entities.ErrShortURLExists) {
+		return "", fmt.Errorf("%w: %q", ErrAliasTaken, alias)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	outputURL := fmt.Sprintf("%s/%s", s.baseURL, alias)
 
 	return outputURL, nil
 }
 
 // ShortenURLs shortens multiple URLs simultaneously and saves them in the repository.
-func (s *Shortener) ShortenURLs(urls []*entities.ReqURL, userID string) ([]*entities.ReqURL, error) {
-	shortenedURLs := make([]*entities.ReqURL, len(urls))
+// URLs with an alias are saved under it, the others get generated short codes.
+// Every URL may expire at its own time given by expires_at or ttl.
+// The batch is stored atomically, so all short codes are regenerated if any of them is already taken,
+// unless the taken one is an alias, then an error wrapping ErrAliasTaken is returned.
+func (s *Shortener) ShortenURLs(ctx context.Context, urls []*entities.ReqURL, userID string) (_ []*entities.ReqURL, err error) {
+	ctx, span := tracing.Start(ctx, "Shortener.ShortenURLs", attribute.Int("urls", len(urls)))
+	defer func() { tracing.End(span, err) }()
 
+	if err = validateBatchAliases(urls); err != nil {
+		return nil, err
+	}
+
+	expirations := make([]*time.Time, len(urls))
 	for i, u := range urls {
-		shortURL := s.GenerateShortURL()
+		expiresAt, err := ResolveExpiration(u.ExpiresAt, u.TTL)
+		if err != nil {
+			return nil, err
+		}
+		expirations[i] = expiresAt
+	}
 
-		shortenedURLs[i] = &entities.ReqURL{
-			ID:          u.ID,
-			OriginalURL: u.OriginalURL,
-			ShortURL:    shortURL,
+	ctx, cancel := withTimeout(ctx, s.writeTimeout)
+	defer cancel()
+
+	shortenedURLs := make([]*entities.ReqURL, len(urls))
+
+	for attempt := 0; ; attempt++ {
+		if attempt == maxGenerateAttempts {
+			return nil, ErrShortURLCollision
 		}
 
-		urls[i].ShortURL = shortURL
-	}
+		for i, u := range urls {
+			shortURL := u.Alias
+			if shortURL == "" {
+				var err error
+				if shortURL, err = s.GenerateShortURL(ctx); err != nil {
+					return nil, err
+				}
+			}
 
-	err := s.repo.CreateShortURLs(shortenedURLs, userID)
-	if err != nil {
-		return nil, err
+			shortenedURLs[i] = &entities.ReqURL{
+				ID:          u.ID,
+				OriginalURL: u.OriginalURL,
+				ShortURL:    shortURL,
+				ExpiresAt:   expirations[i],
+			}
+
+			urls[i].ShortURL = shortURL
+		}
+
+		err := s.repo.CreateShortURLs(ctx, shortenedURLs, userID)
+		if errors.Is(err, entities.ErrShortURLExists) {
+			if alias, taken := s.takenAlias(ctx, urls); taken {
+				return nil, fmt.Errorf("%w: %q", ErrAliasTaken, alias)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		break
 	}
 
 	for i, v := range shortenedURLs {
@@ -109,28 +265,148 @@ func (s *Shortener) ShortenURLs(urls []*entities.ReqURL, userID string) ([]*enti
 	return shortenedURLs, nil
 }
 
+// SetDeleter makes DeleteURLs queue the deletions to the background deleter.
+func (s *Shortener) SetDeleter(d *Deleter) {
+	s.deleter = d
+}
+
 // DeleteURLs deletes URLs associated with a given user ID.
-func (s *Shortener) DeleteURLs(urls []string, userID string) {
-	go func() {
-		if err := s.repo.DeleteURLs(urls, userID); err != nil {
-			logger.Log.Error("Failed to delete URLs " + err.Error())
-		}
-	}()
+// With a deleter set, the deletion is queued and runs in the background,
+// ErrDeletionQueueFull is returned if the queue is saturated.
+// Otherwise the URLs are deleted before returning, limited by the write timeout.
+func (s *Shortener) DeleteURLs(ctx context.Context, urls []string, userID string) (err error) {
+	ctx, span := tracing.Start(ctx, "Shortener.DeleteURLs", attribute.Int("urls", len(urls)))
+	defer func() { tracing.End(span, err) }()
+
+	if s.deleter != nil {
+		return s.deleter.Enqueue(urls, userID)
+	}
+
+	ctx, cancel := withTimeout(ctx, s.writeTimeout)
+	defer cancel()
+
+	return s.repo.DeleteURLs(ctx, urls, userID)
 }
 
 // IsDeleted checks if a given short URL has been deleted.
-func (s *Shortener) IsDeleted(shortURL string) bool {
-	return s.repo.URLDeleted(shortURL)
+func (s *Shortener) IsDeleted(ctx context.Context, shortURL string) bool {
+	ctx, span := tracing.Start(ctx, "Shortener.IsDeleted")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, s.readTimeout)
+	defer cancel()
+
+	link, err := s.repo.GetLink(ctx, shortURL)
+
+	return err == nil && link.Deleted
+}
+
+// IsExpired checks if a given short URL has expired.
+func (s *Shortener) IsExpired(ctx context.Context, shortURL string) bool {
+	ctx, span := tracing.Start(ctx, "Shortener.IsExpired")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, s.readTimeout)
+	defer cancel()
+
+	link, err := s.repo.GetLink(ctx, shortURL)
+
+	return err == nil && link.Expired(time.Now())
+}
+
+// LinkStats returns the click statistics of the short URL owned by the user,
+// with the histogram split into buckets of the given duration.
+// It returns entities.ErrURLNotFound if the user has no such URL.
+func (s *Shortener) LinkStats(ctx context.Context, shortURL, userID string, bucket time.Duration) (_ *entities.LinkStats, err error) {
+	ctx, span := tracing.Start(ctx, "Shortener.LinkStats")
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := withTimeout(ctx, s.readTimeout)
+	defer cancel()
+
+	return s.repo.ClickStats(ctx, shortURL, userID, bucket)
+}
+
+// Stats returns the usage statistics of the whole service.
+func (s *Shortener) Stats(ctx context.Context) (_ *entities.ServiceStats, err error) {
+	ctx, span := tracing.Start(ctx, "Shortener.Stats")
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := withTimeout(ctx, s.readTimeout)
+	defer cancel()
+
+	return s.repo.Stats(ctx)
+}
+
+// takenAlias returns the first alias of the batch already used by a stored link.
+func (s *Shortener) takenAlias(ctx context.Context, urls []*entities.ReqURL) (string, bool) {
+	for _, u := range urls {
+		if u.Alias == "" {
+			continue
+		}
+
+		if _, exist := s.repo.GetByShortURL(ctx, u.Alias); exist {
+			return u.Alias, true
+		}
+	}
+
+	return "", false
+}
+
+// validateBatchAliases validates the aliases of the batch and checks that none of them is used twice.
+func validateBatchAliases(urls []*entities.ReqURL) error {
+	aliases := make(map[string]struct{})
+
+	for _, u := range urls {
+		if u.Alias == "" {
+			continue
+		}
+
+		if err := ValidateAlias(u.Alias); err != nil {
+			return err
+		}
+
+		if _, exist := aliases[u.Alias]; exist {
+			return fmt.Errorf("%w: %q is used twice in the batch", ErrAliasTaken, u.Alias)
+		}
+		aliases[u.Alias] = struct{}{}
+	}
+
+	return nil
 }
 
-// GenerateShortURL generates a unique short URL using UUID.
-func (s *Shortener) GenerateShortURL() string {
-	return uuid.New().String()
+// GenerateShortURL generates a short URL code with the configured generator.
+// The code is not guaranteed to be unused, the repository rejects duplicates.
+func (s *Shortener) GenerateShortURL(ctx context.Context) (string, error) {
+	codes := s.codes
+	if codes == nil {
+		random, err := codegen.NewRandom(defaultCodeLength)
+		if err != nil {
+			return "", err
+		}
+		codes = random
+	}
+
+	return codes.Generate(ctx)
 }
 
 // PingDB pings the database to check its connectivity.
-func (s *Shortener) PingDB() error {
-	err := s.repo.Ping()
+func (s *Shortener) PingDB(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "Shortener.PingDB")
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := withTimeout(ctx, s.readTimeout)
+	defer cancel()
+
+	err = s.repo.Ping(ctx)
 
 	return err
 }
+
+// withTimeout returns a copy of ctx limited by the timeout, or a cancelable copy if the timeout is not set.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
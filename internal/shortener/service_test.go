@@ -1,8 +1,13 @@
 package shortener
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/Azzonya/go-shortener/internal/repo"
 
@@ -11,7 +16,7 @@ import (
 )
 
 const BaseURL = "http://localhost:8080"
-const fileStoragePath = "/tmp/short-url-repo.json"
+const fileStorageName = "short-url-repo.json"
 
 func BenchmarkService(b *testing.B) {
 	//db, err := pkg.InitDatabasePg(PgDsn)
@@ -19,12 +24,12 @@ func BenchmarkService(b *testing.B) {
 	//	panic(err)
 	//}
 
-	repoTest, err := inmemory.New("/tmp/short-url-repo.json") //pg.New(db)
+	repoTest, err := inmemory.New(filepath.Join(b.TempDir(), fileStorageName)) //pg.New(db)
 	if err != nil {
 		panic(err)
 	}
 
-	shortener := New(BaseURL, repoTest)
+	shortener := New(BaseURL, repoTest, nil, 0, 0)
 
 	urls := []*entities.ReqURL{
 		{
@@ -43,31 +48,31 @@ func BenchmarkService(b *testing.B) {
 
 	b.Run("generate_shortURL", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
-			shortener.GenerateShortURL()
+			shortener.GenerateShortURL(context.Background())
 		}
 	})
 
 	b.Run("shorten_urls", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
-			shortener.ShortenURLs(urls, "1")
+			shortener.ShortenURLs(context.Background(), urls, "1")
 		}
 	})
 
 	b.Run("shorten_and_save_link", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
-			shortener.ShortenAndSaveLink("ysadsadas", "1")
+			shortener.ShortenAndSaveLink(context.Background(), "ysadsadas", "1", nil)
 		}
 	})
 
 	b.Run("get_one_by_originalURL", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
-			shortener.GetOneByOriginalURL("blab2la.com")
+			shortener.GetOneByOriginalURL(context.Background(), "blab2la.com")
 		}
 	})
 }
 
 func TestNew(t *testing.T) {
-	repoTest, err := inmemory.New(fileStoragePath)
+	repoTest, err := inmemory.New(filepath.Join(t.TempDir(), fileStorageName))
 	if err != nil {
 		panic(err)
 	}
@@ -95,7 +100,7 @@ func TestNew(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := New(tt.args.baseURL, tt.args.repo); !reflect.DeepEqual(got, tt.want) {
+			if got := New(tt.args.baseURL, tt.args.repo, nil, 0, 0); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("New() = %v, want %v", got, tt.want)
 			}
 		})
@@ -103,7 +108,7 @@ func TestNew(t *testing.T) {
 }
 
 func TestShortener_DeleteURLs(t *testing.T) {
-	repoTest, err := inmemory.New(fileStoragePath)
+	repoTest, err := inmemory.New(filepath.Join(t.TempDir(), fileStorageName))
 	if err != nil {
 		panic(err)
 	}
@@ -139,13 +144,13 @@ func TestShortener_DeleteURLs(t *testing.T) {
 				repo:    tt.fields.repo,
 				baseURL: tt.fields.baseURL,
 			}
-			s.DeleteURLs(tt.args.urls, tt.args.userID)
+			s.DeleteURLs(context.Background(), tt.args.urls, tt.args.userID)
 		})
 	}
 }
 
 func TestShortener_GenerateShortURL(t *testing.T) {
-	repoTest, err := inmemory.New(fileStoragePath)
+	repoTest, err := inmemory.New(filepath.Join(t.TempDir(), fileStorageName))
 	if err != nil {
 		panic(err)
 	}
@@ -172,7 +177,11 @@ func TestShortener_GenerateShortURL(t *testing.T) {
 				repo:    tt.fields.repo,
 				baseURL: tt.fields.baseURL,
 			}
-			if got := s.GenerateShortURL(); got == "" {
+			got, err := s.GenerateShortURL(context.Background())
+			if err != nil {
+				t.Fatalf("GenerateShortURL() error = %v", err)
+			}
+			if got == "" {
 				t.Errorf("GenerateShortURL() = %v, want not empty", got)
 			}
 		})
@@ -202,7 +211,7 @@ func TestShortener_GetOneByOriginalURL(t *testing.T) {
 				repo:    tt.fields.repo,
 				baseURL: tt.fields.baseURL,
 			}
-			got, got1 := s.GetOneByOriginalURL(tt.args.url)
+			got, got1 := s.GetOneByOriginalURL(context.Background(), tt.args.url)
 			if got != tt.want {
 				t.Errorf("GetOneByOriginalURL() got = %v, want %v", got, tt.want)
 			}
@@ -236,7 +245,7 @@ func TestShortener_GetOneByShortURL(t *testing.T) {
 				repo:    tt.fields.repo,
 				baseURL: tt.fields.baseURL,
 			}
-			got, got1 := s.GetOneByShortURL(tt.args.key)
+			got, got1 := s.GetOneByShortURL(context.Background(), tt.args.key)
 			if got != tt.want {
 				t.Errorf("GetOneByShortURL() got = %v, want %v", got, tt.want)
 			}
@@ -269,7 +278,7 @@ func TestShortener_IsDeleted(t *testing.T) {
 				repo:    tt.fields.repo,
 				baseURL: tt.fields.baseURL,
 			}
-			if got := s.IsDeleted(tt.args.shortURL); got != tt.want {
+			if got := s.IsDeleted(context.Background(), tt.args.shortURL); got != tt.want {
 				t.Errorf("IsDeleted() = %v, want %v", got, tt.want)
 			}
 		})
@@ -299,7 +308,7 @@ func TestShortener_ListAll(t *testing.T) {
 				repo:    tt.fields.repo,
 				baseURL: tt.fields.baseURL,
 			}
-			got, err := s.ListAll(tt.args.userID)
+			got, err := s.ListAll(context.Background(), tt.args.userID)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ListAll() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -329,7 +338,7 @@ func TestShortener_PingDB(t *testing.T) {
 				repo:    tt.fields.repo,
 				baseURL: tt.fields.baseURL,
 			}
-			if err := s.PingDB(); (err != nil) != tt.wantErr {
+			if err := s.PingDB(context.Background()); (err != nil) != tt.wantErr {
 				t.Errorf("PingDB() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -360,7 +369,7 @@ func TestShortener_ShortenAndSaveLink(t *testing.T) {
 				repo:    tt.fields.repo,
 				baseURL: tt.fields.baseURL,
 			}
-			got, err := s.ShortenAndSaveLink(tt.args.originalURL, tt.args.userID)
+			got, err := s.ShortenAndSaveLink(context.Background(), tt.args.originalURL, tt.args.userID, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ShortenAndSaveLink() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -373,7 +382,7 @@ func TestShortener_ShortenAndSaveLink(t *testing.T) {
 }
 
 func TestShortener_ShortenURLs(t *testing.T) {
-	repoTest, err := inmemory.New(fileStoragePath)
+	repoTest, err := inmemory.New(filepath.Join(t.TempDir(), fileStorageName))
 	if err != nil {
 		panic(err)
 	}
@@ -421,7 +430,7 @@ func TestShortener_ShortenURLs(t *testing.T) {
 				repo:    tt.fields.repo,
 				baseURL: tt.fields.baseURL,
 			}
-			got, err := s.ShortenURLs(tt.args.urls, tt.args.userID)
+			got, err := s.ShortenURLs(context.Background(), tt.args.urls, tt.args.userID)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ShortenURLs() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -432,3 +441,146 @@ func TestShortener_ShortenURLs(t *testing.T) {
 		})
 	}
 }
+
+func TestWithTimeout(t *testing.T) {
+	tests := []struct {
+		name         string
+		timeout      time.Duration
+		wantDeadline bool
+	}{
+		{
+			name:         "timeout disabled",
+			timeout:      0,
+			wantDeadline: false,
+		},
+		{
+			name:         "timeout set",
+			timeout:      time.Second,
+			wantDeadline: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := withTimeout(context.Background(), tt.timeout)
+			defer cancel()
+
+			if _, got := ctx.Deadline(); got != tt.wantDeadline {
+				t.Errorf("withTimeout() deadline set = %v, want %v", got, tt.wantDeadline)
+			}
+
+			cancel()
+			if ctx.Err() == nil {
+				t.Errorf("withTimeout() context is not canceled by cancel func")
+			}
+		})
+	}
+}
+
+// stubCodes returns the given codes in order.
+type stubCodes struct {
+	codes []string
+}
+
+// Generate returns the next stub code.
+func (g *stubCodes) Generate(_ context.Context) (string, error) {
+	code := g.codes[0]
+	g.codes = g.codes[1:]
+	return code, nil
+}
+
+func TestShortener_ShortenAndSaveLinkCollision(t *testing.T) {
+	repoTest, err := inmemory.New("")
+	if err != nil {
+		t.Fatalf("inmemory.New() error = %v", err)
+	}
+
+	if err = repoTest.Add(context.Background(), "taken.com", "taken", "1", nil); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		codes   []string
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "retry on taken code",
+			codes: []string{"taken", "free"},
+			want:  BaseURL + "/free",
+		},
+		{
+			name:    "give up after max attempts",
+			codes:   []string{"taken", "taken", "taken", "taken", "taken"},
+			wantErr: ErrShortURLCollision,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(BaseURL, repoTest, &stubCodes{codes: tt.codes}, 0, 0)
+
+			got, err := s.ShortenAndSaveLink(context.Background(), fmt.Sprintf("new%d.com", i), "1", nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ShortenAndSaveLink() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ShortenAndSaveLink() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShortener_Resolve(t *testing.T) {
+	repoTest, err := inmemory.New("")
+	if err != nil {
+		t.Fatalf("inmemory.New() error = %v", err)
+	}
+
+	expired := time.Now().Add(-time.Hour)
+	for _, u := range []struct {
+		originalURL, shortURL string
+		expiresAt             *time.Time
+	}{
+		{originalURL: "active.com", shortURL: "active"},
+		{originalURL: "deleted.com", shortURL: "deleted"},
+		{originalURL: "expired.com", shortURL: "expired", expiresAt: &expired},
+	} {
+		if err = repoTest.Add(context.Background(), u.originalURL, u.shortURL, "1", u.expiresAt); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	if err = repoTest.DeleteURLs(context.Background(), []string{"deleted"}, "1"); err != nil {
+		t.Fatalf("DeleteURLs() error = %v", err)
+	}
+
+	s := New(BaseURL, repoTest, nil, 0, 0)
+
+	tests := []struct {
+		name     string
+		shortURL string
+		want     string
+		wantErr  error
+	}{
+		{name: "active link", shortURL: "active", want: "active.com"},
+		{name: "deleted link", shortURL: "deleted", want: "deleted.com", wantErr: ErrURLGone},
+		{name: "expired link", shortURL: "expired", want: "expired.com", wantErr: ErrURLGone},
+		{name: "missing link", shortURL: "missing", wantErr: entities.ErrURLNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			link, err := s.Resolve(context.Background(), tt.shortURL)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want == "" {
+				if link != nil {
+					t.Errorf("Resolve() = %+v, want nil", link)
+				}
+				return
+			}
+			if link.OriginalURL != tt.want {
+				t.Errorf("Resolve().OriginalURL = %v, want %v", link.OriginalURL, tt.want)
+			}
+		})
+	}
+}
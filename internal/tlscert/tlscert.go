@@ -0,0 +1,137 @@
+// Package tlscert provides the certificates of the HTTPS server,
+// generating a self-signed one when no certificate is configured.
+package tlscert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Names of the self-signed certificate files in the cache directory.
+const (
+	selfSignedCertFile = "self-signed-cert.pem" // selfSignedCertFile is the PEM encoded certificate.
+	selfSignedKeyFile  = "self-signed-key.pem"  // selfSignedKeyFile is the PEM encoded private key.
+)
+
+// selfSignedValidity is the validity period of generated certificates.
+const selfSignedValidity = 365 * 24 * time.Hour
+
+// renewBefore is how long before its expiration a cached certificate is regenerated.
+const renewBefore = 7 * 24 * time.Hour
+
+// Resolve returns the certificate and key files the HTTPS server should use.
+// Configured files are returned as is and must both be set. Without them, a self-signed
+// certificate for the hosts is generated in the cache directory on first use,
+// and reused until it is about to expire.
+func Resolve(certFile, keyFile, cacheDir string, hosts []string) (string, string, error) {
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return "", "", errors.New("both TLS certificate and key files must be set")
+		}
+		return certFile, keyFile, nil
+	}
+
+	certFile = filepath.Join(cacheDir, selfSignedCertFile)
+	keyFile = filepath.Join(cacheDir, selfSignedKeyFile)
+
+	if valid(certFile, keyFile, time.Now().Add(renewBefore)) {
+		return certFile, keyFile, nil
+	}
+
+	if err := os.MkdirAll(cacheDir, 0700); err != nil {
+		return "", "", fmt.Errorf("cannot create certificate cache directory: %w", err)
+	}
+
+	if err := GenerateSelfSigned(certFile, keyFile, hosts); err != nil {
+		return "", "", err
+	}
+
+	return certFile, keyFile, nil
+}
+
+// GenerateSelfSigned writes a new self-signed certificate valid for the hosts and its private key.
+// Hosts may be DNS names or IP addresses.
+func GenerateSelfSigned(certFile, keyFile string, hosts []string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("cannot generate private key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return fmt.Errorf("cannot generate serial number: %w", err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"go-shortener"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if host != "" {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return fmt.Errorf("cannot create certificate: %w", err)
+	}
+
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("cannot encode private key: %w", err)
+	}
+
+	if err = writePEM(keyFile, "PRIVATE KEY", keyDer, 0600); err != nil {
+		return err
+	}
+
+	return writePEM(certFile, "CERTIFICATE", der, 0644)
+}
+
+// valid checks that the certificate and key files form a pair which is still valid at the given time.
+func valid(certFile, keyFile string, at time.Time) bool {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return false
+	}
+
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return false
+	}
+
+	return at.Before(cert.NotAfter)
+}
+
+// writePEM writes the PEM encoded block to the file with the given permissions.
+func writePEM(path, blockType string, der []byte, perm os.FileMode) error {
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+
+	if err := os.WriteFile(path, data, perm); err != nil {
+		return fmt.Errorf("cannot write %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package tlscert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve_SelfSigned(t *testing.T) {
+	cacheDir := filepath.Join(t.TempDir(), "tls")
+
+	certFile, keyFile, err := Resolve("", "", cacheDir, []string{"localhost", "127.0.0.1"})
+	require.NoError(t, err)
+
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	require.NoError(t, err)
+	assert.NoError(t, cert.VerifyHostname("localhost"))
+	assert.NoError(t, cert.VerifyHostname("127.0.0.1"))
+
+	generated, err := os.ReadFile(certFile)
+	require.NoError(t, err)
+
+	certFile, _, err = Resolve("", "", cacheDir, []string{"localhost"})
+	require.NoError(t, err)
+
+	cached, err := os.ReadFile(certFile)
+	require.NoError(t, err)
+	assert.Equal(t, generated, cached, "the cached certificate must be reused")
+}
+
+func TestResolve_Configured(t *testing.T) {
+	tests := []struct {
+		name     string
+		certFile string
+		keyFile  string
+		wantErr  bool
+	}{
+		{name: "both files", certFile: "cert.pem", keyFile: "key.pem"},
+		{name: "certificate only", certFile: "cert.pem", wantErr: true},
+		{name: "key only", keyFile: "key.pem", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			certFile, keyFile, err := Resolve(tt.certFile, tt.keyFile, t.TempDir(), nil)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.certFile, certFile)
+			assert.Equal(t, tt.keyFile, keyFile)
+		})
+	}
+}
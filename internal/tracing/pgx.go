@@ -0,0 +1,73 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// dbSystem is the db.system attribute of the PostgreSQL spans.
+const dbSystem = "postgresql"
+
+// PgxTracer traces the queries, batches and copies run by pgx connections
+// in client spans recording the SQL statements.
+type PgxTracer struct{}
+
+// Assert that PgxTracer traces every kind of pgx operation run by the repository.
+var (
+	_ pgx.QueryTracer    = PgxTracer{}
+	_ pgx.BatchTracer    = PgxTracer{}
+	_ pgx.CopyFromTracer = PgxTracer{}
+)
+
+// startClient starts a client span of a database operation.
+func startClient(ctx context.Context, name string, attrs ...attribute.KeyValue) context.Context {
+	attrs = append(attrs, attribute.String("db.system", dbSystem))
+	ctx, _ = Tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	return ctx
+}
+
+// TraceQueryStart starts the span of a query recording its SQL statement.
+func (PgxTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return startClient(ctx, "db.query", attribute.String("db.statement", data.SQL))
+}
+
+// TraceQueryEnd ends the span of a query.
+func (PgxTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+	End(span, data.Err)
+}
+
+// TraceBatchStart starts the span of a batch recording the number of its queries.
+func (PgxTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	return startClient(ctx, "db.batch", attribute.Int("db.batch.size", data.Batch.Len()))
+}
+
+// TraceBatchQuery records a query of the batch as an event of the batch span.
+func (PgxTracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	span := trace.SpanFromContext(ctx)
+	span.AddEvent("db.query", trace.WithAttributes(attribute.String("db.statement", data.SQL)))
+	if data.Err != nil {
+		span.RecordError(data.Err)
+	}
+}
+
+// TraceBatchEnd ends the span of a batch.
+func (PgxTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	End(trace.SpanFromContext(ctx), data.Err)
+}
+
+// TraceCopyFromStart starts the span of a copy recording the target table.
+func (PgxTracer) TraceCopyFromStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	return startClient(ctx, "db.copy", attribute.String("db.sql.table", data.TableName.Sanitize()))
+}
+
+// TraceCopyFromEnd ends the span of a copy.
+func (PgxTracer) TraceCopyFromEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+	End(span, data.Err)
+}
@@ -0,0 +1,361 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Azzonya/go-shortener/internal/entities"
+	"github.com/Azzonya/go-shortener/internal/repo"
+	"github.com/Azzonya/go-shortener/internal/repo/inmemory"
+)
+
+// Repo decorates a repository, tracing every call in a span named after the method.
+type Repo struct {
+	next    repo.Repo // Repository the calls are passed to
+	backend string    // Name of the storage backend recorded as the db.system attribute
+}
+
+// NewRepo returns the repository decorated with tracing spans recording the backend name.
+func NewRepo(next repo.Repo, backend string) *Repo {
+	return &Repo{
+		next:    next,
+		backend: backend,
+	}
+}
+
+// start starts the span of the method call.
+func (r *Repo) start(ctx context.Context, method string) (context.Context, trace.Span) {
+	return Start(ctx, "repo."+method,
+		attribute.String("db.system", r.backend),
+		attribute.String("db.operation", method))
+}
+
+// Initialize initializes the repository.
+func (r *Repo) Initialize(ctx context.Context) error {
+	ctx, span := r.start(ctx, "Initialize")
+	err := r.next.Initialize(ctx)
+	End(span, err)
+
+	return err
+}
+
+// TableExist checks if the necessary tables exist in the database.
+func (r *Repo) TableExist(ctx context.Context) bool {
+	ctx, span := r.start(ctx, "TableExist")
+	defer span.End()
+
+	return r.next.TableExist(ctx)
+}
+
+// Add adds a new URL entry to the repository.
+func (r *Repo) Add(ctx context.Context, originalURL, shortURL, userID string, expiresAt *time.Time) error {
+	ctx, span := r.start(ctx, "Add")
+	err := r.next.Add(ctx, originalURL, shortURL, userID, expiresAt)
+	End(span, err)
+
+	return err
+}
+
+// CreateShortURLs creates multiple short URLs in the repository for the given user.
+func (r *Repo) CreateShortURLs(ctx context.Context, urls []*entities.ReqURL, userID string) error {
+	ctx, span := r.start(ctx, "CreateShortURLs")
+	err := r.next.CreateShortURLs(ctx, urls, userID)
+	End(span, err)
+
+	return err
+}
+
+// Update updates the short URL for the given original URL.
+func (r *Repo) Update(ctx context.Context, originalURL, shortURL string) error {
+	ctx, span := r.start(ctx, "Update")
+	err := r.next.Update(ctx, originalURL, shortURL)
+	End(span, err)
+
+	return err
+}
+
+// GetByShortURL retrieves the original URL associated with the given short URL.
+func (r *Repo) GetByShortURL(ctx context.Context, shortURL string) (string, bool) {
+	ctx, span := r.start(ctx, "GetByShortURL")
+	defer span.End()
+
+	return r.next.GetByShortURL(ctx, shortURL)
+}
+
+// GetLink returns the stored state of the short URL.
+func (r *Repo) GetLink(ctx context.Context, shortURL string) (*entities.Link, error) {
+	ctx, span := r.start(ctx, "GetLink")
+	res, err := r.next.GetLink(ctx, shortURL)
+	End(span, err)
+
+	return res, err
+}
+
+// GetByOriginalURL retrieves the short URL associated with the given original URL.
+func (r *Repo) GetByOriginalURL(ctx context.Context, originalURL string) (string, bool) {
+	ctx, span := r.start(ctx, "GetByOriginalURL")
+	defer span.End()
+
+	return r.next.GetByOriginalURL(ctx, originalURL)
+}
+
+// ListAll retrieves all short URLs associated with the given user.
+func (r *Repo) ListAll(ctx context.Context, userID string) ([]*entities.ReqListAll, error) {
+	ctx, span := r.start(ctx, "ListAll")
+	res, err := r.next.ListAll(ctx, userID)
+	End(span, err)
+
+	return res, err
+}
+
+// DeleteURLs deletes multiple URLs associated with the given user.
+func (r *Repo) DeleteURLs(ctx context.Context, urls []string, userID string) error {
+	ctx, span := r.start(ctx, "DeleteURLs")
+	err := r.next.DeleteURLs(ctx, urls, userID)
+	End(span, err)
+
+	return err
+}
+
+// DeleteURLsBatch deletes the URLs of several deletion requests at once.
+func (r *Repo) DeleteURLsBatch(ctx context.Context, requests []*entities.DeleteRequest) error {
+	ctx, span := r.start(ctx, "DeleteURLsBatch")
+	err := r.next.DeleteURLsBatch(ctx, requests)
+	End(span, err)
+
+	return err
+}
+
+// URLDeleted checks if the URL with the given short URL is deleted.
+func (r *Repo) URLDeleted(ctx context.Context, shortURL string) bool {
+	ctx, span := r.start(ctx, "URLDeleted")
+	defer span.End()
+
+	return r.next.URLDeleted(ctx, shortURL)
+}
+
+// Stats returns the total number of stored URLs and of distinct users owning them.
+func (r *Repo) Stats(ctx context.Context) (*entities.ServiceStats, error) {
+	ctx, span := r.start(ctx, "Stats")
+	res, err := r.next.Stats(ctx)
+	End(span, err)
+
+	return res, err
+}
+
+// AddClicks stores the click events.
+func (r *Repo) AddClicks(ctx context.Context, clicks []*entities.Click) error {
+	ctx, span := r.start(ctx, "AddClicks")
+	err := r.next.AddClicks(ctx, clicks)
+	End(span, err)
+
+	return err
+}
+
+// ClickStats returns the click statistics of the short URL owned by the user.
+func (r *Repo) ClickStats(ctx context.Context, shortURL, userID string, bucket time.Duration) (*entities.LinkStats, error) {
+	ctx, span := r.start(ctx, "ClickStats")
+	res, err := r.next.ClickStats(ctx, shortURL, userID, bucket)
+	End(span, err)
+
+	return res, err
+}
+
+// AddAPIKey stores a new API key.
+func (r *Repo) AddAPIKey(ctx context.Context, key *entities.APIKey) error {
+	ctx, span := r.start(ctx, "AddAPIKey")
+	err := r.next.AddAPIKey(ctx, key)
+	End(span, err)
+
+	return err
+}
+
+// GetAPIKey returns the API key with the given ID.
+func (r *Repo) GetAPIKey(ctx context.Context, id string) (*entities.APIKey, error) {
+	ctx, span := r.start(ctx, "GetAPIKey")
+	res, err := r.next.GetAPIKey(ctx, id)
+	End(span, err)
+
+	return res, err
+}
+
+// ListAPIKeys returns the API keys of the user.
+func (r *Repo) ListAPIKeys(ctx context.Context, userID string) ([]*entities.APIKey, error) {
+	ctx, span := r.start(ctx, "ListAPIKeys")
+	res, err := r.next.ListAPIKeys(ctx, userID)
+	End(span, err)
+
+	return res, err
+}
+
+// RevokeAPIKey revokes the user's API key.
+func (r *Repo) RevokeAPIKey(ctx context.Context, id, userID string, revokedAt time.Time) error {
+	ctx, span := r.start(ctx, "RevokeAPIKey")
+	err := r.next.RevokeAPIKey(ctx, id, userID, revokedAt)
+	End(span, err)
+
+	return err
+}
+
+// AddAccount stores a new user account.
+func (r *Repo) AddAccount(ctx context.Context, account *entities.Account) error {
+	ctx, span := r.start(ctx, "AddAccount")
+	err := r.next.AddAccount(ctx, account)
+	End(span, err)
+
+	return err
+}
+
+// GetAccount returns the user account with the given ID.
+func (r *Repo) GetAccount(ctx context.Context, id string) (*entities.Account, error) {
+	ctx, span := r.start(ctx, "GetAccount")
+	res, err := r.next.GetAccount(ctx, id)
+	End(span, err)
+
+	return res, err
+}
+
+// GetAccountByLogin returns the user account with the given login.
+func (r *Repo) GetAccountByLogin(ctx context.Context, login string) (*entities.Account, error) {
+	ctx, span := r.start(ctx, "GetAccountByLogin")
+	res, err := r.next.GetAccountByLogin(ctx, login)
+	End(span, err)
+
+	return res, err
+}
+
+// TransferURLs makes the user toUserID the owner of every URL of the user fromUserID.
+func (r *Repo) TransferURLs(ctx context.Context, fromUserID, toUserID string) (int64, error) {
+	ctx, span := r.start(ctx, "TransferURLs")
+	res, err := r.next.TransferURLs(ctx, fromUserID, toUserID)
+	End(span, err)
+
+	return res, err
+}
+
+// SearchLinks returns the links of every user matching the filter.
+func (r *Repo) SearchLinks(ctx context.Context, filter *entities.LinkFilter) ([]*entities.Link, error) {
+	ctx, span := r.start(ctx, "SearchLinks")
+	res, err := r.next.SearchLinks(ctx, filter)
+	End(span, err)
+
+	return res, err
+}
+
+// SetURLDisabled disables or enables again the short URL, whoever owns it.
+func (r *Repo) SetURLDisabled(ctx context.Context, shortURL string, disabled bool) error {
+	ctx, span := r.start(ctx, "SetURLDisabled")
+	err := r.next.SetURLDisabled(ctx, shortURL, disabled)
+	End(span, err)
+
+	return err
+}
+
+// ReassignURL makes the user the owner of the short URL.
+func (r *Repo) ReassignURL(ctx context.Context, shortURL, userID string) error {
+	ctx, span := r.start(ctx, "ReassignURL")
+	err := r.next.ReassignURL(ctx, shortURL, userID)
+	End(span, err)
+
+	return err
+}
+
+// BanUser bans the user.
+func (r *Repo) BanUser(ctx context.Context, ban *entities.Ban) error {
+	ctx, span := r.start(ctx, "BanUser")
+	err := r.next.BanUser(ctx, ban)
+	End(span, err)
+
+	return err
+}
+
+// UnbanUser lifts the ban of the user.
+func (r *Repo) UnbanUser(ctx context.Context, userID string) error {
+	ctx, span := r.start(ctx, "UnbanUser")
+	err := r.next.UnbanUser(ctx, userID)
+	End(span, err)
+
+	return err
+}
+
+// GetBan returns the ban of the user.
+func (r *Repo) GetBan(ctx context.Context, userID string) (*entities.Ban, error) {
+	ctx, span := r.start(ctx, "GetBan")
+	res, err := r.next.GetBan(ctx, userID)
+	End(span, err)
+
+	return res, err
+}
+
+// AddAuditEntry appends the entry to the audit trail.
+func (r *Repo) AddAuditEntry(ctx context.Context, entry *entities.AuditEntry) error {
+	ctx, span := r.start(ctx, "AddAuditEntry")
+	err := r.next.AddAuditEntry(ctx, entry)
+	End(span, err)
+
+	return err
+}
+
+// ListAuditEntries returns the most recent entries of the audit trail.
+func (r *Repo) ListAuditEntries(ctx context.Context, limit int) ([]*entities.AuditEntry, error) {
+	ctx, span := r.start(ctx, "ListAuditEntries")
+	res, err := r.next.ListAuditEntries(ctx, limit)
+	End(span, err)
+
+	return res, err
+}
+
+// NextSequence returns the next value of the sequence backing the sequential short code generators.
+func (r *Repo) NextSequence(ctx context.Context) (int64, error) {
+	ctx, span := r.start(ctx, "NextSequence")
+	res, err := r.next.NextSequence(ctx)
+	End(span, err)
+
+	return res, err
+}
+
+// URLExpired checks if the URL with the given short URL has expired.
+func (r *Repo) URLExpired(ctx context.Context, shortURL string) bool {
+	ctx, span := r.start(ctx, "URLExpired")
+	defer span.End()
+
+	return r.next.URLExpired(ctx, shortURL)
+}
+
+// DeleteExpired removes the URLs which expired before the given time and returns their number.
+func (r *Repo) DeleteExpired(ctx context.Context, before time.Time, archive bool) (int64, error) {
+	ctx, span := r.start(ctx, "DeleteExpired")
+	res, err := r.next.DeleteExpired(ctx, before, archive)
+	End(span, err)
+
+	return res, err
+}
+
+// WriteEvent writes an event to the storage.
+func (r *Repo) WriteEvent(event *inmemory.Event) error {
+	_, span := r.start(context.Background(), "WriteEvent")
+	err := r.next.WriteEvent(event)
+	End(span, err)
+
+	return err
+}
+
+// SyncData synchronizes data.
+func (r *Repo) SyncData() {
+	_, span := r.start(context.Background(), "SyncData")
+	defer span.End()
+
+	r.next.SyncData()
+}
+
+// Ping pings the database to check its availability.
+func (r *Repo) Ping(ctx context.Context) error {
+	ctx, span := r.start(ctx, "Ping")
+	err := r.next.Ping(ctx)
+	End(span, err)
+
+	return err
+}
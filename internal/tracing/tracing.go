@@ -0,0 +1,161 @@
+// Package tracing provides OpenTelemetry tracing of the URL shortener service.
+//
+// Init installs the global tracer provider exporting the spans with the configured exporter
+// and the W3C trace context propagator. Spans are started for HTTP requests by Middleware,
+// for repository calls by the decorator returned by NewRepo and for SQL statements by PgxTracer.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Names of the supported span exporters.
+const (
+	ExporterNone   = "none"   // Spans are not recorded
+	ExporterStdout = "stdout" // Spans are written to the standard output
+	ExporterFile   = "file"   // Spans are appended to a file
+	ExporterOTLP   = "otlp"   // Spans are sent to an OTLP/HTTP collector
+)
+
+// instrumentationName identifies the tracer of the service.
+const instrumentationName = "github.com/Azzonya/go-shortener"
+
+// serviceName is reported as the service.name resource attribute.
+const serviceName = "go-shortener"
+
+// ErrUnknownExporter is returned by Init for an unsupported exporter name.
+var ErrUnknownExporter = errors.New("unknown trace exporter")
+
+// Config represents the tracing settings.
+type Config struct {
+	Exporter string // Exporter is the name of the span exporter, empty means ExporterNone.
+	Endpoint string // Endpoint is the URL of the OTLP/HTTP collector, like http://localhost:4318.
+	File     string // File is the path the file exporter appends the spans to.
+}
+
+// Init installs the W3C trace context propagator and the global tracer provider exporting spans as configured.
+// The returned function flushes the pending spans and shuts the provider down.
+func Init(ctx context.Context, conf Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	exporter, closer, err := newExporter(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+	if exporter == nil {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", serviceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			err = errors.Join(err, closer.Close())
+		}
+		return err
+	}, nil
+}
+
+// newExporter creates the span exporter named in the configuration.
+// The closer, if not nil, releases the file written by the exporter.
+func newExporter(ctx context.Context, conf Config) (sdktrace.SpanExporter, io.Closer, error) {
+	switch conf.Exporter {
+	case "", ExporterNone:
+		return nil, nil, nil
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		return exporter, nil, err
+	case ExporterFile:
+		f, err := os.OpenFile(conf.File, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		return exporter, f, nil
+	case ExporterOTLP:
+		opts, err := otlpOptions(conf.Endpoint)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		exporter, err := otlptracehttp.New(ctx, opts...)
+		return exporter, nil, err
+	default:
+		return nil, nil, fmt.Errorf("%w %q", ErrUnknownExporter, conf.Exporter)
+	}
+}
+
+// otlpOptions returns the OTLP/HTTP exporter options sending the spans to the collector URL.
+// An empty endpoint leaves the exporter defaults and the OTEL_EXPORTER_OTLP_* variables in effect.
+func otlpOptions(endpoint string) ([]otlptracehttp.Option, error) {
+	if endpoint == "" {
+		return nil, nil
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trace endpoint %q: %w", endpoint, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid trace endpoint %q: missing host", endpoint)
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(u.Host)}
+	if u.Scheme == "http" {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	if u.Path != "" && u.Path != "/" {
+		opts = append(opts, otlptracehttp.WithURLPath(u.Path))
+	}
+
+	return opts, nil
+}
+
+// Tracer returns the tracer of the service backed by the global tracer provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start starts a span with the given name and attributes as a child of the span in ctx.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records the error, if any, on the span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,136 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/Azzonya/go-shortener/internal/repo/inmemory"
+)
+
+// recordSpans installs a tracer provider recording the ended spans for the duration of the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	return recorder
+}
+
+func TestMiddleware(t *testing.T) {
+	recorder := recordSpans(t)
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(Middleware())
+	r.GET("/:id", func(c *gin.Context) {
+		_, span := Start(c.Request.Context(), "handler")
+		span.End()
+		c.Status(http.StatusTemporaryRedirect)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("ended %v spans, want %v", len(spans), 2)
+	}
+
+	handler, server := spans[0], spans[1]
+	if server.Name() != "GET /:id" {
+		t.Errorf("server span name = %q, want %q", server.Name(), "GET /:id")
+	}
+	if got := server.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace ID = %v, want the trace of the traceparent header", got)
+	}
+	if got := server.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("parent span ID = %v, want the span of the traceparent header", got)
+	}
+	if handler.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Errorf("handler span is not a child of the server span")
+	}
+}
+
+func TestRepo(t *testing.T) {
+	recorder := recordSpans(t)
+
+	storage, err := inmemory.New("")
+	if err != nil {
+		t.Fatalf("inmemory.New() error = %v", err)
+	}
+
+	r := NewRepo(storage, "inmemory")
+
+	if err = r.Add(context.Background(), "example.com", "tst", "1", nil); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err = r.Add(context.Background(), "example.com", "tst", "1", nil); err == nil {
+		t.Fatalf("Add() of a taken short URL error = nil, want an error")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("ended %v spans, want %v", len(spans), 2)
+	}
+	if spans[0].Name() != "repo.Add" {
+		t.Errorf("span name = %q, want %q", spans[0].Name(), "repo.Add")
+	}
+	if got := spans[1].Status().Code.String(); got != "Error" {
+		t.Errorf("failed call span status = %v, want Error", got)
+	}
+}
+
+func TestInit(t *testing.T) {
+	t.Run("unknown exporter", func(t *testing.T) {
+		_, err := Init(context.Background(), Config{Exporter: "jaeger"})
+		if !errors.Is(err, ErrUnknownExporter) {
+			t.Errorf("Init() error = %v, want %v", err, ErrUnknownExporter)
+		}
+	})
+
+	t.Run("file exporter", func(t *testing.T) {
+		previous := otel.GetTracerProvider()
+		t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+		file := filepath.Join(t.TempDir(), "traces.json")
+
+		shutdown, err := Init(context.Background(), Config{Exporter: ExporterFile, File: file})
+		if err != nil {
+			t.Fatalf("Init() error = %v", err)
+		}
+
+		_, span := Start(context.Background(), "test-span")
+		span.End()
+
+		if err = shutdown(context.Background()); err != nil {
+			t.Fatalf("shutdown() error = %v", err)
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("ReadFile() error = %v", err)
+		}
+		if !strings.Contains(string(data), "test-span") {
+			t.Errorf("trace file does not contain the span: %s", data)
+		}
+	})
+}
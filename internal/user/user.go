@@ -13,10 +13,14 @@ import (
 	"github.com/gofrs/uuid/v5"
 )
 
+// RoleAdmin is the role of the users allowed to moderate the links of every user.
+const RoleAdmin = "admin"
+
 // User represents a user with an ID and a flag indicating whether it's a new user.
 type User struct {
-	ID  string
-	new bool
+	ID   string
+	Role string // Role is the role granted to the user, empty for regular users.
+	new  bool
 }
 
 // IsNew returns true if the user is newly created, otherwise false.
@@ -24,6 +28,11 @@ func (u *User) IsNew() bool {
 	return u.new
 }
 
+// IsAdmin returns true if the user has the admin role.
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
 // NewWithID creates a new user with the specified ID.
 func NewWithID(id string) *User {
 	return &User{ID: id}
@@ -7,16 +7,20 @@ import (
 	"context"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/Azzonya/go-shortener/internal/tracing"
 )
 
 // InitDatabasePg initializes a connection pool to a PostgreSQL database.
 // It takes a PostgreSQL connection string (pgDsn) as input and returns a *pgxpool.Pool representing the database connection pool.
+// Queries run through the pool are traced with tracing.PgxTracer.
 // If initialization fails, it returns an error.
 func InitDatabasePg(pgDsn string) (*pgxpool.Pool, error) {
 	cfg, err := pgxpool.ParseConfig(pgDsn)
 	if err != nil {
 		return nil, err
 	}
+	cfg.ConnConfig.Tracer = tracing.PgxTracer{}
 
 	dbPool, err := pgxpool.NewWithConfig(context.Background(), cfg)
 	if err != nil {